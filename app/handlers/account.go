@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"dapa/app/model"
+	"dapa/app/utils"
+	"dapa/database"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// @Summary		Get the authenticated user
+// @Description	Returns the account of the authenticated user with the permissions of their role
+// @Tags		account
+// @Produce		json
+// @Success		200	{object} model.AccountDTO "Account of the user"
+// @Failure		404	{object} model.ApiResponse "User not found"
+// @Failure		500	{object} model.ApiResponse "Error fetching account"
+// @Router		/me [get]
+func GetAccountHandler(c *gin.Context) {
+	user, ok := findAccount(c)
+	if !ok {
+		return
+	}
+
+	permissions, ok := userPermissions(c)
+	if !ok {
+		return
+	}
+
+	// Los permisos se listan en el orden del catálogo
+	account := model.AccountDTO{User: user, Permissions: []model.Permission{}}
+	for _, info := range model.Permissions {
+		if permissions[info.Permission] {
+			account.Permissions = append(account.Permissions, info.Permission)
+		}
+	}
+
+	utils.RespondWithSuccess(c, http.StatusOK, account, "Account fetched successfully")
+}
+
+// @Summary		Update the authenticated user
+// @Description	Updates the name, phone and license expiration date of the authenticated user. Only the fields sent are changed; the email and role can only be changed by an admin
+// @Tags		account
+// @Accept		json
+// @Produce		json
+// @Param		account body model.UpdateAccountDTO true "Account data"
+// @Success		200	{object} model.ApiResponse "Account updated successfully"
+// @Failure		400	{object} model.ApiResponse "Invalid request format"
+// @Failure		404	{object} model.ApiResponse "User not found"
+// @Failure		500	{object} model.ApiResponse "Error updating account"
+// @Router		/me [patch]
+func UpdateAccountHandler(c *gin.Context) {
+	var req model.UpdateAccountDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+
+	user, ok := findAccount(c)
+	if !ok {
+		return
+	}
+
+	if req.Name != nil {
+		user.Name = *req.Name
+	}
+	if req.LastName != nil {
+		user.LastName = *req.LastName
+	}
+	if req.Phone != nil {
+		user.Phone = *req.Phone
+	}
+	if req.LicenseExpirationDate != nil {
+		user.LicenseExpirationDate = *req.LicenseExpirationDate
+	}
+
+	err := database.DB.Model(&user).
+		Select("name", "last_name", "phone", "license_expiration_date", "last_modified_at").
+		Updates(&user).Error
+	if err != nil {
+		utils.RespondWithInternalError(c, "Error updating account")
+		return
+	}
+
+	utils.RespondWithSuccess(c, http.StatusOK, user, "Account updated successfully")
+}
+
+// @Summary		Change the password
+// @Description	Changes the password of the authenticated user after verifying the current one. Every other session of the user is closed
+// @Tags		account
+// @Accept		json
+// @Produce		json
+// @Param		data body model.ChangePasswordDTO true "Current and new password"
+// @Success		200	{object} model.ApiResponse "Password updated successfully"
+// @Failure		400	{object} model.ApiResponse "Invalid request format"
+// @Failure		403	{object} model.ApiResponse "Current password is incorrect"
+// @Failure		404	{object} model.ApiResponse "User not found"
+// @Failure		429	{object} model.ApiResponse "Too many attempts"
+// @Failure		500	{object} model.ApiResponse "Error changing password"
+// @Router		/me/password [post]
+func ChangePasswordHandler(c *gin.Context) {
+	claims := c.MustGet("claims").(*model.EmployeeClaims)
+
+	var req model.ChangePasswordDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+
+	var user model.User
+	if err := database.DB.Where("id = ? AND is_active = ?", claims.UserID, true).First(&user).Error; err != nil {
+		utils.RespondWithCustomError(c, http.StatusNotFound, "User not found", "Something went wrong")
+		return
+	}
+
+	// La contraseña actual se verifica con los mismos límites que el inicio de sesión
+	wait, err := reserveLoginAttempt(&user, time.Now())
+	if err != nil {
+		utils.RespondWithInternalError(c, "Error changing password")
+		return
+	}
+	if wait > 0 {
+		respondTooManyAttempts(c, wait)
+		return
+	}
+
+	if !utils.CheckPassword(req.CurrentPassword, user.PasswordHash) {
+		utils.RespondWithCustomError(c, http.StatusForbidden, "Current password is incorrect", "Invalid credentials")
+		return
+	}
+
+	if err = clearLoginFailures(database.DB, user.ID); err != nil {
+		utils.RespondWithInternalError(c, "Error changing password")
+		return
+	}
+
+	if req.NewPassword == req.CurrentPassword {
+		utils.RespondWithCustomError(c, http.StatusBadRequest, "New password must be different from the current one", "Invalid request format")
+		return
+	}
+
+	passwordHash, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		utils.RespondWithInternalError(c, "Error changing password")
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Update("password_hash", passwordHash).Error; err != nil {
+			return err
+		}
+
+		// La sesión desde la que se cambia la contraseña se mantiene abierta
+		return tx.Model(&model.Session{}).
+			Where("user_id = ? AND id <> ? AND revoked_at IS NULL", user.ID, claims.SessionID).
+			Update("revoked_at", time.Now()).Error
+	})
+	if err != nil {
+		utils.RespondWithInternalError(c, "Error changing password")
+		return
+	}
+
+	utils.RespondWithSuccess(c, http.StatusOK, nil, "Password updated successfully")
+}
+
+// Busca el usuario autenticado sin el hash de su contraseña
+// Envía la respuesta de error y retorna false si no existe o está inactivo
+func findAccount(c *gin.Context) (model.User, bool) {
+	claims := c.MustGet("claims").(*model.EmployeeClaims)
+
+	var user model.User
+	if err := database.DB.Where("id = ? AND is_active = ?", claims.UserID, true).Limit(1).Find(&user).Error; err != nil {
+		utils.RespondWithInternalError(c, "Error fetching account")
+		return user, false
+	}
+
+	if user.ID == 0 {
+		utils.RespondWithCustomError(c, http.StatusNotFound, "User not found", "Something went wrong")
+		return user, false
+	}
+
+	user.PasswordHash = ""
+	return user, true
+}
@@ -0,0 +1,366 @@
+package handlers
+
+import (
+	"bytes"
+	"dapa/app/model"
+	"dapa/app/utils"
+	"dapa/database"
+	"dapa/storage"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Tipos de archivo aceptados como adjuntos y la extensión con la que se guardan
+var attachmentTypes = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+}
+
+// Tipos de archivo aceptados cuando solo se esperan imágenes
+var imageTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+// Entidades que pueden recibir adjuntos desde la API
+var attachmentOwners = map[model.AttachmentOwner]any{
+	model.AttachmentOwnerOrder:   &model.Order{},
+	model.AttachmentOwnerExpense: &model.Expense{},
+	model.AttachmentOwnerVehicle: &model.Vehicle{},
+	model.AttachmentOwnerUser:    &model.User{},
+}
+
+var (
+	errUnsupportedFile = errors.New("unsupported file type")
+	errFileTooLarge    = errors.New("file is too large")
+)
+
+// @Summary		Get the attachments of a resource
+// @Description	Returns the files attached to an order, expense, vehicle or user with signed download URLs
+// @Tags		attachments
+// @Produce		json
+// @Param       owner path string true "Resource type" Enums(orders, expenses, vehicles, users)
+// @Param       ownerId path int true "Resource ID"
+// @Success		200	{object} model.ApiResponse "List of attachments"
+// @Failure		403	{object} model.ApiResponse "Insufficient permissions"
+// @Failure		404	{object} model.ApiResponse "Resource not found"
+// @Failure		500	{object} model.ApiResponse "Error fetching attachments"
+// @Router		/attachments/{owner}/{ownerId} [get]
+func GetAttachmentsHandler(c *gin.Context) {
+	owner, ownerID, ok := findAttachmentOwner(c)
+	if !ok {
+		return
+	}
+
+	var attachments []model.Attachment
+	err := database.DB.Where("owner_type = ? AND owner_id = ?", owner, ownerID).Order("created_at").Find(&attachments).Error
+	if err != nil {
+		utils.RespondWithInternalError(c, "Error fetching attachments")
+		return
+	}
+
+	if err = signAttachments(attachments); err != nil {
+		utils.RespondWithInternalError(c, "Error fetching attachments")
+		return
+	}
+
+	utils.RespondWithSuccess(c, http.StatusOK, attachments, "Attachments fetched successfully")
+}
+
+// @Summary		Attaches a file to a resource
+// @Description	Uploads an image or PDF and attaches it to an order, expense, vehicle or user
+// @Tags		attachments
+// @Accept		multipart/form-data
+// @Produce		json
+// @Param       owner path string true "Resource type" Enums(orders, expenses, vehicles, users)
+// @Param       ownerId path int true "Resource ID"
+// @Param		file formData file true "File to attach"
+// @Param		category formData string false "Category of the file, such as receipt or license"
+// @Success		201	{object} model.ApiResponse "File attached successfully"
+// @Failure		400	{object} model.ApiResponse "Invalid request format"
+// @Failure		403	{object} model.ApiResponse "Insufficient permissions"
+// @Failure		404	{object} model.ApiResponse "Resource not found"
+// @Failure		413	{object} model.ApiResponse "File is too large"
+// @Failure		500	{object} model.ApiResponse "Error saving file"
+// @Router		/attachments/{owner}/{ownerId} [post]
+func CreateAttachmentHandler(c *gin.Context) {
+	claims := c.MustGet("claims").(*model.EmployeeClaims)
+
+	owner, ownerID, ok := findAttachmentOwner(c)
+	if !ok {
+		return
+	}
+
+	limitUploadBody(c, 1)
+	header, err := c.FormFile("file")
+	if err != nil {
+		respondUploadError(c, err)
+		return
+	}
+
+	category := strings.TrimSpace(c.PostForm("category"))
+	if utf8.RuneCountInString(category) > 50 {
+		utils.RespondWithCustomError(c, http.StatusBadRequest, "Category can't exceed 50 characters", "Invalid request format")
+		return
+	}
+
+	attachment, err := uploadFile(header, fmt.Sprintf("%s/%d", owner, ownerID), attachmentTypes)
+	if err != nil {
+		respondUploadError(c, err)
+		return
+	}
+
+	attachment.OwnerType = owner
+	attachment.OwnerID = ownerID
+	attachment.Category = category
+	attachment.UploadedBy = &claims.UserID
+
+	if err = database.DB.Create(&attachment).Error; err != nil {
+		storage.Files.Delete(attachment.FileKey)
+		utils.RespondWithInternalError(c, "Error saving file")
+		return
+	}
+
+	attachment.URL, _ = storage.Files.SignedURL(attachment.FileKey, storage.URLExpiry())
+	utils.RespondWithSuccess(c, http.StatusCreated, attachment, "File attached successfully")
+}
+
+// @Summary		Deletes an attachment
+// @Description	Removes the attachment and its file from storage
+// @Tags		attachments
+// @Produce		json
+// @Param       id path int true "Attachment ID"
+// @Success		200	{object} model.ApiResponse "Attachment deleted successfully"
+// @Failure		404	{object} model.ApiResponse "Attachment not found"
+// @Failure		500	{object} model.ApiResponse "Error deleting attachment"
+// @Router		/attachments/{id} [delete]
+func DeleteAttachmentHandler(c *gin.Context) {
+	var attachment model.Attachment
+	if err := database.DB.Where("id = ?", c.Param("id")).First(&attachment).Error; err != nil {
+		utils.RespondWithCustomError(
+			c,
+			http.StatusNotFound,
+			"Attachment not found",
+			"Something went wrong",
+		)
+		return
+	}
+
+	if err := database.DB.Delete(&attachment).Error; err != nil {
+		utils.RespondWithInternalError(c, "Error deleting attachment")
+		return
+	}
+
+	deleteStoredFiles([]model.Attachment{attachment})
+	utils.RespondWithSuccess(c, http.StatusOK, nil, "Attachment deleted successfully")
+}
+
+// @Summary		Downloads a stored file
+// @Description	Serves a file from local storage using a signed URL generated by the API
+// @Tags		attachments
+// @Produce		octet-stream
+// @Param       key path string true "File key"
+// @Param       expires query int true "Expiration time of the URL"
+// @Param       signature query string true "URL signature"
+// @Success		200	{file} file "File content"
+// @Failure		403	{object} model.ApiResponse "Invalid or expired URL"
+// @Failure		404	{object} model.ApiResponse "File not found"
+// @Router		/files/{key} [get]
+func DownloadFileHandler(c *gin.Context) {
+	local, ok := storage.Files.(*storage.LocalStorage)
+	if !ok {
+		utils.RespondWithCustomError(c, http.StatusNotFound, "File not found", "Something went wrong")
+		return
+	}
+
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	if err := local.Verify(key, c.Query("expires"), c.Query("signature")); err != nil {
+		utils.RespondWithCustomError(c, http.StatusForbidden, "Invalid or expired URL", "Access denied")
+		return
+	}
+
+	content, err := local.Open(key)
+	if err != nil {
+		utils.RespondWithCustomError(c, http.StatusNotFound, "File not found", "Something went wrong")
+		return
+	}
+	defer content.Close()
+
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	c.DataFromReader(http.StatusOK, -1, contentType, content, nil)
+}
+
+// Obtiene el tipo y el ID del recurso indicado en la ruta y verifica que exista y que el usuario pueda acceder a él
+// Retorna false si no es posible, después de enviar la respuesta de error
+func findAttachmentOwner(c *gin.Context) (model.AttachmentOwner, uint, bool) {
+	owner := model.AttachmentOwner(c.Param("owner"))
+
+	entity, supported := attachmentOwners[owner]
+	id, err := strconv.ParseUint(c.Param("ownerId"), 10, 64)
+	if !supported || err != nil {
+		utils.RespondWithCustomError(c, http.StatusNotFound, "Resource not found", "Something went wrong")
+		return "", 0, false
+	}
+	ownerID := uint(id)
+
+	var count int64
+	if err = database.DB.Model(entity).Where("id = ?", ownerID).Count(&count).Error; err != nil {
+		utils.RespondWithInternalError(c, "Error fetching resource")
+		return "", 0, false
+	}
+
+	if count == 0 {
+		utils.RespondWithCustomError(c, http.StatusNotFound, "Resource not found", "Something went wrong")
+		return "", 0, false
+	}
+
+	// El piloto y el ayudante asignados pueden adjuntar fotografías de la orden
+	var order model.Order
+	if owner == model.AttachmentOwnerOrder {
+		if err = database.DB.Where("id = ?", ownerID).First(&order).Error; err != nil {
+			utils.RespondWithInternalError(c, "Error fetching resource")
+			return "", 0, false
+		}
+	}
+
+	allowed := authorize(c, func(permissions model.PermissionSet, claims *model.EmployeeClaims) bool {
+		return canAccessAttachments(permissions, claims, owner, ownerID, &order)
+	})
+	if !allowed {
+		return "", 0, false
+	}
+
+	return owner, ownerID, true
+}
+
+// Tamaño máximo de cada archivo subido, configurable en megabytes con UPLOAD_MAX_SIZE_MB
+func maxUploadSize() int64 {
+	megabytes, err := strconv.ParseInt(utils.EnvGet("UPLOAD_MAX_SIZE_MB", "10"), 10, 64)
+	if err != nil || megabytes <= 0 {
+		megabytes = 10
+	}
+	return megabytes << 20
+}
+
+// Limita el tamaño del cuerpo de la solicitud según la cantidad de archivos que puede contener
+// Se reserva un megabyte adicional para los campos del formulario
+func limitUploadBody(c *gin.Context, files int) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, int64(files)*maxUploadSize()+1<<20)
+}
+
+// Guarda un archivo subido en el almacenamiento dentro del directorio indicado
+// El tipo de contenido se detecta a partir del archivo y debe ser uno de los permitidos
+// Retorna el adjunto sin propietario, listo para asociarlo y guardarlo
+func uploadFile(header *multipart.FileHeader, dir string, allowed map[string]string) (model.Attachment, error) {
+	if header.Size > maxUploadSize() {
+		return model.Attachment{}, fmt.Errorf("%s: %w", header.Filename, errFileTooLarge)
+	}
+
+	src, err := header.Open()
+	if err != nil {
+		return model.Attachment{}, err
+	}
+	defer src.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(src, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return model.Attachment{}, err
+	}
+	head = head[:n]
+
+	contentType := http.DetectContentType(head)
+	ext, ok := allowed[contentType]
+	if !ok {
+		return model.Attachment{}, fmt.Errorf("%s: %w", header.Filename, errUnsupportedFile)
+	}
+
+	key, err := storage.NewKey(dir, ext)
+	if err != nil {
+		return model.Attachment{}, err
+	}
+
+	if err = storage.Files.Save(key, io.MultiReader(bytes.NewReader(head), src), header.Size, contentType); err != nil {
+		return model.Attachment{}, err
+	}
+
+	return model.Attachment{
+		FileName:    header.Filename,
+		FileKey:     key,
+		ContentType: contentType,
+		Size:        header.Size,
+	}, nil
+}
+
+// Envía la respuesta correspondiente a un error al recibir o guardar un archivo
+func respondUploadError(c *gin.Context, err error) {
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.Is(err, errFileTooLarge) || errors.As(err, &maxBytesErr):
+		utils.RespondWithCustomError(
+			c,
+			http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("Files can't exceed %d MB", maxUploadSize()>>20),
+			"File is too large",
+		)
+	case errors.Is(err, errUnsupportedFile):
+		utils.RespondWithCustomError(c, http.StatusBadRequest, err.Error(), "Unsupported file type")
+	case errors.Is(err, http.ErrMissingFile) || errors.Is(err, http.ErrNotMultipart):
+		utils.RespondWithCustomError(c, http.StatusBadRequest, "A file is required", "Invalid request format")
+	default:
+		utils.RespondWithInternalError(c, "Error saving file")
+	}
+}
+
+// Genera el enlace de descarga firmado de cada adjunto
+func signAttachments(attachments []model.Attachment) error {
+	expiry := storage.URLExpiry()
+	for i := range attachments {
+		url, err := storage.Files.SignedURL(attachments[i].FileKey, expiry)
+		if err != nil {
+			return err
+		}
+		attachments[i].URL = url
+	}
+	return nil
+}
+
+// Elimina del almacenamiento los archivos de los adjuntos indicados
+// Los errores se ignoran porque un archivo huérfano no afecta a los registros
+func deleteStoredFiles(attachments []model.Attachment) {
+	for _, attachment := range attachments {
+		storage.Files.Delete(attachment.FileKey)
+	}
+}
+
+// Elimina los registros de los archivos adjuntos a una entidad
+// Retorna los archivos eliminados para borrarlos del almacenamiento una vez confirmada la transacción
+func deleteOwnerAttachments(tx *gorm.DB, owner model.AttachmentOwner, ownerID uint) ([]model.Attachment, error) {
+	var attachments []model.Attachment
+	if err := tx.Where("owner_type = ? AND owner_id = ?", owner, ownerID).Find(&attachments).Error; err != nil {
+		return nil, err
+	}
+
+	if len(attachments) == 0 {
+		return nil, nil
+	}
+	return attachments, tx.Delete(&attachments).Error
+}
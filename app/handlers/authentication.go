@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -34,6 +35,10 @@ func RegisterHandler(c *gin.Context) {
 		return
 	}
 
+	if !validateRole(c, req.Role) {
+		return
+	}
+
 	// VERIFICACIÓN DE CORREOS ELECTRÓNICOS
 	verificationApi := "https://api.emailable.com/v1/verify"
 	client := &http.Client{}
@@ -97,7 +102,7 @@ func RegisterHandler(c *gin.Context) {
 }
 
 // @Summary      Employee login
-// @Description  Authenticates an employee and returns a JWT token
+// @Description  Authenticates an employee and returns a short-lived JWT token. The refresh token of the new session is set in an HttpOnly cookie. Repeated failures make the account wait longer between attempts until it's temporarily locked, and each IP has a limit of failed attempts
 // @Tags         auth
 // @Accept       json
 // @Produce      json
@@ -105,6 +110,7 @@ func RegisterHandler(c *gin.Context) {
 // @Success      200 {object} model.ApiResponse "Login successful, token returned in data"
 // @Failure      400 {object} model.ApiResponse "Invalid request format"
 // @Failure      401 {object} model.ApiResponse "Invalid credentials"
+// @Failure      429 {object} model.ApiResponse "Too many attempts"
 // @Failure      500 {object} model.ApiResponse "Internal server error"
 // @Router       /login/ [post]
 func LoginHandler(c *gin.Context) {
@@ -114,13 +120,35 @@ func LoginHandler(c *gin.Context) {
 		return
 	}
 
+	// Cada intento queda en la bitácora, también los que se rechazan
+	now := time.Now()
+	attempt, err := startLoginAttempt(c, model.LoginAttemptLogin, req.Email)
+	if err != nil {
+		utils.RespondWithInternalError(c, "Error logging user in")
+		return
+	}
+	defer finishLoginAttempt(&attempt)
+
+	// Los límites se revisan antes de comparar la contraseña, que es la operación costosa
+	wait, err := ipRetryAfter(&attempt, maxLoginFailuresPerIP, true, now)
+	if err != nil {
+		utils.RespondWithInternalError(c, "Error logging user in")
+		return
+	}
+	if wait > 0 {
+		attempt.Reason = attemptReasonIPLimited
+		respondTooManyAttempts(c, wait)
+		return
+	}
+
 	var user model.User
 
-	err := database.DB.
+	err = database.DB.
 		Where("email = ? AND is_active = ?", req.Email, true).
 		First(&user).Error
 
-	if err != nil || !utils.CheckPassword(req.Password, user.PasswordHash) {
+	if err != nil {
+		attempt.Reason = attemptReasonUnknownEmail
 		utils.RespondWithCustomError(
 			c,
 			http.StatusUnauthorized,
@@ -129,24 +157,168 @@ func LoginHandler(c *gin.Context) {
 		)
 		return
 	}
+	attempt.UserID = &user.ID
+
+	wait, err = reserveLoginAttempt(&user, now)
+	if err != nil {
+		utils.RespondWithInternalError(c, "Error logging user in")
+		return
+	}
+	if wait > 0 {
+		attempt.Reason = attemptReasonAccountLocked
+		respondTooManyAttempts(c, wait)
+		return
+	}
+
+	if !utils.CheckPassword(req.Password, user.PasswordHash) {
+		attempt.Reason = attemptReasonInvalidPassword
+		utils.RespondWithCustomError(
+			c,
+			http.StatusUnauthorized,
+			"Email or password is incorrect",
+			"Invalid credentials",
+		)
+		return
+	}
+
+	if err = clearLoginFailures(database.DB, user.ID); err != nil {
+		utils.RespondWithInternalError(c, "Error logging user in")
+		return
+	}
 
-	token, err := utils.GenerateToken(&user)
+	token, err := startSession(c, &user)
 	if err != nil {
 		utils.RespondWithInternalError(c, "Error logging user in")
 		return
 	}
 
+	attempt.Success = true
 	utils.RespondWithSuccess(c, http.StatusOK, token, "User successfully logged in")
 }
 
+// @Summary      Refresh the access token
+// @Description  Uses the refresh token of the session cookie to issue a new access token. The refresh token is replaced on each use; reusing an old one revokes the session
+// @Tags         auth
+// @Produce      json
+// @Success      200 {object} model.ApiResponse "Token refreshed, new token returned in data"
+// @Failure      401 {object} model.ApiResponse "Invalid session"
+// @Failure      500 {object} model.ApiResponse "Internal server error"
+// @Router       /auth/refresh [post]
+func RefreshTokenHandler(c *gin.Context) {
+	refreshToken, err := c.Cookie(refreshTokenCookie)
+	if err != nil || refreshToken == "" {
+		utils.RespondWithCustomError(c, http.StatusUnauthorized, "Missing refresh token", "Invalid session")
+		return
+	}
+	hash := utils.HashToken(refreshToken)
+	now := time.Now()
+
+	var session model.Session
+	if err = database.DB.Where("token_hash = ?", hash).Limit(1).Find(&session).Error; err != nil {
+		utils.RespondWithInternalError(c, "Error refreshing token")
+		return
+	}
+
+	if session.ID == 0 {
+		// Un token ya utilizado indica que pudo ser robado, se cierra la sesión completa
+		database.DB.Model(&model.Session{}).
+			Where("previous_token_hash = ? AND revoked_at IS NULL", hash).
+			Update("revoked_at", now)
+
+		clearRefreshCookie(c)
+		utils.RespondWithCustomError(c, http.StatusUnauthorized, "Refresh token is invalid", "Invalid session")
+		return
+	}
+
+	if session.RevokedAt != nil || now.After(session.ExpiresAt) {
+		clearRefreshCookie(c)
+		utils.RespondWithCustomError(c, http.StatusUnauthorized, "Session has expired or was revoked", "Invalid session")
+		return
+	}
+
+	var user model.User
+	if err = database.DB.Where("id = ? AND is_active = ?", session.UserID, true).Limit(1).Find(&user).Error; err != nil {
+		utils.RespondWithInternalError(c, "Error refreshing token")
+		return
+	}
+	if user.ID == 0 {
+		database.DB.Model(&session).Update("revoked_at", now)
+		clearRefreshCookie(c)
+		utils.RespondWithCustomError(c, http.StatusUnauthorized, "User is inactive", "Invalid session")
+		return
+	}
+
+	newRefreshToken, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		utils.RespondWithInternalError(c, "Error refreshing token")
+		return
+	}
+
+	// Solo se rota si el token no cambió desde que se leyó, para que dos renovaciones simultáneas no usen el mismo
+	result := database.DB.Model(&model.Session{}).
+		Where("id = ? AND token_hash = ?", session.ID, hash).
+		Updates(map[string]any{
+			"token_hash":          utils.HashToken(newRefreshToken),
+			"previous_token_hash": hash,
+			"last_used_at":        now,
+		})
+	if result.Error != nil {
+		utils.RespondWithInternalError(c, "Error refreshing token")
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.RespondWithCustomError(c, http.StatusUnauthorized, "Refresh token is invalid", "Invalid session")
+		return
+	}
+
+	token, err := utils.GenerateToken(&user, session.ID)
+	if err != nil {
+		utils.RespondWithInternalError(c, "Error refreshing token")
+		return
+	}
+
+	setRefreshCookie(c, newRefreshToken, session.ExpiresAt)
+	utils.RespondWithSuccess(c, http.StatusOK, token, "Token refreshed successfully")
+}
+
+// @Summary      Log out
+// @Description  Revokes the session of the refresh token cookie, or of the access token if there is no cookie, and clears the cookie
+// @Tags         auth
+// @Produce      json
+// @Success      200 {object} model.ApiResponse "Logged out successfully"
+// @Failure      500 {object} model.ApiResponse "Internal server error"
+// @Router       /auth/logout [post]
+func LogoutHandler(c *gin.Context) {
+	query := database.DB.Model(&model.Session{}).Where("revoked_at IS NULL")
+
+	if refreshToken, err := c.Cookie(refreshTokenCookie); err == nil && refreshToken != "" {
+		query = query.Where("token_hash = ?", utils.HashToken(refreshToken))
+	} else if claims, err := utils.ValidateToken(strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")); err == nil {
+		query = query.Where("id = ? AND user_id = ?", claims.SessionID, claims.UserID)
+	} else {
+		clearRefreshCookie(c)
+		utils.RespondWithSuccess(c, http.StatusOK, nil, "Logged out successfully")
+		return
+	}
+
+	if err := query.Update("revoked_at", time.Now()).Error; err != nil {
+		utils.RespondWithInternalError(c, "Error logging out")
+		return
+	}
+
+	clearRefreshCookie(c)
+	utils.RespondWithSuccess(c, http.StatusOK, nil, "Logged out successfully")
+}
+
 // @Summary      Reset password request
-// @Description  Initiates the process to reset an account's password with a link sent via email
+// @Description  Initiates the process to reset an account's password with a link sent via email. Each IP and each account have a limit of requests
 // @Tags         auth
 // @Accept       json
 // @Produce      json
 // @Param        data body model.ForgotPasswordDTO true "User email"
 // @Success      200 {object} model.ApiResponse "Email sent successfully"
 // @Failure      400 {object} model.ApiResponse "Invalid request format"
+// @Failure      429 {object} model.ApiResponse "Too many attempts"
 // @Failure      500 {object} model.ApiResponse "Internal server error"
 // @Router       /auth/forgot [post]
 func ForgotPasswordHandler(c *gin.Context) {
@@ -156,14 +328,49 @@ func ForgotPasswordHandler(c *gin.Context) {
 		return
 	}
 
-	var err error
+	now := time.Now()
+	attempt, err := startLoginAttempt(c, model.LoginAttemptForgot, req.Email)
+	if err != nil {
+		utils.RespondWithInternalError(c, "Error sending reset email")
+		return
+	}
+	defer finishLoginAttempt(&attempt)
+
+	wait, err := ipRetryAfter(&attempt, maxForgotRequestsPerIP, false, now)
+	if err != nil {
+		utils.RespondWithInternalError(c, "Error sending reset email")
+		return
+	}
+	if wait > 0 {
+		attempt.Reason = attemptReasonIPLimited
+		respondTooManyAttempts(c, wait)
+		return
+	}
+
 	var user model.User
 
 	err = database.DB.Where("email = ? AND is_active = ?", req.Email, true).First(&user).Error
 	if err != nil {
+		attempt.Reason = attemptReasonUnknownEmail
 		utils.RespondWithInternalError(c, "Error sending reset email")
 		return
 	}
+	attempt.UserID = &user.ID
+
+	// Evita que se usen los correos de recuperación para saturar la bandeja de un usuario
+	var sent int64
+	err = database.DB.Model(&model.LoginAttempt{}).
+		Where("user_id = ? AND kind = ? AND success = ? AND created_at > ?", user.ID, model.LoginAttemptForgot, true, now.Add(-time.Hour)).
+		Count(&sent).Error
+	if err != nil {
+		utils.RespondWithInternalError(c, "Error sending reset email")
+		return
+	}
+	if sent >= maxResetEmailsPerHour {
+		attempt.Reason = attemptReasonAccountLimited
+		respondTooManyAttempts(c, time.Hour)
+		return
+	}
 
 	token, err := utils.GenerateSecureToken(32)
 	if err != nil {
@@ -196,6 +403,7 @@ func ForgotPasswordHandler(c *gin.Context) {
 		return
 	}
 
+	attempt.Success = true
 	utils.RespondWithSuccess(c, http.StatusOK, nil, "Reset email sent")
 }
 
@@ -241,11 +449,14 @@ func ResetPasswordHandler(c *gin.Context) {
 		return
 	}
 
+	// Recuperar la contraseña por correo también desbloquea la cuenta
 	user.PasswordHash = paswordHash
+	user.FailedLoginAttempts = 0
+	user.LockedUntil = nil
 	resetToken.IsUsed = true
 
-	database.DB.Transaction(func(tx *gorm.DB) error {
-		err = tx.Save(&user).Error
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Save(&user).Error
 		if err != nil {
 			return err
 		}
@@ -255,8 +466,75 @@ func ResetPasswordHandler(c *gin.Context) {
 			return err
 		}
 
-		return nil
+		// Al cambiar la contraseña se cierran las sesiones abiertas
+		return revokeSessions(tx, user.ID)
 	})
 
+	if err != nil {
+		utils.RespondWithInternalError(c, "Error resetting password")
+		return
+	}
+
 	utils.RespondWithSuccess(c, http.StatusOK, nil, "The user's password has been updated")
 }
+
+// Nombre de la cookie con el refresh token, solo se envía a las rutas de autenticación
+const refreshTokenCookie = "refresh_token"
+
+// Duración de una sesión desde que se inicia; renovar el token no la extiende
+const sessionDuration = 30 * 24 * time.Hour
+
+// Inicia una sesión para el usuario y envía su refresh token en una cookie HttpOnly
+// Retorna el token de acceso de la sesión
+func startSession(c *gin.Context, user *model.User) (string, error) {
+	refreshToken, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	session := model.Session{
+		UserID:     user.ID,
+		TokenHash:  utils.HashToken(refreshToken),
+		UserAgent:  truncate(c.Request.UserAgent(), 255),
+		IP:         c.ClientIP(),
+		ExpiresAt:  now.Add(sessionDuration),
+		LastUsedAt: now,
+	}
+	if err = database.DB.Create(&session).Error; err != nil {
+		return "", err
+	}
+
+	token, err := utils.GenerateToken(user, session.ID)
+	if err != nil {
+		return "", err
+	}
+
+	setRefreshCookie(c, refreshToken, session.ExpiresAt)
+	return token, nil
+}
+
+// Revoca todas las sesiones abiertas de un usuario
+func revokeSessions(tx *gorm.DB, userID uint) error {
+	return tx.Model(&model.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func setRefreshCookie(c *gin.Context, token string, expiresAt time.Time) {
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(refreshTokenCookie, token, int(time.Until(expiresAt).Seconds()), "/api/auth", "", true, true)
+}
+
+func clearRefreshCookie(c *gin.Context) {
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(refreshTokenCookie, "", -1, "/api/auth", "", true, true)
+}
+
+func truncate(value string, length int) string {
+	runes := []rune(value)
+	if len(runes) <= length {
+		return value
+	}
+	return string(runes[:length])
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"dapa/app/model"
+	"dapa/app/utils"
+	"dapa/database"
+	"fmt"
+	"net/http"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Lee los parámetros from, to, tz y granularity de una gráfica
+// Por defecto abarca los últimos meses indicados hasta hoy, agrupados por mes
+// Retorna false si algún parámetro no es válido, después de enviar la respuesta de error
+func parseChartRange(c *gin.Context, defaultMonths int) (model.ChartRange, bool) {
+	location, err := time.LoadLocation(c.DefaultQuery("tz", model.DefaultChartTimezone))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err, "Invalid timezone")
+		return model.ChartRange{}, false
+	}
+
+	to := time.Now().In(location)
+	if value := c.Query("to"); value != "" {
+		if to, err = time.ParseInLocation("2006-01-02", value, location); err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, err, "Invalid to date format")
+			return model.ChartRange{}, false
+		}
+	}
+
+	from := time.Date(to.Year(), to.Month()-time.Month(defaultMonths-1), 1, 0, 0, 0, 0, location)
+	if value := c.Query("from"); value != "" {
+		if from, err = time.ParseInLocation("2006-01-02", value, location); err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, err, "Invalid from date format")
+			return model.ChartRange{}, false
+		}
+	}
+
+	granularity := model.Granularity(c.DefaultQuery("granularity", string(model.GranularityMonth)))
+	chartRange, err := model.NewChartRange(from, to, granularity)
+	if err != nil {
+		utils.RespondWithCustomError(c, http.StatusBadRequest, err.Error(), "Invalid chart range")
+		return chartRange, false
+	}
+	return chartRange, true
+}
+
+// Construye la tabla de exportación de una serie con un valor por intervalo
+func bucketTable[T int | float64](chartRange model.ChartRange, header string, data []T) utils.Table {
+	table := utils.Table{Headers: []string{"Periodo", header}}
+	for i, start := range chartRange.Buckets() {
+		table.Rows = append(table.Rows, []any{chartRange.Label(start), data[i]})
+	}
+	return table
+}
+
+// Total de una gráfica en un día, agrupado en la base de datos
+type dailyTotal struct {
+	Day   string
+	Total float64
+}
+
+// Expresión con el día (YYYY-MM-DD) de una columna
+// Las columnas de fecha y hora se convierten a la zona horaria de la gráfica; las de tipo fecha se toman tal como están guardadas
+func dayExpr(column string, chartRange model.ChartRange, hasTime bool) clause.Expr {
+	if database.DB.Dialector.Name() == "sqlite" {
+		if !hasTime {
+			return gorm.Expr(fmt.Sprintf("strftime('%%Y-%%m-%%d', %s)", column))
+		}
+		// SQLite no conoce las zonas horarias, así que se usa el desfase vigente al inicio del rango
+		_, offset := chartRange.From.Zone()
+		return gorm.Expr(fmt.Sprintf("strftime('%%Y-%%m-%%d', %s, '%+d seconds')", column, offset))
+	}
+
+	if !hasTime {
+		return gorm.Expr(fmt.Sprintf("TO_CHAR(%s, 'YYYY-MM-DD')", column))
+	}
+	return gorm.Expr(fmt.Sprintf("TO_CHAR(%s AT TIME ZONE ?, 'YYYY-MM-DD')", column), chartRange.From.Location().String())
+}
+
+// Suma los totales diarios en el intervalo de la gráfica al que pertenece cada día
+func bucketDailyTotals[T int | float64](chartRange model.ChartRange, totals []dailyTotal) ([]T, error) {
+	data := make([]T, len(chartRange.Buckets()))
+	for _, total := range totals {
+		day, err := time.Parse("2006-01-02", total.Day)
+		if err != nil {
+			return nil, err
+		}
+
+		if i := chartRange.DateIndex(day); i >= 0 {
+			data[i] += T(total.Total)
+		}
+	}
+	return data, nil
+}
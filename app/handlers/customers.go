@@ -0,0 +1,349 @@
+package handlers
+
+import (
+	"dapa/app/model"
+	"dapa/app/utils"
+	"dapa/database"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// @Summary		Get all customers
+// @Description	Returns the active customers with their number of orders, optionally filtered by name or phone
+// @Tags		customers
+// @Produce		json
+// @Param       search query string false "Name or phone"
+// @Success		200	{object} model.ApiResponse "List of customers"
+// @Failure		500	{object} model.ApiResponse "Error fetching customers"
+// @Router		/customers [get]
+func GetCustomersHandler(c *gin.Context) {
+	query := database.DB.Where("is_active = ?", true)
+
+	if search := c.Query("search"); search != "" {
+		pattern := "%" + search + "%"
+		query = query.Where("LOWER(name) LIKE LOWER(?) OR phone LIKE ?", pattern, pattern)
+	}
+
+	var customers []model.Customer
+	if err := query.Order("name").Find(&customers).Error; err != nil {
+		utils.RespondWithInternalError(c, "Error fetching customers")
+		return
+	}
+
+	if err := countCustomerOrders(customers); err != nil {
+		utils.RespondWithInternalError(c, "Error fetching customers")
+		return
+	}
+
+	utils.RespondWithSuccess(c, http.StatusOK, customers, "Customers fetched successfully")
+}
+
+// @Summary		Get one customer by ID
+// @Description	Returns the customer with their addresses and number of orders
+// @Tags		customers
+// @Produce		json
+// @Param       id path int true "Customer ID"
+// @Success		200	{object} model.ApiResponse "Customer"
+// @Failure		404	{object} model.ApiResponse "Customer not found"
+// @Failure		500	{object} model.ApiResponse "Error fetching customer"
+// @Router		/customers/{id} [get]
+func GetCustomerHandler(c *gin.Context) {
+	var customer model.Customer
+	if !findCustomer(c, &customer) {
+		return
+	}
+
+	customers := []model.Customer{customer}
+	if err := countCustomerOrders(customers); err != nil {
+		utils.RespondWithInternalError(c, "Error fetching customer")
+		return
+	}
+
+	utils.RespondWithSuccess(c, http.StatusOK, customers[0], "Customer fetched successfully")
+}
+
+// @Summary		Creates a customer
+// @Description	Registers a new customer; the phone number identifies the customer and can't be repeated
+// @Tags		customers
+// @Accept		json
+// @Produce		json
+// @Param		customer body model.CustomerDTO true "Customer information"
+// @Success		201	{object} model.ApiResponse "Customer created successfully"
+// @Failure		400	{object} model.ApiResponse "Invalid request format"
+// @Failure		409	{object} model.ApiResponse "Customer already exists"
+// @Failure		500	{object} model.ApiResponse "Error creating customer"
+// @Router		/customers [post]
+func CreateCustomerHandler(c *gin.Context) {
+	var req model.CustomerDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+
+	phone := utils.NormalizePhone(req.Phone)
+	if phone == "" {
+		utils.RespondWithError(c, http.StatusBadRequest, nil, "Invalid phone number")
+		return
+	}
+
+	var customer model.Customer
+	err := database.DB.Where("phone = ?", phone).First(&customer).Error
+	if err == nil && customer.IsActive {
+		utils.RespondWithConflict(c, customer, "A customer with this phone already exists", "Customer already exists")
+		return
+	}
+
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.RespondWithInternalError(c, "Error creating customer")
+		return
+	}
+
+	// Un cliente eliminado con el mismo teléfono se reactiva en lugar de duplicarse
+	customer.IsActive = true
+	customer.DeletedAt = nil
+	if !saveCustomer(c, &customer, req, phone) {
+		return
+	}
+
+	utils.RespondWithSuccess(c, http.StatusCreated, customer, "Customer created successfully")
+}
+
+// @Summary		Updates a customer
+// @Description	Updates the customer's data and replaces their addresses
+// @Tags		customers
+// @Accept		json
+// @Produce		json
+// @Param       id path int true "Customer ID"
+// @Param		customer body model.CustomerDTO true "Customer information"
+// @Success		200	{object} model.ApiResponse "Customer updated successfully"
+// @Failure		400	{object} model.ApiResponse "Invalid request format"
+// @Failure		404	{object} model.ApiResponse "Customer not found"
+// @Failure		409	{object} model.ApiResponse "Customer already exists"
+// @Failure		500	{object} model.ApiResponse "Error updating customer"
+// @Router		/customers/{id} [put]
+func UpdateCustomerHandler(c *gin.Context) {
+	var req model.CustomerDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+
+	phone := utils.NormalizePhone(req.Phone)
+	if phone == "" {
+		utils.RespondWithError(c, http.StatusBadRequest, nil, "Invalid phone number")
+		return
+	}
+
+	var customer model.Customer
+	if !findCustomer(c, &customer) {
+		return
+	}
+
+	var duplicate model.Customer
+	err := database.DB.Where("phone = ? AND id <> ?", phone, customer.ID).Limit(1).Find(&duplicate).Error
+	if err != nil {
+		utils.RespondWithInternalError(c, "Error updating customer")
+		return
+	}
+
+	if duplicate.ID != 0 {
+		utils.RespondWithConflict(c, duplicate, "Another customer already uses this phone", "Customer already exists")
+		return
+	}
+
+	if !saveCustomer(c, &customer, req, phone) {
+		return
+	}
+
+	utils.RespondWithSuccess(c, http.StatusOK, customer, "Customer updated successfully")
+}
+
+// @Summary		Deletes a customer
+// @Description	Deactivates the customer; their orders keep the reference
+// @Tags		customers
+// @Produce		json
+// @Param       id path int true "Customer ID"
+// @Success		200	{object} model.ApiResponse "Customer deleted successfully"
+// @Failure		500	{object} model.ApiResponse "Error deleting customer"
+// @Router		/customers/{id} [delete]
+func DeleteCustomerHandler(c *gin.Context) {
+	err := database.DB.Model(&model.Customer{}).
+		Where("id = ?", c.Param("id")).
+		Updates(map[string]any{
+			"deleted_at": time.Now(),
+			"is_active":  false,
+		}).Error
+
+	if err != nil {
+		utils.RespondWithInternalError(c, "Error deleting customer")
+		return
+	}
+
+	utils.RespondWithSuccess(c, http.StatusOK, nil, "Customer deleted successfully")
+}
+
+// @Summary		Get the orders of a customer
+// @Description	Returns every order of the customer, newest first
+// @Tags		customers
+// @Produce		json
+// @Param       id path int true "Customer ID"
+// @Success		200	{object} model.ApiResponse "List of orders"
+// @Failure		404	{object} model.ApiResponse "Customer not found"
+// @Failure		500	{object} model.ApiResponse "Error fetching orders"
+// @Router		/customers/{id}/orders [get]
+func GetCustomerOrdersHandler(c *gin.Context) {
+	var customer model.Customer
+	if !findCustomer(c, &customer) {
+		return
+	}
+
+	var orders []model.Order
+	err := database.DB.Preload("Items").
+		Where("customer_id = ?", customer.ID).
+		Order("meeting_date DESC").
+		Find(&orders).Error
+	if err != nil {
+		utils.RespondWithInternalError(c, "Error fetching orders")
+		return
+	}
+
+	if err = computeOrderTotals(orders); err != nil {
+		utils.RespondWithInternalError(c, "Error fetching orders")
+		return
+	}
+
+	utils.RespondWithSuccess(c, http.StatusOK, orders, "Orders fetched successfully")
+}
+
+// Busca el cliente indicado en la ruta junto con sus direcciones
+// Retorna false si no existe, después de enviar la respuesta de error
+func findCustomer(c *gin.Context, customer *model.Customer) bool {
+	err := database.DB.Preload("Addresses").Where("id = ?", c.Param("id")).First(customer).Error
+	if err != nil {
+		utils.RespondWithCustomError(
+			c,
+			http.StatusNotFound,
+			"Customer not found",
+			"Something went wrong",
+		)
+		return false
+	}
+
+	return true
+}
+
+// Guarda los datos del cliente y reemplaza sus direcciones
+// Retorna false si falla el guardado, después de enviar la respuesta de error
+func saveCustomer(c *gin.Context, customer *model.Customer, dto model.CustomerDTO, phone string) bool {
+	customer.Name = dto.Name
+	customer.Phone = phone
+	customer.Email = dto.Email
+	customer.Notes = dto.Notes
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		customer.Addresses = nil
+		if txErr := tx.Save(customer).Error; txErr != nil {
+			return txErr
+		}
+
+		if txErr := tx.Where("customer_id = ?", customer.ID).Delete(&model.CustomerAddress{}).Error; txErr != nil {
+			return txErr
+		}
+
+		customer.Addresses = make([]model.CustomerAddress, len(dto.Addresses))
+		for i, address := range dto.Addresses {
+			customer.Addresses[i] = model.CustomerAddress{
+				CustomerID: customer.ID,
+				Label:      address.Label,
+				Address:    address.Address,
+			}
+		}
+
+		if len(customer.Addresses) == 0 {
+			return nil
+		}
+		return tx.Create(&customer.Addresses).Error
+	})
+
+	if err != nil {
+		utils.RespondWithInternalError(c, "Error saving customer")
+		return false
+	}
+
+	return true
+}
+
+// Calcula la cantidad de órdenes de cada cliente de la lista
+func countCustomerOrders(customers []model.Customer) error {
+	if len(customers) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, len(customers))
+	for i, customer := range customers {
+		ids[i] = customer.ID
+	}
+
+	var counts []struct {
+		CustomerID uint
+		Count      int64
+	}
+	err := database.DB.Model(&model.Order{}).
+		Select("customer_id, COUNT(*) AS count").
+		Where("customer_id IN ?", ids).
+		Group("customer_id").
+		Scan(&counts).Error
+	if err != nil {
+		return err
+	}
+
+	byCustomer := make(map[uint]int64, len(counts))
+	for _, count := range counts {
+		byCustomer[count.CustomerID] = count.Count
+	}
+
+	for i := range customers {
+		customers[i].OrdersCount = byCustomer[customers[i].ID]
+	}
+	return nil
+}
+
+// Obtiene el cliente con el teléfono indicado o lo registra si aún no existe
+// Un cliente eliminado se reactiva al volver a contratar
+// Retorna nil si el teléfono no contiene dígitos
+func resolveCustomer(tx *gorm.DB, name, phone string) (*uint, error) {
+	phone = utils.NormalizePhone(phone)
+	if phone == "" {
+		return nil, nil
+	}
+
+	var customer model.Customer
+	err := tx.Where("phone = ?", phone).First(&customer).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		customer = model.Customer{Name: name, Phone: phone, IsActive: true}
+		if err = tx.Create(&customer).Error; err != nil {
+			return nil, err
+		}
+		return &customer.ID, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !customer.IsActive {
+		err = tx.Model(&customer).Updates(map[string]any{
+			"deleted_at": nil,
+			"is_active":  true,
+		}).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &customer.ID, nil
+}
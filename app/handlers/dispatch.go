@@ -0,0 +1,586 @@
+package handlers
+
+import (
+	"dapa/app/model"
+	"dapa/app/utils"
+	"dapa/database"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Recursos que se asignan a una orden
+type orderAssignment struct {
+	UserID    *uint
+	VehicleID *uint
+	HelperID  *uint
+}
+
+// Asignación rechazada por datos inválidos o por conflictos de agenda
+type assignmentError struct {
+	problems  []string
+	conflicts []model.AssignmentConflictDTO
+}
+
+func (e *assignmentError) Error() string {
+	return "invalid assignment"
+}
+
+// Valida que el piloto, el ayudante y el vehículo puedan asignarse a una orden
+// Recibe la transacción, la orden, la asignación propuesta, la fecha de reunión y el peso de la carga a evaluar
+// Retorna los errores de validación, los conflictos de agenda y un error de base de datos
+func validateAssignment(tx *gorm.DB, order *model.Order, assignment orderAssignment, meetingDate time.Time, cargoKg float64) ([]string, []model.AssignmentConflictDTO, error) {
+	var problems []string
+
+	if assignment.UserID != nil && assignment.HelperID != nil && *assignment.UserID == *assignment.HelperID {
+		problems = append(problems, "Driver and helper must be different users")
+	}
+
+	if assignment.UserID != nil {
+		problem, err := validateStaffMember(tx, *assignment.UserID, "driver", model.PermissionSet.CanDrive)
+		if err != nil {
+			return nil, nil, err
+		}
+		if problem != "" {
+			problems = append(problems, problem)
+		}
+	}
+
+	if assignment.HelperID != nil {
+		problem, err := validateStaffMember(tx, *assignment.HelperID, "helper", model.PermissionSet.IsFieldStaff)
+		if err != nil {
+			return nil, nil, err
+		}
+		if problem != "" {
+			problems = append(problems, problem)
+		}
+	}
+
+	if assignment.VehicleID != nil {
+		var vehicle model.Vehicle
+		err := tx.Where("id = ?", *assignment.VehicleID).Limit(1).Find(&vehicle).Error
+		if err != nil {
+			return nil, nil, err
+		}
+
+		switch {
+		case vehicle.ID == 0 || !vehicle.IsActive:
+			problems = append(problems, fmt.Sprintf("Vehicle %d does not exist", *assignment.VehicleID))
+		case !vehicle.IsAvailable:
+			problems = append(problems, fmt.Sprintf("Vehicle %d is not available", vehicle.ID))
+		case cargoKg > vehicle.CapacityKg:
+			problems = append(problems, fmt.Sprintf("Cargo weight of %.2f kg exceeds the %.2f kg capacity of vehicle %d", cargoKg, vehicle.CapacityKg, vehicle.ID))
+		}
+	}
+
+	if len(problems) > 0 {
+		return problems, nil, nil
+	}
+
+	conflicts, err := findScheduleConflicts(tx, order.ID, assignment, meetingDate)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return nil, conflicts, nil
+}
+
+// Verifica que un usuario exista, esté activo y que su rol tenga los permisos de la función indicada
+// Retorna una descripción del problema o una cadena vacía si es válido
+func validateStaffMember(tx *gorm.DB, userID uint, function string, eligible func(model.PermissionSet) bool) (string, error) {
+	var user model.User
+	err := tx.Where("id = ?", userID).Limit(1).Find(&user).Error
+	if err != nil {
+		return "", err
+	}
+
+	if user.ID == 0 || !user.IsActive {
+		return fmt.Sprintf("User %d does not exist or is inactive", userID), nil
+	}
+
+	permissions, err := findRolePermissions(tx, user.Role)
+	if err != nil {
+		return "", err
+	}
+
+	if !eligible(permissions) {
+		return fmt.Sprintf("User %d does not have the %s role", userID, function), nil
+	}
+
+	return "", nil
+}
+
+// Busca otras órdenes del mismo día que ya utilicen alguno de los recursos
+// Las órdenes canceladas o fallidas no ocupan la agenda
+// Retorna la lista de conflictos encontrados
+func findScheduleConflicts(tx *gorm.DB, orderID uint, assignment orderAssignment, meetingDate time.Time) ([]model.AssignmentConflictDTO, error) {
+	dayStart, dayEnd := utils.DayBounds(meetingDate)
+
+	var orders []model.Order
+	err := tx.
+		Where("id <> ? AND meeting_date >= ? AND meeting_date < ?", orderID, dayStart, dayEnd).
+		Where("status NOT IN ?", []model.OrderStatus{model.OrderStatusCancelled, model.OrderStatusFailed, model.OrderStatusNoShow}).
+		Find(&orders).Error
+	if err != nil {
+		return nil, err
+	}
+
+	sameID := func(a, b *uint) bool {
+		return a != nil && b != nil && *a == *b
+	}
+
+	var conflicts []model.AssignmentConflictDTO
+	for _, other := range orders {
+		// Un miembro del personal no puede estar en dos órdenes el mismo día, sin importar su función
+		for _, member := range []struct {
+			resource string
+			id       *uint
+		}{
+			{"driver", assignment.UserID},
+			{"helper", assignment.HelperID},
+		} {
+			if sameID(member.id, other.UserID) || sameID(member.id, other.HelperID) {
+				conflicts = append(conflicts, model.AssignmentConflictDTO{
+					Resource:    member.resource,
+					ResourceID:  *member.id,
+					OrderID:     other.ID,
+					MeetingDate: other.MeetingDate,
+				})
+			}
+		}
+
+		if sameID(assignment.VehicleID, other.VehicleID) {
+			conflicts = append(conflicts, model.AssignmentConflictDTO{
+				Resource:    "vehicle",
+				ResourceID:  *assignment.VehicleID,
+				OrderID:     other.ID,
+				MeetingDate: other.MeetingDate,
+			})
+		}
+	}
+
+	return conflicts, nil
+}
+
+// Bloquea al personal y al vehículo de la asignación hasta que termine la transacción
+// Así dos asignaciones simultáneas de los mismos recursos se validan una después de la otra
+func lockAssignment(tx *gorm.DB, assignment orderAssignment) error {
+	var userIDs []uint
+	for _, id := range []*uint{assignment.UserID, assignment.HelperID} {
+		if id != nil {
+			userIDs = append(userIDs, *id)
+		}
+	}
+
+	locking := tx.Clauses(clause.Locking{Strength: "UPDATE"})
+	if len(userIDs) > 0 {
+		var users []model.User
+		if err := locking.Where("id IN ?", userIDs).Order("id").Find(&users).Error; err != nil {
+			return err
+		}
+	}
+
+	if assignment.VehicleID != nil {
+		var vehicles []model.Vehicle
+		if err := locking.Where("id = ?", *assignment.VehicleID).Find(&vehicles).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Valida una asignación dentro de la transacción que la guarda, con sus recursos bloqueados
+// Retorna un assignmentError si la asignación no es válida
+func checkAssignment(tx *gorm.DB, order *model.Order, assignment orderAssignment, meetingDate time.Time, cargoKg float64) error {
+	if err := lockAssignment(tx, assignment); err != nil {
+		return err
+	}
+
+	problems, conflicts, err := validateAssignment(tx, order, assignment, meetingDate, cargoKg)
+	if err != nil {
+		return err
+	}
+
+	if len(problems) > 0 || len(conflicts) > 0 {
+		return &assignmentError{problems: problems, conflicts: conflicts}
+	}
+	return nil
+}
+
+// Envía la respuesta de una asignación rechazada
+func respondAssignmentError(c *gin.Context, rejected *assignmentError) {
+	if len(rejected.problems) > 0 {
+		utils.RespondWithErrors(c, http.StatusBadRequest, rejected.problems, "Invalid assignment")
+		return
+	}
+
+	utils.RespondWithConflict(c, rejected.conflicts, "Resources already booked on the meeting date", "Scheduling conflict")
+}
+
+// Días de anticipación con los que se penaliza una licencia o seguro por vencer
+const expiryWarningDays = 30
+
+// Cantidad de candidatos por recurso que se combinan al generar sugerencias
+const candidatesPerResource = 3
+
+// Puntos que pierde un candidato por cada orden que ya tiene en la semana del servicio
+const workloadPenalty = 5
+
+// @Summary		Get assignment suggestions for an order
+// @Description	Ranks available driver, helper and vehicle combinations using the workload of the service date, vehicle capacity, license and insurance validity
+// @Tags		orders
+// @Produce		json
+// @Param       id path int true "Order ID"
+// @Param       limit query int false "Maximum number of suggestions (default 5)"
+// @Success		200	{object} model.ApiResponse "Assignment suggestions"
+// @Failure		404	{object} model.ApiResponse "Order not found"
+// @Failure		500	{object} model.ApiResponse "Error generating suggestions"
+// @Router		/orders/{id}/assignment-suggestions [get]
+func GetAssignmentSuggestionsHandler(c *gin.Context) {
+	id := c.Param("id")
+
+	var order model.Order
+	if err := database.DB.Where("id = ?", id).First(&order).Error; err != nil {
+		utils.RespondWithCustomError(
+			c,
+			http.StatusNotFound,
+			"Order not found",
+			"Something went wrong",
+		)
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "5"))
+	if err != nil || limit <= 0 {
+		utils.RespondWithCustomError(c, http.StatusBadRequest, "Limit must be a positive integer", "Invalid request format")
+		return
+	}
+
+	suggestions, err := suggestAssignments(&order)
+	if err != nil {
+		utils.RespondWithInternalError(c, "Error generating suggestions")
+		return
+	}
+
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+
+	utils.RespondWithSuccess(c, http.StatusOK, suggestions, "Assignment suggestions generated successfully")
+}
+
+// @Summary		Automatically assigns pending orders
+// @Description	Applies the best assignment suggestion to every pending order scheduled on the given day
+// @Tags		orders
+// @Accept		json
+// @Produce		json
+// @Param		body body model.AutoAssignDTO true "Day to dispatch"
+// @Success		200	{object} model.ApiResponse "Result for each pending order"
+// @Failure		400	{object} model.ApiResponse "Invalid request format"
+// @Failure		500	{object} model.ApiResponse "Error assigning orders"
+// @Router		/orders/auto-assign [post]
+func AutoAssignOrdersHandler(c *gin.Context) {
+	var req model.AutoAssignDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+
+	claims := c.MustGet("claims").(*model.EmployeeClaims)
+	dayStart, dayEnd := utils.DayBounds(req.Date)
+
+	var orders []model.Order
+	err := database.DB.
+		Where("status = ? AND meeting_date >= ? AND meeting_date < ?", model.OrderStatusPending, dayStart, dayEnd).
+		Order("id ASC").
+		Find(&orders).Error
+	if err != nil {
+		utils.RespondWithInternalError(c, "Error fetching pending orders")
+		return
+	}
+
+	results := []model.AutoAssignResultDTO{}
+	for i := range orders {
+		order := &orders[i]
+
+		// Cada asignación se guarda antes de evaluar la siguiente orden,
+		// por lo que los recursos ya utilizados quedan ocupados para el resto del día
+		suggestions, err := suggestAssignments(order)
+		if err != nil {
+			utils.RespondWithInternalError(c, "Error generating suggestions")
+			return
+		}
+
+		if len(suggestions) == 0 {
+			results = append(results, model.AutoAssignResultDTO{
+				OrderID: order.ID,
+				Reason:  "No available driver, helper and vehicle combination",
+			})
+			continue
+		}
+
+		cargoKg, err := orderCargoKg(order.ID)
+		if err != nil {
+			utils.RespondWithInternalError(c, "Error assigning orders")
+			return
+		}
+
+		best := suggestions[0]
+		assignment := orderAssignment{
+			UserID:    &best.Driver.ID,
+			VehicleID: &best.Vehicle.ID,
+			HelperID:  &best.Helper.ID,
+		}
+
+		// Otra asignación pudo ocupar los recursos o cambiar la orden después de generar las sugerencias
+		err = database.DB.Transaction(func(tx *gorm.DB) error {
+			if txErr := checkAssignment(tx, order, assignment, order.MeetingDate, cargoKg); txErr != nil {
+				return txErr
+			}
+			return assignOrder(tx, order, assignment, claims.UserID)
+		})
+
+		var rejected *assignmentError
+		switch {
+		case errors.As(err, &rejected), errors.Is(err, errOrderStatusChanged):
+			results = append(results, model.AutoAssignResultDTO{
+				OrderID: order.ID,
+				Reason:  "The suggested resources or the order changed during the assignment",
+			})
+			continue
+		case err != nil:
+			utils.RespondWithInternalError(c, "Error assigning orders")
+			return
+		}
+
+		results = append(results, model.AutoAssignResultDTO{
+			OrderID:    order.ID,
+			Assignment: &best,
+		})
+	}
+
+	utils.RespondWithSuccess(c, http.StatusOK, results, "Pending orders processed successfully")
+}
+
+// Guarda la asignación de la orden y la pasa a asignada, solo si sigue en el estado que se leyó
+// Si el estado cambió, registra el cambio en el historial
+func assignOrder(tx *gorm.DB, order *model.Order, assignment orderAssignment, userID uint) error {
+	result := tx.Model(&model.Order{}).Where("id = ? AND status = ?", order.ID, order.Status).Updates(map[string]any{
+		"user_id":    assignment.UserID,
+		"vehicle_id": assignment.VehicleID,
+		"helper_id":  assignment.HelperID,
+		"status":     model.OrderStatusAssigned,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected != 1 {
+		return errOrderStatusChanged
+	}
+
+	previousStatus := order.Status
+	order.UserID = assignment.UserID
+	order.VehicleID = assignment.VehicleID
+	order.HelperID = assignment.HelperID
+	order.Status = model.OrderStatusAssigned
+
+	if previousStatus == order.Status {
+		return nil
+	}
+	return recordOrderStatus(tx, order.ID, previousStatus, order.Status, userID, nil)
+}
+
+// Peso estimado de la carga de una orden según sus artículos
+func orderCargoKg(orderID uint) (float64, error) {
+	var total float64
+	err := database.DB.Model(&model.OrderItem{}).
+		Where("order_id = ?", orderID).
+		Select("COALESCE(SUM(quantity * weight_kg), 0)").
+		Row().
+		Scan(&total)
+	return total, err
+}
+
+// Genera las combinaciones de piloto, ayudante y vehículo disponibles para una orden
+// Retorna las sugerencias ordenadas de mejor a peor puntaje
+func suggestAssignments(order *model.Order) ([]model.AssignmentSuggestionDTO, error) {
+	dayStart, dayEnd := utils.DayBounds(order.MeetingDate)
+
+	// Nadie puede estar en dos órdenes el mismo día, así que quien ya tiene carga ese día queda descartado
+	staffLoad, vehicleLoad, err := scheduledWorkload(order, dayStart, dayEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	// Entre los disponibles se prefiere a quien tiene menos órdenes en la semana del servicio, para repartir la carga
+	weekStart := dayStart.AddDate(0, 0, -((int(dayStart.Weekday()) + 6) % 7))
+	staffWeek, vehicleWeek, err := scheduledWorkload(order, weekStart, weekStart.AddDate(0, 0, 7))
+	if err != nil {
+		return nil, err
+	}
+
+	warningLimit := dayStart.AddDate(0, 0, expiryWarningDays)
+
+	roles, err := allRolePermissions()
+	if err != nil {
+		return nil, err
+	}
+
+	var staffRoles []string
+	for name, permissions := range roles {
+		if permissions.IsFieldStaff() {
+			staffRoles = append(staffRoles, name)
+		}
+	}
+
+	var staff []model.User
+	err = database.DB.Where("is_active = ? AND role IN ?", true, staffRoles).Order("id").Find(&staff).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var drivers, helpers []model.SuggestedResourceDTO
+	for _, user := range staff {
+		if staffLoad[user.ID] > 0 {
+			continue
+		}
+
+		candidate := model.SuggestedResourceDTO{
+			ID:       user.ID,
+			Name:     user.Name + " " + user.LastName,
+			Score:    100 - workloadPenalty*float64(staffWeek[user.ID]),
+			Workload: staffWeek[user.ID],
+		}
+
+		if !roles[user.Role].CanDrive() {
+			helpers = append(helpers, candidate)
+			continue
+		}
+
+		if user.LicenseExpirationDate.Before(dayStart) {
+			continue
+		}
+		if user.LicenseExpirationDate.Before(warningLimit) {
+			candidate.Score -= 15
+			candidate.Notes = append(candidate.Notes, "License expires within 30 days")
+		}
+		drivers = append(drivers, candidate)
+	}
+
+	var vehicles []model.Vehicle
+	err = database.DB.Where("is_active = ? AND is_available = ?", true, true).Order("id").Find(&vehicles).Error
+	if err != nil {
+		return nil, err
+	}
+
+	cargoKg, err := orderCargoKg(order.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var vehicleCandidates []model.SuggestedResourceDTO
+	for _, vehicle := range vehicles {
+		if vehicleLoad[vehicle.ID] > 0 || vehicle.InsuranceDate.Before(dayStart) || vehicle.CapacityKg < cargoKg {
+			continue
+		}
+
+		candidate := model.SuggestedResourceDTO{
+			ID:       vehicle.ID,
+			Name:     vehicle.Brand + " " + vehicle.Model + " (" + vehicle.LicensePlate + ")",
+			Score:    100 - workloadPenalty*float64(vehicleWeek[vehicle.ID]),
+			Workload: vehicleWeek[vehicle.ID],
+		}
+
+		// Se prefiere el vehículo cuya capacidad se ajusta mejor a la carga
+		if cargoKg > 0 && vehicle.CapacityKg > 0 {
+			candidate.Score -= 20 * (1 - cargoKg/vehicle.CapacityKg)
+		}
+		if vehicle.InsuranceDate.Before(warningLimit) {
+			candidate.Score -= 15
+			candidate.Notes = append(candidate.Notes, "Insurance expires within 30 days")
+		}
+		vehicleCandidates = append(vehicleCandidates, candidate)
+	}
+
+	drivers = topCandidates(drivers)
+	helpers = topCandidates(helpers)
+	vehicleCandidates = topCandidates(vehicleCandidates)
+
+	suggestions := []model.AssignmentSuggestionDTO{}
+	for _, driver := range drivers {
+		for _, helper := range helpers {
+			for _, vehicle := range vehicleCandidates {
+				suggestions = append(suggestions, model.AssignmentSuggestionDTO{
+					Score:   math.Round((driver.Score+helper.Score+vehicle.Score)/3*100) / 100,
+					Driver:  driver,
+					Helper:  helper,
+					Vehicle: vehicle,
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].Score > suggestions[j].Score
+	})
+
+	return suggestions, nil
+}
+
+// Cuenta las otras órdenes del rango indicado en las que participa cada persona y vehículo
+// Las órdenes canceladas o fallidas no ocupan la agenda
+// Retorna la carga de trabajo por usuario y por vehículo
+func scheduledWorkload(order *model.Order, from, to time.Time) (map[uint]int, map[uint]int, error) {
+	var orders []model.Order
+	err := database.DB.
+		Where("id <> ? AND meeting_date >= ? AND meeting_date < ?", order.ID, from, to).
+		Where("status NOT IN ?", []model.OrderStatus{model.OrderStatusCancelled, model.OrderStatusFailed, model.OrderStatusNoShow}).
+		Find(&orders).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	staffLoad := map[uint]int{}
+	vehicleLoad := map[uint]int{}
+	for _, o := range orders {
+		if o.UserID != nil {
+			staffLoad[*o.UserID]++
+		}
+		if o.HelperID != nil {
+			staffLoad[*o.HelperID]++
+		}
+		if o.VehicleID != nil {
+			vehicleLoad[*o.VehicleID]++
+		}
+	}
+
+	return staffLoad, vehicleLoad, nil
+}
+
+// Ordena los candidatos por puntaje y conserva únicamente los mejores
+// Los empates se resuelven por carga de trabajo y luego por ID, así las sugerencias no cambian entre consultas
+func topCandidates(candidates []model.SuggestedResourceDTO) []model.SuggestedResourceDTO {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		if candidates[i].Workload != candidates[j].Workload {
+			return candidates[i].Workload < candidates[j].Workload
+		}
+		return candidates[i].ID < candidates[j].ID
+	})
+
+	if len(candidates) > candidatesPerResource {
+		return candidates[:candidatesPerResource]
+	}
+	return candidates
+}
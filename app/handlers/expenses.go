@@ -4,11 +4,24 @@ import (
 	"dapa/app/model"
 	"dapa/app/utils"
 	"dapa/database"
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
+// Filtros disponibles al consultar gastos y la columna que corresponde a cada uno
+var expenseFilters = []struct {
+	param  string
+	column string
+}{
+	{"orderId", "order_id"},
+	{"vehicleId", "vehicle_id"},
+	{"userId", "user_id"},
+}
+
 // @Summary		Get all expense types
 // @Description	Returns a list of all expense types
 // @Tags		expense-types
@@ -130,41 +143,88 @@ func DeleteExpenseType(c *gin.Context) {
 }
 
 // @Summary		Get all expenses
-// @Description	Returns a list of all expenses
+// @Description	Returns a list of all expenses, optionally filtered by order, vehicle or driver
 // @Tags		expenses
 // @Produce		json
+// @Produce		text/csv
+// @Produce		application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param		orderId query int false "Order ID"
+// @Param		vehicleId query int false "Vehicle ID"
+// @Param		userId query int false "User ID"
+// @Param		format query string false "Export format" Enums(json, csv, xlsx)
 // @Success		200	{object} model.ApiResponse "List of expenses"
+// @Failure		400	{object} model.ApiResponse "Invalid filter"
 // @Failure		500	{object} model.ApiResponse "Error retrieving expenses"
 // @Router		/expenses [get]
 func GetExpenses(c *gin.Context) {
+	query := database.DB.Model(&model.Expense{}).Preload("Type")
+	for _, filter := range expenseFilters {
+		value := c.Query(filter.param)
+		if value == "" {
+			continue
+		}
+
+		id, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, err, fmt.Sprintf("Invalid %s filter", filter.param))
+			return
+		}
+		query = query.Where(filter.column+" = ?", id)
+	}
+
 	var expenses []model.Expense
-	if err := database.DB.Find(&expenses).Error; err != nil {
+	if err := query.Find(&expenses).Error; err != nil {
 		utils.RespondWithInternalError(c, "Error fetching expenses")
 		return
 	}
-	utils.RespondWithSuccess(c, http.StatusOK, expenses, "Expenses fetched successfully")
+	utils.RespondWithExport(c, expenses, expensesExport(expenses), "Expenses fetched successfully", "egresos")
+}
+
+// Filas de la exportación de gastos con el nombre de su tipo
+func expensesExport(expenses []model.Expense) []model.ExpenseExportDTO {
+	rows := make([]model.ExpenseExportDTO, len(expenses))
+	for i, expense := range expenses {
+		rows[i] = model.ExpenseExportDTO{
+			ID:               expense.ID,
+			Date:             expense.Date,
+			Type:             expense.Type.Type,
+			TemporalEmployee: expense.TemporalEmployee,
+			Description:      expense.Description,
+			Amount:           expense.Amount,
+			OrderID:          expense.OrderID,
+			VehicleID:        expense.VehicleID,
+			UserID:           expense.UserID,
+		}
+	}
+	return rows
 }
 
 // @Summary		Get expense by ID
-// @Description	Returns a single expense by its ID
+// @Description	Returns a single expense by its ID with its receipts
 // @Tags		expenses
 // @Produce		json
 // @Param		id path int true "Expense ID"
 // @Success		200	{object} model.ApiResponse "Expense"
 // @Failure		404	{object} model.ApiResponse "Expense not found"
+// @Failure		500	{object} model.ApiResponse "Error fetching receipts"
 // @Router		/expenses/{id} [get]
 func GetExpense(c *gin.Context) {
 	id := c.Param("id")
 	var expense model.Expense
-	if err := database.DB.First(&expense, id).Error; err != nil {
+	if err := database.DB.Preload("Receipts").First(&expense, id).Error; err != nil {
 		utils.RespondWithCustomError(c, http.StatusNotFound, "Expense not found", "No se encontró el egreso")
 		return
 	}
+
+	if err := signAttachments(expense.Receipts); err != nil {
+		utils.RespondWithInternalError(c, "Error fetching receipts")
+		return
+	}
 	utils.RespondWithSuccess(c, http.StatusOK, expense, "Expense fetched successfully")
 }
 
 // @Summary		Create expense
-// @Description	Creates a new expense
+// @Description	Creates a new expense, optionally attributed to an order, vehicle or driver
 // @Tags		expenses
 // @Accept		json
 // @Produce		json
@@ -180,12 +240,19 @@ func CreateExpense(c *gin.Context) {
 		return
 	}
 
+	if !validateExpenseReferences(c, dto) {
+		return
+	}
+
 	expense := model.Expense{
 		Date:             dto.Date,
 		TypeID:           dto.TypeID,
 		TemporalEmployee: dto.TemporalEmployee,
 		Description:      dto.Description,
 		Amount:           dto.Amount,
+		OrderID:          dto.OrderID,
+		VehicleID:        dto.VehicleID,
+		UserID:           dto.UserID,
 	}
 
 	if err := database.DB.Create(&expense).Error; err != nil {
@@ -221,11 +288,18 @@ func UpdateExpense(c *gin.Context) {
 		return
 	}
 
+	if !validateExpenseReferences(c, dto) {
+		return
+	}
+
 	expense.Date = dto.Date
 	expense.TypeID = dto.TypeID
 	expense.TemporalEmployee = dto.TemporalEmployee
 	expense.Description = dto.Description
 	expense.Amount = dto.Amount
+	expense.OrderID = dto.OrderID
+	expense.VehicleID = dto.VehicleID
+	expense.UserID = dto.UserID
 
 	if err := database.DB.Save(&expense).Error; err != nil {
 		utils.RespondWithInternalError(c, "Error updating expense")
@@ -235,7 +309,7 @@ func UpdateExpense(c *gin.Context) {
 }
 
 // @Summary		Delete expense
-// @Description	Deletes an expense by its ID
+// @Description	Deletes an expense by its ID along with its receipts
 // @Tags		expenses
 // @Produce		json
 // @Param		id path int true "Expense ID"
@@ -251,9 +325,58 @@ func DeleteExpense(c *gin.Context) {
 		return
 	}
 
-	if err := database.DB.Delete(&expense).Error; err != nil {
+	var receipts []model.Attachment
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var txErr error
+		if receipts, txErr = deleteOwnerAttachments(tx, model.AttachmentOwnerExpense, expense.ID); txErr != nil {
+			return txErr
+		}
+		return tx.Delete(&expense).Error
+	})
+
+	if err != nil {
 		utils.RespondWithInternalError(c, "Error deleting expense")
 		return
 	}
+
+	deleteStoredFiles(receipts)
 	utils.RespondWithSuccess(c, http.StatusOK, nil, "Expense deleted successfully")
 }
+
+// Verifica que la orden, el vehículo y el usuario indicados en el gasto existan
+// Retorna false si alguno no existe, después de enviar la respuesta de error
+func validateExpenseReferences(c *gin.Context, dto model.ExpenseDTO) bool {
+	references := []struct {
+		id     *uint
+		entity any
+		name   string
+	}{
+		{dto.OrderID, &model.Order{}, "Order"},
+		{dto.VehicleID, &model.Vehicle{}, "Vehicle"},
+		{dto.UserID, &model.User{}, "User"},
+	}
+
+	var errs []string
+	for _, reference := range references {
+		if reference.id == nil {
+			continue
+		}
+
+		var count int64
+		if err := database.DB.Model(reference.entity).Where("id = ?", *reference.id).Count(&count).Error; err != nil {
+			utils.RespondWithInternalError(c, "Error validating expense")
+			return false
+		}
+
+		if count == 0 {
+			errs = append(errs, fmt.Sprintf("%s %d doesn't exist", reference.name, *reference.id))
+		}
+	}
+
+	if len(errs) > 0 {
+		utils.RespondWithErrors(c, http.StatusBadRequest, errs, "Invalid request body")
+		return false
+	}
+
+	return true
+}
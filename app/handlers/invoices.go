@@ -0,0 +1,317 @@
+package handlers
+
+import (
+	"dapa/app/model"
+	"dapa/app/utils"
+	"dapa/database"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Posición vertical a partir de la cual el contenido continúa en una nueva página
+const invoicePageBottom = 790.0
+
+var paymentMethodLabels = map[model.PaymentMethod]string{
+	model.PaymentMethodCash:     "Efectivo",
+	model.PaymentMethodTransfer: "Transferencia",
+	model.PaymentMethodCard:     "Tarjeta",
+	model.PaymentMethodUnknown:  "No registrado",
+}
+
+var stopTypeLabels = map[model.StopType]string{
+	model.StopTypePickup:  "Recolección",
+	model.StopTypeDropoff: "Entrega",
+}
+
+// Datos necesarios para generar el documento de una factura
+type invoiceData struct {
+	invoice  model.Invoice
+	order    model.Order
+	lines    []model.InvoiceLine
+	stops    []model.OrderStop
+	payments []model.Payment
+}
+
+// @Summary		Get the invoice of an order
+// @Description	Returns the invoice of a delivered order as a PDF; it's issued with the next number the first time it's requested
+// @Tags		orders
+// @Produce		application/pdf
+// @Param       id path int true "Order ID"
+// @Success		200	{file} file "Invoice document"
+// @Failure		404	{object} model.ApiResponse "Order not found"
+// @Failure		409	{object} model.ApiResponse "Order not delivered"
+// @Failure		500	{object} model.ApiResponse "Error generating invoice"
+// @Router		/orders/{id}/invoice.pdf [get]
+func GetOrderInvoiceHandler(c *gin.Context) {
+	claims := c.MustGet("claims").(*model.EmployeeClaims)
+
+	var order model.Order
+	if err := database.DB.Preload("Items").Where("id = ?", c.Param("id")).First(&order).Error; err != nil {
+		utils.RespondWithCustomError(
+			c,
+			http.StatusNotFound,
+			"Order not found",
+			"Something went wrong",
+		)
+		return
+	}
+
+	var invoice model.Invoice
+	if err := database.DB.Where("order_id = ?", order.ID).Limit(1).Find(&invoice).Error; err != nil {
+		utils.RespondWithInternalError(c, "Error fetching invoice")
+		return
+	}
+
+	if invoice.ID == 0 {
+		if order.Status != model.OrderStatusDelivered {
+			utils.RespondWithCustomError(
+				c,
+				http.StatusConflict,
+				"Invoices can only be issued for delivered orders",
+				"Order not delivered",
+			)
+			return
+		}
+
+		var err error
+		invoice, err = issueInvoice(&order, claims.UserID)
+		if err != nil {
+			utils.RespondWithInternalError(c, "Error generating invoice")
+			return
+		}
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="factura-%06d.pdf"`, invoice.Number))
+	c.Data(http.StatusOK, "application/pdf", invoice.Document)
+}
+
+// Emite la factura de una orden con el siguiente número de la secuencia
+// El número se reserva en la misma transacción, por lo que un error no deja saltos en la numeración
+// Las emisiones esperan el bloqueo de la secuencia, así que si otra petición ya emitió la factura de la orden se retorna esa
+func issueInvoice(order *model.Order, userID uint) (model.Invoice, error) {
+	taxRate, err := strconv.ParseFloat(utils.EnvGet("INVOICE_TAX_RATE", "0.12"), 64)
+	if err != nil {
+		return model.Invoice{}, err
+	}
+
+	data := invoiceData{order: *order}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var sequence model.InvoiceSequence
+		txErr := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			FirstOrCreate(&sequence, model.InvoiceSequence{ID: 1}).Error
+		if txErr != nil {
+			return txErr
+		}
+
+		if txErr = tx.Where("order_id = ?", order.ID).Limit(1).Find(&data.invoice).Error; txErr != nil || data.invoice.ID != 0 {
+			return txErr
+		}
+
+		if data.stops, txErr = loadOrderStops(tx, order); txErr != nil {
+			return txErr
+		}
+
+		if txErr = tx.Scopes(model.ActivePayments).Where("order_id = ?", order.ID).Order("paid_at, id").Find(&data.payments).Error; txErr != nil {
+			return txErr
+		}
+
+		if data.lines, txErr = invoiceLines(tx, order); txErr != nil {
+			return txErr
+		}
+
+		sequence.LastNumber++
+		if txErr = tx.Save(&sequence).Error; txErr != nil {
+			return txErr
+		}
+
+		subtotal, tax := model.SplitTax(order.TotalAmount, taxRate)
+		data.invoice = model.Invoice{
+			Number:      sequence.LastNumber,
+			OrderID:     order.ID,
+			ClientName:  order.ClientName,
+			ClientPhone: order.ClientPhone,
+			Subtotal:    subtotal,
+			TaxRate:     taxRate,
+			TaxAmount:   tax,
+			Total:       order.TotalAmount,
+			IssuedBy:    &userID,
+			IssuedAt:    time.Now(),
+		}
+		data.invoice.Document = renderInvoice(data)
+
+		return tx.Create(&data.invoice).Error
+	})
+
+	return data.invoice, err
+}
+
+// Obtiene las líneas de cobro de la orden
+// Si la orden proviene de una cotización se usa su desglose; si no, se cobra el servicio en una sola línea
+// La diferencia con el total de la orden se agrega como ajuste
+func invoiceLines(tx *gorm.DB, order *model.Order) ([]model.InvoiceLine, error) {
+	var lines []model.InvoiceLine
+	if order.QuoteID != nil {
+		var quote model.Quote
+		err := tx.Where("id = ?", *order.QuoteID).Limit(1).Find(&quote).Error
+		if err != nil {
+			return nil, err
+		}
+
+		if quote.ID != 0 {
+			lines = quote.InvoiceLines()
+		}
+	}
+
+	if len(lines) == 0 {
+		return []model.InvoiceLine{{Description: "Servicio de " + order.Type, Amount: order.TotalAmount}}, nil
+	}
+
+	var sum float64
+	for _, line := range lines {
+		sum += line.Amount
+	}
+
+	if difference := math.Round((order.TotalAmount-sum)*100) / 100; difference != 0 {
+		lines = append(lines, model.InvoiceLine{Description: "Ajuste acordado", Amount: difference})
+	}
+	return lines, nil
+}
+
+// Dibuja el documento de la factura
+func renderInvoice(data invoiceData) []byte {
+	w := &invoiceWriter{doc: utils.NewPDFDocument(), y: 60}
+	invoice, order := data.invoice, data.order
+
+	w.doc.Text(50, w.y, 18, utils.PDFFontBold, utils.EnvGet("COMPANY_NAME", "DAPA"))
+	w.doc.Text(380, w.y, 12, utils.PDFFontBold, fmt.Sprintf("Recibo No. %06d", invoice.Number))
+	w.y += 16
+	w.doc.Text(380, w.y, 9, utils.PDFFontRegular, "Fecha de emisión: "+invoice.IssuedAt.Format("02/01/2006"))
+	for _, key := range []string{"COMPANY_TAX_ID", "COMPANY_ADDRESS", "COMPANY_PHONE", "COMPANY_EMAIL"} {
+		if value := utils.EnvGet(key, ""); value != "" {
+			w.doc.Text(50, w.y, 9, utils.PDFFontRegular, value)
+			w.y += 12
+		}
+	}
+
+	w.y += 16
+	w.rule()
+
+	w.heading("Cliente")
+	w.row(order.ClientName, "")
+	if order.ClientPhone != "" {
+		w.row("Teléfono: "+order.ClientPhone, "")
+	}
+
+	w.heading(fmt.Sprintf("Orden #%d", order.ID))
+	w.row(fmt.Sprintf("Servicio de %s del %s", order.Type, order.MeetingDate.Format("02/01/2006")), "")
+	for _, stop := range data.stops {
+		w.row(fmt.Sprintf("%d. %s: %s", stop.Position, stopTypeLabels[stop.Type], stop.Address), "")
+	}
+
+	if len(order.Items) > 0 {
+		w.heading("Carga")
+		for _, item := range order.Items {
+			w.row(fmt.Sprintf("%d x %s (%.1f kg)", item.Quantity, item.Description, item.TotalWeightKg()), "")
+		}
+	}
+
+	w.heading("Detalle")
+	for _, line := range data.lines {
+		w.row(line.Description, formatMoney(line.Amount))
+	}
+	w.rule()
+	w.row("Subtotal", formatMoney(invoice.Subtotal))
+	w.row(fmt.Sprintf("IVA (%g%%)", invoice.TaxRate*100), formatMoney(invoice.TaxAmount))
+	w.total("Total", formatMoney(invoice.Total))
+
+	w.heading("Pagos")
+	var paid float64
+	for _, payment := range data.payments {
+		description := payment.PaidAt.Format("02/01/2006") + " - " + paymentMethodLabels[payment.Method]
+		if payment.Reference != nil && *payment.Reference != "" {
+			description += " (" + *payment.Reference + ")"
+		}
+		w.row(description, formatMoney(payment.Amount))
+		paid += payment.Amount
+	}
+	if len(data.payments) == 0 {
+		w.row("Sin pagos registrados", "")
+	}
+	w.rule()
+	order.ApplyPayments(paid)
+	w.row("Total pagado", formatMoney(order.AmountPaid))
+	w.total("Saldo pendiente", formatMoney(order.BalanceDue))
+
+	return w.doc.Bytes()
+}
+
+// Escribe el contenido de la factura de arriba hacia abajo, continuando en otra página si no hay espacio
+type invoiceWriter struct {
+	doc *utils.PDFDocument
+	y   float64
+}
+
+func (w *invoiceWriter) reserve(height float64) {
+	if w.y+height > invoicePageBottom {
+		w.doc.AddPage()
+		w.y = 60
+	}
+}
+
+func (w *invoiceWriter) heading(text string) {
+	w.reserve(40)
+	w.y += 18
+	w.doc.Text(50, w.y, 11, utils.PDFFontBold, text)
+	w.y += 16
+}
+
+func (w *invoiceWriter) row(text, amount string) {
+	w.reserve(14)
+	w.doc.Text(60, w.y, 9, utils.PDFFontRegular, text)
+	if amount != "" {
+		w.doc.TextRight(545, w.y, 9, amount)
+	}
+	w.y += 14
+}
+
+func (w *invoiceWriter) total(text, amount string) {
+	w.reserve(16)
+	w.doc.Text(60, w.y, 10, utils.PDFFontBold, text)
+	w.doc.TextRight(545, w.y, 10, amount)
+	w.y += 16
+}
+
+func (w *invoiceWriter) rule() {
+	w.reserve(10)
+	w.doc.Line(50, w.y-6, 545, w.y-6)
+	w.y += 4
+}
+
+// Da formato de moneda a un monto, con separador de miles
+func formatMoney(amount float64) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	whole := strconv.FormatFloat(amount, 'f', 2, 64)
+	integer, decimals := whole[:len(whole)-3], whole[len(whole)-2:]
+
+	var groups []string
+	for len(integer) > 3 {
+		groups = append([]string{integer[len(integer)-3:]}, groups...)
+		integer = integer[:len(integer)-3]
+	}
+	groups = append([]string{integer}, groups...)
+
+	return fmt.Sprintf("%sQ %s.%s", sign, strings.Join(groups, ","), decimals)
+}
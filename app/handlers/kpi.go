@@ -2,178 +2,379 @@ package handlers
 
 import (
 	"dapa/app/model"
+	"dapa/app/services"
 	"dapa/app/utils"
 	"dapa/database"
-	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // @Summary		Get the performance goal
-// @Description	Returns the current performance goal (single row, default zeros if none exists).
+// @Description	Returns the performance goal of the month or year, general or of a driver (default zeros if none exists).
 // @Tags		kpi
 // @Produce		json
+// @Param		period query string false "Month (YYYY-MM) or year (YYYY), defaults to the current month"
+// @Param		userId query int false "Driver ID"
 // @Success		200	{object} model.PerformanceGoal "Performance goal"
+// @Failure		400	{object} model.ApiResponse "Invalid period"
 // @Failure		500	{object} model.ApiResponse "Error fetching performance goal"
 // @Router		/kpi/goals [get]
 func GetPerformanceGoal(c *gin.Context) {
-	var goal model.PerformanceGoal
-	result := database.DB.First(&goal)
-
-	if result.Error != nil {
-		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			// Return a default all-zero goal instead of 500
-			defaultGoal := model.PerformanceGoal{
-				OrderGoal:           0,
-				UtilityGoal:         0.00,
-				AveragePerOrderGoal: 0.00,
-				TravelGoal:          0,
-				DeliveryGoal:        0.00,
-				AchievementRateGoal: 0.00,
-			}
-			utils.RespondWithSuccess(c, http.StatusOK, defaultGoal, "No performance goal found, returning default values")
-			return
-		}
+	period, userID, ok := parseGoalScope(c)
+	if !ok {
+		return
+	}
+
+	goal, err := findPerformanceGoal(period, userID)
+	if err != nil {
 		utils.RespondWithInternalError(c, "Error fetching performance goal")
 		return
 	}
 
+	if goal.ID == 0 {
+		utils.RespondWithSuccess(c, http.StatusOK, goal, "No performance goal found, returning default values")
+		return
+	}
+
 	utils.RespondWithSuccess(c, http.StatusOK, goal, "Performance goal fetched successfully")
 }
 
+// @Summary		Get the performance goals history
+// @Description	Returns the goals of every period, newest first
+// @Tags		kpi
+// @Produce		json
+// @Param		userId query int false "Only the goals of this driver"
+// @Success		200	{object} model.ApiResponse "Performance goals"
+// @Failure		400	{object} model.ApiResponse "Invalid user"
+// @Failure		500	{object} model.ApiResponse "Error fetching performance goals"
+// @Router		/kpi/goals/history [get]
+func GetPerformanceGoalHistory(c *gin.Context) {
+	query := database.DB.Order("period_start DESC, period, user_id")
+	if value := c.Query("userId"); value != "" {
+		userID, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, err, "Invalid user")
+			return
+		}
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var goals []model.PerformanceGoal
+	if err := query.Find(&goals).Error; err != nil {
+		utils.RespondWithInternalError(c, "Error fetching performance goals")
+		return
+	}
+
+	utils.RespondWithSuccess(c, http.StatusOK, goals, "Performance goals fetched successfully")
+}
+
 // @Summary		Update the performance goal
-// @Description	Updates the single performance goal (creates if none exists).
+// @Description	Sets the goals of a month or year, general or of a driver (creates them if they don't exist). Without a period the goals of the current month are updated.
 // @Tags		kpi
 // @Accept		json
 // @Produce		json
-// @Param		body body model.PerformanceGoal true "Performance goal data"
+// @Param		body body model.PerformanceGoalDTO true "Performance goal data"
 // @Success		200 {object} model.PerformanceGoal
-// @Router		/kpi/goals [post]
+// @Success		201 {object} model.PerformanceGoal
+// @Failure		400	{object} model.ApiResponse "Invalid request format"
+// @Failure		500	{object} model.ApiResponse "Error updating performance goal"
+// @Router		/kpi/goals [put]
 func UpsertPerformanceGoal(c *gin.Context) {
-	var input model.PerformanceGoal
+	claims := c.MustGet("claims").(*model.EmployeeClaims)
+
+	var input model.PerformanceGoalDTO
 	if err := c.ShouldBindJSON(&input); err != nil {
-		utils.RespondWithCustomError(c, http.StatusBadRequest, "Invalid request data", "Error actualizando objetivos")
+		utils.RespondWithError(c, http.StatusBadRequest, err, "Invalid request format")
 		return
 	}
 
-	var goal model.PerformanceGoal
-	result := database.DB.First(&goal)
+	// Antes de que las metas tuvieran periodo se actualizaba una sola meta general; sin periodo se usa el mes actual
+	if input.Period == "" {
+		input.Period = chartToday().Format("2006-01")
+	}
 
-	if result.Error != nil {
-		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			// No existing record → create one
-			if err := database.DB.Create(&input).Error; err != nil {
-				utils.RespondWithInternalError(c, "Error creating performance goal")
-				return
-			}
-			utils.RespondWithSuccess(c, http.StatusCreated, input, "Performance goal created successfully")
-			return
-		}
+	period, err := model.ParseGoalPeriod(input.Period)
+	if err != nil {
+		utils.RespondWithCustomError(c, http.StatusBadRequest, err.Error(), "Invalid request format")
+		return
+	}
+
+	if input.UserID != nil && !validateGoalDriver(c, *input.UserID) {
+		return
+	}
+
+	existing, err := findPerformanceGoal(period, input.UserID)
+	if err != nil {
 		utils.RespondWithInternalError(c, "Error fetching existing performance goal")
 		return
 	}
 
-	// Update the existing one
-	goal.OrderGoal = input.OrderGoal
-	goal.UtilityGoal = input.UtilityGoal
-	goal.AveragePerOrderGoal = input.AveragePerOrderGoal
-	goal.TravelGoal = input.TravelGoal
-	goal.DeliveryGoal = input.DeliveryGoal
-	goal.AchievementRateGoal = input.AchievementRateGoal
+	status := http.StatusOK
+	if existing.ID == 0 {
+		status = http.StatusCreated
+	}
+
+	goal := model.PerformanceGoal{
+		Period:              period.Kind,
+		PeriodStart:         period.Start,
+		UserID:              input.UserID,
+		OrderGoal:           input.OrderGoal,
+		UtilityGoal:         input.UtilityGoal,
+		AveragePerOrderGoal: input.AveragePerOrderGoal,
+		TravelGoal:          input.TravelGoal,
+		DeliveryGoal:        input.DeliveryGoal,
+		AchievementRateGoal: input.AchievementRateGoal,
+		UpdatedBy:           &claims.UserID,
+	}
 
-	if err := database.DB.Save(&goal).Error; err != nil {
+	// La inserción y la actualización son una sola sentencia para que dos peticiones simultáneas no dupliquen las metas
+	if err = database.DB.Clauses(performanceGoalConflict(input.UserID)).Create(&goal).Error; err != nil {
 		utils.RespondWithInternalError(c, "Error updating performance goal")
 		return
 	}
 
-	utils.RespondWithSuccess(c, http.StatusOK, goal, "Performance goal updated successfully")
+	utils.RespondWithSuccess(c, status, goal, "Performance goal updated successfully")
 }
 
-// @Summary		Get current KPIs
-// @Description	Returns the current KPIs for the month.
+// @Summary		Get the progress towards the performance goal
+// @Description	Compares each goal of the month or year with the actual KPIs and projects the value at the end of the period from the pace so far
 // @Tags		kpi
 // @Produce		json
-// @Success		200	{object} model.ApiResponse "Current KPIs"
-// @Failure		500	{object} model.ApiResponse "Error fetching current KPIs"
-// @Router		/kpi/current [get]
-func GetCurrentKPIs(c *gin.Context) {
-	now := time.Now()
-	startOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
-	endOfMonth := startOfMonth.AddDate(0, 1, 0).Add(-time.Nanosecond)
+// @Param		period query string false "Month (YYYY-MM) or year (YYYY), defaults to the current month"
+// @Param		userId query int false "Driver ID"
+// @Success		200	{object} model.ApiResponse "Goal progress"
+// @Failure		400	{object} model.ApiResponse "Invalid period"
+// @Failure		500	{object} model.ApiResponse "Error fetching goal progress"
+// @Router		/kpi/goals/progress [get]
+func GetGoalProgress(c *gin.Context) {
+	period, userID, ok := parseGoalScope(c)
+	if !ok {
+		return
+	}
 
-	// Total Income
-	var totalIncome float64
-	err := database.DB.Model(&model.Order{}).Where("status = ? AND date BETWEEN ? AND ?", "delivered", startOfMonth, endOfMonth).Select("COALESCE(SUM(total_amount), 0)").Row().Scan(&totalIncome)
+	goal, err := findPerformanceGoal(period, userID)
 	if err != nil {
-		utils.RespondWithInternalError(c, "Error fetching total income")
+		utils.RespondWithInternalError(c, "Error fetching goal progress")
 		return
 	}
 
-	// Total Expenses
-	var totalExpenses float64
-	err = database.DB.Model(&model.Expense{}).Where("date BETWEEN ? AND ?", startOfMonth, endOfMonth).Select("COALESCE(SUM(amount), 0)").Row().Scan(&totalExpenses)
+	kpis, err := services.ComputeKPIs(period.Start, period.End, userID)
 	if err != nil {
-		utils.RespondWithInternalError(c, "Error fetching total expenses")
+		utils.RespondWithInternalError(c, "Error fetching goal progress")
 		return
 	}
 
-	// Utility
-	utility := totalIncome - totalExpenses
+	elapsed := model.ElapsedFraction(period, chartToday())
+	progress := model.GoalProgressDTO{
+		Period:     model.FormatGoalPeriod(period),
+		UserID:     userID,
+		Start:      period.Start,
+		End:        period.End.AddDate(0, 0, -1),
+		ElapsedPct: float64(int(elapsed*10000+0.5)) / 100,
+		KPIs:       kpis,
+		Metrics:    model.NewGoalMetrics(goal, kpis, elapsed),
+	}
+	if goal.ID != 0 {
+		progress.Goal = &goal
+	}
 
-	// Average Amount Per Order
-	var averageAmount float64
-	err = database.DB.Model(&model.Order{}).Where("status = ? AND date BETWEEN ? AND ?", "delivered", startOfMonth, endOfMonth).Select("COALESCE(AVG(total_amount), 0)").Row().Scan(&averageAmount)
+	utils.RespondWithSuccess(c, http.StatusOK, progress, "Goal progress fetched successfully")
+}
+
+// @Summary		Get the KPIs of a month
+// @Description	Returns the KPIs of the month compared with the previous month and the series of the last 12 months of each KPI. Closed months use the values saved at month close when available
+// @Tags		kpi
+// @Produce		json
+// @Param		period query string false "Month (YYYY-MM), defaults to the current month"
+// @Success		200	{object} model.KPIReportDTO "KPIs of the month"
+// @Failure		400	{object} model.ApiResponse "Invalid period"
+// @Failure		500	{object} model.ApiResponse "Error fetching KPIs"
+// @Router		/kpi [get]
+func GetKPIs(c *gin.Context) {
+	today := chartToday()
+	value := c.DefaultQuery("period", today.Format("2006-01"))
+
+	period, err := model.ParseGoalPeriod(value)
+	if err == nil && period.Kind != model.PeriodMonth {
+		err = fmt.Errorf("period must be a month (YYYY-MM)")
+	}
+	if err == nil && period.Start.Format("2006-01") > today.Format("2006-01") {
+		err = fmt.Errorf("period can't be in the future")
+	}
 	if err != nil {
-		utils.RespondWithInternalError(c, "Error fetching average amount per order")
+		utils.RespondWithCustomError(c, http.StatusBadRequest, err.Error(), "Invalid period")
 		return
 	}
 
-	// Completed Trips
-	var completedTrips int64
-	err = database.DB.Model(&model.Order{}).Where("status = ? AND date BETWEEN ? AND ?", "delivered", startOfMonth, endOfMonth).Count(&completedTrips).Error
-	if err != nil {
-		utils.RespondWithInternalError(c, "Error fetching completed trips")
+	first := period.Start.AddDate(0, -(kpiSeriesMonths - 1), 0)
+	var snapshots []model.KPISnapshot
+	if err = database.DB.Where("period_start >= ? AND period_start <= ?", first, period.Start).Find(&snapshots).Error; err != nil {
+		utils.RespondWithInternalError(c, "Error fetching KPIs")
+		return
+	}
+	saved := make(map[string]model.KPISnapshot, len(snapshots))
+	for _, snapshot := range snapshots {
+		saved[snapshot.PeriodStart.Format("2006-01")] = snapshot
+	}
+
+	report := model.KPIReportDTO{
+		Period: model.FormatGoalPeriod(period),
+		Start:  period.Start,
+		End:    period.End.AddDate(0, 0, -1),
+		Closed: period.Start.Format("2006-01") < today.Format("2006-01"),
+	}
+
+	months := make([]model.KPIs, kpiSeriesMonths)
+	for i := range months {
+		start := first.AddDate(0, i, 0)
+		label := start.Format("2006-01")
+		report.Months = append(report.Months, label)
+
+		if snapshot, ok := saved[label]; ok {
+			months[i] = snapshot.KPIs
+			if i == len(months)-1 {
+				report.SnapshotAt = &snapshot.CreatedAt
+			}
+			continue
+		}
+
+		if months[i], err = services.ComputeKPIs(start, start.AddDate(0, 1, 0), nil); err != nil {
+			utils.RespondWithInternalError(c, "Error fetching KPIs")
+			return
+		}
+	}
+
+	report.KPIs = months[len(months)-1]
+	report.PreviousPeriod = months[len(months)-2]
+	report.Trends = model.NewKPITrends(months)
+
+	utils.RespondWithSuccess(c, http.StatusOK, report, "KPIs fetched successfully")
+}
+
+// @Summary		Get current KPIs
+// @Description	Returns the KPIs within the range, by default the current month.
+// @Tags		kpi
+// @Produce		json
+// @Param		from query string false "First day of the range, defaults to the start of the month (YYYY-MM-DD)"
+// @Param		to query string false "Last day of the range, defaults to today (YYYY-MM-DD)"
+// @Param		tz query string false "Timezone of the range, defaults to America/Guatemala"
+// @Success		200	{object} model.ApiResponse "Current KPIs"
+// @Failure		400	{object} model.ApiResponse "Invalid chart range"
+// @Failure		500	{object} model.ApiResponse "Error fetching current KPIs"
+// @Router		/kpi/current [get]
+func GetCurrentKPIs(c *gin.Context) {
+	chartRange, ok := parseChartRange(c, 1)
+	if !ok {
 		return
 	}
+	from, to := chartRange.DateBounds()
 
-	// Average Orders Per Employee
-	var employeeCount int64
-	err = database.DB.Model(&model.Order{}).Where("status = ? AND date BETWEEN ? AND ? AND user_id IS NOT NULL", "delivered", startOfMonth, endOfMonth).Distinct("user_id").Count(&employeeCount).Error
+	kpis, err := services.ComputeKPIs(from, to, nil)
 	if err != nil {
-		utils.RespondWithInternalError(c, "Error fetching employee count")
+		utils.RespondWithInternalError(c, "Error fetching current KPIs")
 		return
 	}
 
-	var averageOrdersPerEmployee float64
-	if employeeCount > 0 {
-		averageOrdersPerEmployee = float64(completedTrips) / float64(employeeCount)
+	utils.RespondWithSuccess(c, http.StatusOK, kpis, "Current KPIs fetched successfully")
+}
+
+// Cantidad de meses de la serie de cada indicador, incluyendo el mes consultado
+const kpiSeriesMonths = 12
+
+// Lee el periodo y el piloto de las metas
+// Retorna false si algún parámetro no es válido, después de enviar la respuesta de error
+func parseGoalScope(c *gin.Context) (model.ReportPeriod, *uint, bool) {
+	value := c.Query("period")
+	if value == "" {
+		value = chartToday().Format("2006-01")
 	}
 
-	// Fulfillment Rate
-	var pendingOrders int64
-	err = database.DB.Model(&model.Order{}).Where("status = ? AND date BETWEEN ? AND ?", "pending", startOfMonth, endOfMonth).Count(&pendingOrders).Error
+	period, err := model.ParseGoalPeriod(value)
 	if err != nil {
-		utils.RespondWithInternalError(c, "Error fetching pending orders count")
-		return
+		utils.RespondWithCustomError(c, http.StatusBadRequest, err.Error(), "Invalid period")
+		return period, nil, false
 	}
 
-	var fulfillmentRate float64
-	if completedTrips+pendingOrders > 0 {
-		fulfillmentRate = (float64(completedTrips) / float64(completedTrips+pendingOrders)) * 100
+	if value = c.Query("userId"); value == "" {
+		return period, nil, true
 	}
 
-	kpiData := gin.H{
-		"utility":                  utility,
-		"averagePerOrder":          averageAmount,
-		"completedTrips":           completedTrips,
-		"deliveredOrders":          completedTrips,
-		"averageOrdersPerEmployee": int(averageOrdersPerEmployee + 0.5),
-		"fulfillmentRate":          fulfillmentRate,
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err, "Invalid user")
+		return period, nil, false
 	}
+	userID := uint(id)
+	return period, &userID, true
+}
+
+// Busca las metas del periodo y piloto indicados
+// Si no existen retorna metas en cero para ese periodo, sin guardarlas
+func findPerformanceGoal(period model.ReportPeriod, userID *uint) (model.PerformanceGoal, error) {
+	goal := model.PerformanceGoal{Period: period.Kind, PeriodStart: period.Start, UserID: userID}
 
-	utils.RespondWithSuccess(c, http.StatusOK, kpiData, "Current KPIs fetched successfully")
+	query := database.DB.Where("period = ? AND period_start = ?", period.Kind, period.Start)
+	if userID != nil {
+		query = query.Where("user_id = ?", *userID)
+	} else {
+		query = query.Where("user_id IS NULL")
+	}
+
+	err := query.Limit(1).Find(&goal).Error
+	return goal, err
 }
 
+// Conflicto con las metas existentes del mismo periodo y piloto, que se actualizan con los valores nuevos
+// Cada caso corresponde a uno de los índices únicos parciales de las metas
+func performanceGoalConflict(userID *uint) clause.OnConflict {
+	conflict := clause.OnConflict{
+		Columns:     []clause.Column{{Name: "period"}, {Name: "period_start"}},
+		TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "user_id IS NULL"}}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"order_goal", "utility_goal", "average_per_order_goal", "travel_goal",
+			"delivery_goal", "achievement_rate_goal", "updated_by", "updated_at",
+		}),
+	}
+
+	if userID != nil {
+		conflict.Columns = append(conflict.Columns, clause.Column{Name: "user_id"})
+		conflict.TargetWhere = clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "user_id IS NOT NULL"}}}
+	}
+	return conflict
+}
+
+// Verifica que las metas se asignen a un piloto existente
+// Envía la respuesta de error y retorna false si no lo es
+func validateGoalDriver(c *gin.Context, userID uint) bool {
+	var user model.User
+	if err := database.DB.Where("id = ?", userID).Limit(1).Find(&user).Error; err != nil {
+		utils.RespondWithInternalError(c, "Error fetching user")
+		return false
+	}
+
+	permissions, err := findRolePermissions(database.DB, user.Role)
+	if err != nil {
+		utils.RespondWithInternalError(c, "Error fetching user")
+		return false
+	}
+
+	if user.ID == 0 || !permissions.CanDrive() {
+		utils.RespondWithCustomError(c, http.StatusBadRequest, fmt.Sprintf("User %d isn't a driver", userID), "Invalid request format")
+		return false
+	}
+	return true
+}
+
+// Retorna la fecha actual en la zona horaria de las gráficas
+func chartToday() time.Time {
+	location, err := time.LoadLocation(model.DefaultChartTimezone)
+	if err != nil {
+		return time.Now()
+	}
+	return time.Now().In(location)
+}
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"dapa/app/model"
+	"dapa/app/utils"
+	"dapa/database"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// Ventana en la que se cuentan los intentos de cada IP
+	loginAttemptWindow = 15 * time.Minute
+
+	// Intentos fallidos de inicio de sesión y solicitudes de recuperación que acepta cada IP en la ventana
+	maxLoginFailuresPerIP  = 20
+	maxForgotRequestsPerIP = 10
+
+	// Correos de recuperación que se envían a una misma cuenta por hora
+	maxResetEmailsPerHour = 3
+
+	// Fallos seguidos de una cuenta a partir de los cuales se exige esperar entre intentos
+	loginFailuresBeforeWait = 3
+
+	// Al alcanzar este número de fallos seguidos la cuenta se bloquea hasta que pase el tiempo o la desbloquee un administrador
+	maxLoginFailures = 10
+	lockoutDuration  = 30 * time.Minute
+
+	// Espera que se pide a un intento que llega mientras se procesa otro de la misma cuenta
+	concurrentLoginWait = time.Second
+
+	// Cantidad máxima de intentos que se listan en la bitácora
+	loginAttemptsLimit = 500
+)
+
+// Motivos por los que se rechaza un intento
+const (
+	attemptReasonIPLimited       = "ip_limited"
+	attemptReasonAccountLocked   = "account_locked"
+	attemptReasonAccountLimited  = "account_limited"
+	attemptReasonUnknownEmail    = "unknown_email"
+	attemptReasonInvalidPassword = "invalid_password"
+)
+
+// @Summary		Get the login audit trail
+// @Description	Returns the most recent login and password recovery attempts, newest first
+// @Tags		auth
+// @Produce		json
+// @Param		email query string false "Email used in the attempt"
+// @Param		userId query int false "User ID"
+// @Param		ip query string false "IP address"
+// @Param		success query bool false "Only successful or failed attempts"
+// @Success		200	{object} model.ApiResponse "Login attempts"
+// @Failure		400	{object} model.ApiResponse "Invalid request format"
+// @Failure		500	{object} model.ApiResponse "Error fetching login attempts"
+// @Router		/login-attempts [get]
+func GetLoginAttemptsHandler(c *gin.Context) {
+	query := database.DB.Order("created_at DESC, id DESC").Limit(loginAttemptsLimit)
+
+	if email := c.Query("email"); email != "" {
+		query = query.Where("email = ?", email)
+	}
+	if ip := c.Query("ip"); ip != "" {
+		query = query.Where("ip = ?", ip)
+	}
+	if value := c.Query("userId"); value != "" {
+		userID, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, err, "Invalid request format")
+			return
+		}
+		query = query.Where("user_id = ?", userID)
+	}
+	if value := c.Query("success"); value != "" {
+		success, err := strconv.ParseBool(value)
+		if err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, err, "Invalid request format")
+			return
+		}
+		query = query.Where("success = ?", success)
+	}
+
+	var attempts []model.LoginAttempt
+	if err := query.Find(&attempts).Error; err != nil {
+		utils.RespondWithInternalError(c, "Error fetching login attempts")
+		return
+	}
+
+	utils.RespondWithSuccess(c, http.StatusOK, attempts, "Login attempts fetched successfully")
+}
+
+// Registra un intento con los datos de la petición antes de procesarlo
+// Así los intentos simultáneos de una IP se cuentan entre sí para el límite; el resultado se guarda al terminar el handler
+func startLoginAttempt(c *gin.Context, kind model.LoginAttemptKind, email string) (model.LoginAttempt, error) {
+	attempt := model.LoginAttempt{
+		Kind:      kind,
+		Email:     truncate(email, 100),
+		IP:        c.ClientIP(),
+		UserAgent: truncate(c.Request.UserAgent(), 255),
+	}
+	err := database.DB.Create(&attempt).Error
+	return attempt, err
+}
+
+// Guarda el resultado de un intento registrado con startLoginAttempt
+// La respuesta ya se envió, así que un error solo se registra en el log
+func finishLoginAttempt(attempt *model.LoginAttempt) {
+	err := database.DB.Model(attempt).Select("user_id", "success", "reason").Updates(attempt).Error
+	if err != nil {
+		log.Printf("Error saving login attempt %d: %v", attempt.ID, err)
+	}
+}
+
+// Retorna cuánto debe esperar la IP del intento antes de uno nuevo, cero si puede intentarlo
+// Se cuentan los demás intentos de la ventana; para los inicios de sesión solo los fallidos o en curso
+func ipRetryAfter(attempt *model.LoginAttempt, limit int64, failedOnly bool, now time.Time) (time.Duration, error) {
+	attempts := func() *gorm.DB {
+		query := database.DB.Model(&model.LoginAttempt{}).
+			Where("ip = ? AND kind = ? AND id <> ? AND created_at > ?", attempt.IP, attempt.Kind, attempt.ID, now.Add(-loginAttemptWindow))
+		if failedOnly {
+			query = query.Where("success = ?", false)
+		}
+		return query
+	}
+
+	var count int64
+	if err := attempts().Count(&count).Error; err != nil {
+		return 0, err
+	}
+	if count < limit {
+		return 0, nil
+	}
+
+	// Se puede volver a intentar cuando el intento más antiguo salga de la ventana
+	var oldest model.LoginAttempt
+	if err := attempts().Order("created_at").Limit(1).Find(&oldest).Error; err != nil {
+		return 0, err
+	}
+	return oldest.CreatedAt.Add(loginAttemptWindow).Sub(now), nil
+}
+
+// Retorna la espera que se exige después de la cantidad indicada de fallos seguidos
+// A partir del tercer fallo la espera se duplica en cada intento hasta que la cuenta se bloquea
+func loginFailureDelay(failures int) time.Duration {
+	if failures >= maxLoginFailures {
+		return lockoutDuration
+	}
+	if failures < loginFailuresBeforeWait {
+		return 0
+	}
+	return time.Second << (failures - loginFailuresBeforeWait)
+}
+
+// Reserva un intento de la cuenta antes de comparar la contraseña: lo cuenta como fallido y aplica la espera que le corresponde
+// Si la contraseña es correcta el conteo se reinicia después con clearLoginFailures
+// La actualización es condicional, así dos intentos simultáneos no pueden comparar la contraseña con el mismo conteo de fallos
+// Retorna cuánto debe esperar la cuenta si no acepta el intento, cero si se reservó
+func reserveLoginAttempt(user *model.User, now time.Time) (time.Duration, error) {
+	if user.LockedUntil != nil && now.Before(*user.LockedUntil) {
+		return user.LockedUntil.Sub(now), nil
+	}
+
+	failures := user.FailedLoginAttempts + 1
+	var lockedUntil *time.Time
+	if delay := loginFailureDelay(failures); delay > 0 {
+		until := now.Add(delay)
+		lockedUntil = &until
+	}
+
+	result := database.DB.Model(&model.User{}).
+		Where("id = ? AND failed_login_attempts = ?", user.ID, user.FailedLoginAttempts).
+		Where("(locked_until IS NULL OR locked_until <= ?)", now).
+		Updates(map[string]any{
+			"failed_login_attempts": failures,
+			"locked_until":          lockedUntil,
+		})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	// Otro intento de la misma cuenta se reservó primero
+	if result.RowsAffected == 0 {
+		return concurrentLoginWait, nil
+	}
+
+	user.FailedLoginAttempts = failures
+	user.LockedUntil = lockedUntil
+	return 0, nil
+}
+
+// Reinicia el conteo de fallos de la cuenta y retira el bloqueo
+func clearLoginFailures(tx *gorm.DB, userID uint) error {
+	return tx.Model(&model.User{}).Where("id = ?", userID).Updates(map[string]any{
+		"failed_login_attempts": 0,
+		"locked_until":          nil,
+	}).Error
+}
+
+// Responde que se debe esperar antes de un nuevo intento e indica cuánto en el encabezado Retry-After
+func respondTooManyAttempts(c *gin.Context, wait time.Duration) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	utils.RespondWithCustomError(
+		c,
+		http.StatusTooManyRequests,
+		fmt.Sprintf("Too many attempts, try again in %d seconds", seconds),
+		"Too many attempts",
+	)
+}
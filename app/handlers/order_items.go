@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"dapa/app/model"
+	"dapa/app/utils"
+	"dapa/database"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary		Get the cargo items of an order
+// @Description	Returns the items of the order and its total estimated weight
+// @Tags		orders
+// @Produce		json
+// @Param       id path int true "Order ID"
+// @Success		200	{object} model.ApiResponse "Order items"
+// @Failure		403	{object} model.ApiResponse "Insufficient permissions"
+// @Failure		404	{object} model.ApiResponse "Order not found"
+// @Failure		500	{object} model.ApiResponse "Error fetching order items"
+// @Router		/orders/{id}/items [get]
+func GetOrderItemsHandler(c *gin.Context) {
+	id := c.Param("id")
+
+	var order model.Order
+	if err := database.DB.Preload("Items").Where("id = ?", id).First(&order).Error; err != nil {
+		utils.RespondWithCustomError(
+			c,
+			http.StatusNotFound,
+			"Order not found",
+			"Something went wrong",
+		)
+		return
+	}
+
+	if !authorizeOrderRead(c, &order) {
+		return
+	}
+
+	order.ComputeTotals()
+	response := gin.H{
+		"items":         order.Items,
+		"totalWeightKg": order.TotalWeightKg,
+	}
+
+	utils.RespondWithSuccess(c, http.StatusOK, response, "Order items fetched successfully")
+}
+
+// @Summary		Adds a cargo item to an order
+// @Description	Creates a new item; it fails if the assigned vehicle can't carry the resulting weight
+// @Tags		orders
+// @Accept		json
+// @Produce		json
+// @Param       id path int true "Order ID"
+// @Param		item body model.OrderItemDTO true "Item information"
+// @Success		201	{object} model.ApiResponse "Order item created successfully"
+// @Failure		400	{object} model.ApiResponse "Invalid request format or vehicle capacity exceeded"
+// @Failure		404	{object} model.ApiResponse "Order not found"
+// @Failure		500	{object} model.ApiResponse "Error creating order item"
+// @Router		/orders/{id}/items [post]
+func CreateOrderItemHandler(c *gin.Context) {
+	var req model.OrderItemDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+
+	var order model.Order
+	if err := database.DB.Where("id = ?", c.Param("id")).First(&order).Error; err != nil {
+		utils.RespondWithCustomError(
+			c,
+			http.StatusNotFound,
+			"Order not found",
+			"Something went wrong",
+		)
+		return
+	}
+
+	item := buildOrderItems(order.ID, []model.OrderItemDTO{req})[0]
+	if !checkCargoCapacity(c, &order, item.TotalWeightKg(), 0) {
+		return
+	}
+
+	if err := database.DB.Create(&item).Error; err != nil {
+		utils.RespondWithInternalError(c, "Error creating order item")
+		return
+	}
+
+	utils.RespondWithSuccess(c, http.StatusCreated, item, "Order item created successfully")
+}
+
+// @Summary		Updates a cargo item
+// @Description	Updates one item of the order; it fails if the assigned vehicle can't carry the resulting weight
+// @Tags		orders
+// @Accept		json
+// @Produce		json
+// @Param       id path int true "Order ID"
+// @Param       itemId path int true "Item ID"
+// @Param		item body model.OrderItemDTO true "Item information"
+// @Success		200	{object} model.ApiResponse "Order item updated successfully"
+// @Failure		400	{object} model.ApiResponse "Invalid request format or vehicle capacity exceeded"
+// @Failure		404	{object} model.ApiResponse "Order item not found"
+// @Failure		500	{object} model.ApiResponse "Error updating order item"
+// @Router		/orders/{id}/items/{itemId} [put]
+func UpdateOrderItemHandler(c *gin.Context) {
+	var req model.OrderItemDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+
+	var order model.Order
+	var item model.OrderItem
+	if !findOrderItem(c, &order, &item) {
+		return
+	}
+
+	updated := buildOrderItems(order.ID, []model.OrderItemDTO{req})[0]
+	updated.ID = item.ID
+
+	if !checkCargoCapacity(c, &order, updated.TotalWeightKg(), item.TotalWeightKg()) {
+		return
+	}
+
+	if err := database.DB.Save(&updated).Error; err != nil {
+		utils.RespondWithInternalError(c, "Error updating order item")
+		return
+	}
+
+	utils.RespondWithSuccess(c, http.StatusOK, updated, "Order item updated successfully")
+}
+
+// @Summary		Deletes a cargo item
+// @Description	Removes one item from the order
+// @Tags		orders
+// @Produce		json
+// @Param       id path int true "Order ID"
+// @Param       itemId path int true "Item ID"
+// @Success		200	{object} model.ApiResponse "Order item deleted successfully"
+// @Failure		404	{object} model.ApiResponse "Order item not found"
+// @Failure		500	{object} model.ApiResponse "Error deleting order item"
+// @Router		/orders/{id}/items/{itemId} [delete]
+func DeleteOrderItemHandler(c *gin.Context) {
+	var order model.Order
+	var item model.OrderItem
+	if !findOrderItem(c, &order, &item) {
+		return
+	}
+
+	if err := database.DB.Delete(&item).Error; err != nil {
+		utils.RespondWithInternalError(c, "Error deleting order item")
+		return
+	}
+
+	utils.RespondWithSuccess(c, http.StatusOK, nil, "Order item deleted successfully")
+}
+
+// Convierte los artículos recibidos en entidades asociadas a una orden
+func buildOrderItems(orderID uint, dtos []model.OrderItemDTO) []model.OrderItem {
+	items := make([]model.OrderItem, len(dtos))
+	for i, dto := range dtos {
+		items[i] = model.OrderItem{
+			OrderID:     orderID,
+			Description: dto.Description,
+			Quantity:    dto.Quantity,
+			WeightKg:    dto.WeightKg,
+			VolumeM3:    dto.VolumeM3,
+			IsFragile:   dto.IsFragile,
+		}
+	}
+	return items
+}
+
+// Calcula los totales de una lista de órdenes con sus artículos precargados y su saldo según los pagos
+func computeOrderTotals(orders []model.Order) error {
+	ids := make([]uint, len(orders))
+	for i := range orders {
+		ids[i] = orders[i].ID
+	}
+
+	paid, err := orderPayments(database.DB, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range orders {
+		orders[i].ComputeTotals()
+		orders[i].ApplyPayments(paid[orders[i].ID])
+	}
+	return nil
+}
+
+// Busca la orden y el artículo indicados en la ruta
+// Retorna false si alguno no existe, después de enviar la respuesta de error
+func findOrderItem(c *gin.Context, order *model.Order, item *model.OrderItem) bool {
+	if err := database.DB.Where("id = ?", c.Param("id")).First(order).Error; err != nil {
+		utils.RespondWithCustomError(
+			c,
+			http.StatusNotFound,
+			"Order not found",
+			"Something went wrong",
+		)
+		return false
+	}
+
+	if err := database.DB.Where("id = ? AND order_id = ?", c.Param("itemId"), order.ID).First(item).Error; err != nil {
+		utils.RespondWithCustomError(
+			c,
+			http.StatusNotFound,
+			"Order item not found",
+			"Something went wrong",
+		)
+		return false
+	}
+
+	return true
+}
+
+// Verifica que el vehículo asignado pueda transportar la carga después de un cambio
+// Recibe el peso que se agrega y el que se reemplaza
+// Retorna false si la capacidad se excede, después de enviar la respuesta de error
+func checkCargoCapacity(c *gin.Context, order *model.Order, addedKg, replacedKg float64) bool {
+	if order.VehicleID == nil {
+		return true
+	}
+
+	currentKg, err := orderCargoKg(order.ID)
+	if err != nil {
+		utils.RespondWithInternalError(c, "Error validating vehicle capacity")
+		return false
+	}
+
+	var vehicle model.Vehicle
+	if err := database.DB.Where("id = ?", *order.VehicleID).First(&vehicle).Error; err != nil {
+		utils.RespondWithInternalError(c, "Error validating vehicle capacity")
+		return false
+	}
+
+	totalKg := currentKg - replacedKg + addedKg
+	if totalKg > vehicle.CapacityKg {
+		utils.RespondWithErrors(
+			c,
+			http.StatusBadRequest,
+			[]string{fmt.Sprintf("Cargo weight of %.2f kg exceeds the %.2f kg capacity of vehicle %d", totalKg, vehicle.CapacityKg, vehicle.ID)},
+			"Vehicle capacity exceeded",
+		)
+		return false
+	}
+
+	return true
+}
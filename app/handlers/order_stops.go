@@ -0,0 +1,412 @@
+package handlers
+
+import (
+	"dapa/app/model"
+	"dapa/app/utils"
+	"dapa/database"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Longitud máxima del origen y destino resumidos en la orden
+const routeSummaryLength = 100
+
+// Orden en que una orden avanza mientras se recorren sus paradas
+var routeProgression = []model.OrderStatus{
+	model.OrderStatusAssigned,
+	model.OrderStatusPickup,
+	model.OrderStatusCollected,
+	model.OrderStatusDelivered,
+}
+
+// @Summary		Get the route of an order
+// @Description	Returns the ordered stops of the order; orders without stops are returned as a two-stop route
+// @Tags		orders
+// @Produce		json
+// @Param       id path int true "Order ID"
+// @Success		200	{object} model.ApiResponse "Order stops"
+// @Failure		403	{object} model.ApiResponse "Insufficient permissions"
+// @Failure		404	{object} model.ApiResponse "Order not found"
+// @Failure		500	{object} model.ApiResponse "Error fetching order stops"
+// @Router		/orders/{id}/stops [get]
+func GetOrderStopsHandler(c *gin.Context) {
+	var order model.Order
+	if err := database.DB.Where("id = ?", c.Param("id")).First(&order).Error; err != nil {
+		utils.RespondWithCustomError(
+			c,
+			http.StatusNotFound,
+			"Order not found",
+			"Something went wrong",
+		)
+		return
+	}
+
+	if !authorizeOrderRead(c, &order) {
+		return
+	}
+
+	stops, err := loadOrderStops(database.DB, &order)
+	if err != nil {
+		utils.RespondWithInternalError(c, "Error fetching order stops")
+		return
+	}
+
+	utils.RespondWithSuccess(c, http.StatusOK, stops, "Order stops fetched successfully")
+}
+
+// @Summary		Updates the progress of a stop
+// @Description	Marks a stop as arrived, completed or skipped and advances the order status according to the route
+// @Tags		orders
+// @Accept		json
+// @Produce		json
+// @Param       id path int true "Order ID"
+// @Param       position path int true "Stop position"
+// @Param		status body model.StopStatusDTO true "Updated stop status"
+// @Success		200	{object} model.ApiResponse "Stop status updated successfully"
+// @Failure		400 {object} model.ApiResponse "Invalid request format"
+// @Failure		403 {object} model.ApiResponse "Insufficient permissions"
+// @Failure		404 {object} model.ApiResponse "Order or stop not found"
+// @Failure		409 {object} model.ApiResponse "Invalid status transition or missing proof of delivery"
+// @Failure		500 {object} model.ApiResponse "Error updating stop status"
+// @Router		/orders/{id}/stops/{position}/status [patch]
+func UpdateStopStatusHandler(c *gin.Context) {
+	var req model.StopStatusDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+
+	claims := c.MustGet("claims").(*model.EmployeeClaims)
+	position, err := strconv.Atoi(c.Param("position"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err, "Invalid stop position")
+		return
+	}
+
+	var order model.Order
+	if err := database.DB.Where("id = ?", c.Param("id")).First(&order).Error; err != nil {
+		utils.RespondWithCustomError(
+			c,
+			http.StatusNotFound,
+			"Order not found",
+			"Something went wrong",
+		)
+		return
+	}
+
+	if !authorizeOrderOperation(c, &order) {
+		return
+	}
+
+	if routeRank(order.Status) < 0 || order.Status == model.OrderStatusDelivered {
+		utils.RespondWithCustomError(
+			c,
+			http.StatusConflict,
+			fmt.Sprintf("Stops can't be updated while the order is %s", order.Status),
+			"Invalid status transition",
+		)
+		return
+	}
+
+	stops, err := loadOrderStops(database.DB, &order)
+	if err != nil {
+		utils.RespondWithInternalError(c, "Error updating stop status")
+		return
+	}
+
+	index := -1
+	for i, stop := range stops {
+		if stop.Position == position {
+			index = i
+		}
+	}
+
+	if index < 0 {
+		utils.RespondWithCustomError(
+			c,
+			http.StatusNotFound,
+			"Stop not found",
+			"Something went wrong",
+		)
+		return
+	}
+
+	if !stops[index].Status.CanTransitionTo(req.Status) {
+		utils.RespondWithCustomError(
+			c,
+			http.StatusConflict,
+			fmt.Sprintf("Can't change stop status from %s to %s", stops[index].Status, req.Status),
+			"Invalid status transition",
+		)
+		return
+	}
+
+	stops[index].Status = req.Status
+	if req.Status.IsDone() {
+		now := time.Now()
+		stops[index].CompletedAt = &now
+	}
+
+	permissions, ok := userPermissions(c)
+	if !ok {
+		return
+	}
+
+	// Cada estado intermedio debe estar permitido por la tabla de transiciones para el usuario
+	target := model.RouteStatus(stops)
+	from := order.Status
+	for _, next := range routeSteps(order.Status, target) {
+		if !from.CanTransitionTo(next, permissions) {
+			utils.RespondWithCustomError(
+				c,
+				http.StatusConflict,
+				fmt.Sprintf("Can't change status from %s to %s", from, next),
+				"Invalid status transition",
+			)
+			return
+		}
+		from = next
+	}
+
+	if target == model.OrderStatusDelivered && !requireProofOfDelivery(c, order.ID) {
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// Las órdenes sin paradas registradas se guardan como ruta de dos paradas al comenzar a recorrerlas
+		if stops[index].ID == 0 {
+			if txErr := tx.Create(&stops).Error; txErr != nil {
+				return txErr
+			}
+		} else if txErr := tx.Save(&stops[index]).Error; txErr != nil {
+			return txErr
+		}
+
+		return advanceRouteStatus(tx, &order, target, claims.UserID)
+	})
+
+	switch {
+	case errors.Is(err, errOrderStatusChanged):
+		respondOrderStatusChanged(c)
+		return
+	case err != nil:
+		utils.RespondWithInternalError(c, "Error updating stop status")
+		return
+	}
+
+	utils.RespondWithSuccess(c, http.StatusOK, stops[index], "Stop status updated successfully")
+}
+
+// Obtiene las paradas de una orden ordenadas por posición
+// Si la orden no tiene paradas registradas se genera la ruta de dos paradas a partir del origen y destino
+func loadOrderStops(db *gorm.DB, order *model.Order) ([]model.OrderStop, error) {
+	var stops []model.OrderStop
+	if err := db.Where("order_id = ?", order.ID).Order("position").Find(&stops).Error; err != nil {
+		return nil, err
+	}
+
+	if len(stops) > 0 {
+		return stops, nil
+	}
+
+	return defaultOrderStops(order), nil
+}
+
+// Genera la ruta de dos paradas de una orden sin paradas registradas
+// El estado de cada parada se deduce del estado de la orden
+func defaultOrderStops(order *model.Order) []model.OrderStop {
+	pickup := model.OrderStop{
+		OrderID:  order.ID,
+		Position: 1,
+		Type:     model.StopTypePickup,
+		Address:  order.Origin,
+		Status:   model.StopStatusPending,
+	}
+	dropoff := model.OrderStop{
+		OrderID:  order.ID,
+		Position: 2,
+		Type:     model.StopTypeDropoff,
+		Address:  order.Destination,
+		Status:   model.StopStatusPending,
+	}
+
+	switch order.Status {
+	case model.OrderStatusPickup:
+		pickup.Status = model.StopStatusArrived
+	case model.OrderStatusCollected:
+		pickup.Status = model.StopStatusCompleted
+	case model.OrderStatusDelivered:
+		pickup.Status = model.StopStatusCompleted
+		dropoff.Status = model.StopStatusCompleted
+	}
+
+	return []model.OrderStop{pickup, dropoff}
+}
+
+// Convierte las paradas recibidas en entidades asociadas a una orden
+// La posición de cada parada corresponde al orden en que fue enviada
+func buildOrderStops(orderID uint, dtos []model.OrderStopDTO) []model.OrderStop {
+	stops := make([]model.OrderStop, len(dtos))
+	for i, dto := range dtos {
+		stops[i] = model.OrderStop{
+			OrderID:      orderID,
+			Position:     i + 1,
+			Type:         dto.Type,
+			Address:      dto.Address,
+			ContactName:  dto.ContactName,
+			ContactPhone: dto.ContactPhone,
+			WindowStart:  dto.WindowStart,
+			WindowEnd:    dto.WindowEnd,
+			Status:       model.StopStatusPending,
+		}
+	}
+	return stops
+}
+
+// Valida que la ruta tenga al menos una recolección y una entrega y que las ventanas horarias sean coherentes
+// Retorna la lista de errores encontrados
+func validateOrderStops(dtos []model.OrderStopDTO) []string {
+	var errs []string
+	hasPickup, hasDropoff := false, false
+
+	for i, dto := range dtos {
+		hasPickup = hasPickup || dto.Type == model.StopTypePickup
+		hasDropoff = hasDropoff || dto.Type == model.StopTypeDropoff
+
+		if dto.WindowStart != nil && dto.WindowEnd != nil && !dto.WindowStart.Before(*dto.WindowEnd) {
+			errs = append(errs, fmt.Sprintf("Stop %d has a time window that ends before it starts", i+1))
+		}
+	}
+
+	if !hasPickup {
+		errs = append(errs, "The route must include at least one pickup stop")
+	}
+
+	if !hasDropoff {
+		errs = append(errs, "The route must include at least one dropoff stop")
+	}
+
+	return errs
+}
+
+// Actualiza las paradas de una orden después de editarla
+// Las paradas recibidas reemplazan a las anteriores; si no se envían, solo se sincroniza el origen y destino
+func updateOrderRoute(tx *gorm.DB, order *model.Order, stops []model.OrderStop, originChanged, destinationChanged bool) error {
+	if stops != nil {
+		if err := tx.Where("order_id = ?", order.ID).Delete(&model.OrderStop{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&stops).Error
+	}
+
+	if originChanged {
+		err := syncStopAddress(tx, order.ID, model.StopTypePickup, "position", order.Origin)
+		if err != nil {
+			return err
+		}
+	}
+
+	if destinationChanged {
+		return syncStopAddress(tx, order.ID, model.StopTypeDropoff, "position DESC", order.Destination)
+	}
+
+	return nil
+}
+
+// Reemplaza la dirección de la primera parada del tipo indicado según el orden recibido
+func syncStopAddress(tx *gorm.DB, orderID uint, stopType model.StopType, order string, address string) error {
+	var stop model.OrderStop
+	err := tx.Where("order_id = ? AND type = ?", orderID, stopType).Order(order).Limit(1).Find(&stop).Error
+	if err != nil || stop.ID == 0 {
+		return err
+	}
+
+	return tx.Model(&stop).Update("address", address).Error
+}
+
+// Resume la ruta en el origen y destino de la orden
+// El origen es la primera recolección y el destino la última entrega
+func routeEndpoints(stops []model.OrderStop) (string, string) {
+	var origin, destination string
+	for _, stop := range stops {
+		if stop.Type == model.StopTypePickup && origin == "" {
+			origin = stop.Address
+		}
+		if stop.Type == model.StopTypeDropoff {
+			destination = stop.Address
+		}
+	}
+
+	return truncateAddress(origin), truncateAddress(destination)
+}
+
+func truncateAddress(address string) string {
+	runes := []rune(address)
+	if len(runes) <= routeSummaryLength {
+		return address
+	}
+	return string(runes[:routeSummaryLength])
+}
+
+// Posición de un estado dentro del recorrido de la ruta
+// Retorna -1 si el estado no forma parte del recorrido
+func routeRank(status model.OrderStatus) int {
+	for i, s := range routeProgression {
+		if s == status {
+			return i
+		}
+	}
+	return -1
+}
+
+// Estados por los que pasa una orden para llegar desde su estado actual al deducido de sus paradas
+// Retorna una lista vacía si la orden ya está en ese estado o más adelante
+func routeSteps(from, target model.OrderStatus) []model.OrderStatus {
+	var steps []model.OrderStatus
+	for rank := routeRank(from); rank >= 0 && rank < routeRank(target); rank++ {
+		steps = append(steps, routeProgression[rank+1])
+	}
+	return steps
+}
+
+// Avanza la orden hasta el estado deducido de sus paradas
+// Registra en el historial cada estado intermedio para conservar la secuencia completa
+// Las transiciones deben validarse antes con routeSteps y CanTransitionTo
+func advanceRouteStatus(tx *gorm.DB, order *model.Order, target model.OrderStatus, userID uint) error {
+	for _, next := range routeSteps(order.Status, target) {
+		if err := setOrderStatus(tx, order.ID, order.Status, next, userID, nil); err != nil {
+			return err
+		}
+		order.Status = next
+	}
+
+	return nil
+}
+
+// Construye el recorrido visible para el cliente, sin los datos de contacto de cada parada
+// Retorna las paradas y la posición de la parada en curso, nula si ya no queda ninguna
+func trackingStops(stops []model.OrderStop) ([]model.TrackingStopDTO, *int) {
+	tracked := make([]model.TrackingStopDTO, len(stops))
+	for i, stop := range stops {
+		tracked[i] = model.TrackingStopDTO{
+			Position:    stop.Position,
+			Type:        stop.Type,
+			Address:     stop.Address,
+			Status:      stop.Status,
+			WindowStart: stop.WindowStart,
+			WindowEnd:   stop.WindowEnd,
+		}
+	}
+
+	current := model.CurrentStopIndex(stops)
+	if current < 0 {
+		return tracked, nil
+	}
+	return tracked, &stops[current].Position
+}
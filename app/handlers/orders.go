@@ -5,6 +5,8 @@ import (
 	"dapa/app/model"
 	"dapa/app/utils"
 	"dapa/database"
+	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
@@ -12,13 +14,18 @@ import (
 	"gorm.io/gorm"
 )
 
+// La orden cambió de estado mientras se procesaba la petición
+var errOrderStatusChanged = errors.New("order status changed")
+
 // @Summary		Creates and order from a submission
-// @Description	Changes a submission status and creates a new order with the information provided
+// @Description	Changes a submission status and creates a new order with the information provided or the data of an accepted quote
 // @Tags		orders
 // @Produce		json
 // @Param		order body model.AcceptSubmissionDTO true "Order information"
 // @Success		200	{object} model.ApiResponse "Order successfully created"
 // @Failure		400	{object} model.ApiResponse "Invalid request format"
+// @Failure		404	{object} model.ApiResponse "Quote not found"
+// @Failure		409	{object} model.ApiResponse "Quote not accepted or submission already has an order"
 // @Failure		500	{object} model.ApiResponse "Error creating order"
 // @Router		/orders/ [post]
 func CreateOrderHandler(c *gin.Context) {
@@ -31,55 +38,31 @@ func CreateOrderHandler(c *gin.Context) {
 		return
 	}
 
-	token, err := utils.GenerateSecureToken(32)
-	if err != nil {
-		utils.RespondWithInternalError(c, "Error creating order")
+	if req.QuoteID != nil && !applyAcceptedQuote(c, &req) {
 		return
 	}
 
-	ctx := context.Background()
-	err = database.DB.Transaction(func(tx *gorm.DB) error {
-		_, txErr := gorm.G[model.Submission](tx).Where("id = ?", req.SubmissionID).Update(ctx, "status", "approved")
-		if txErr != nil {
-			return txErr
-		}
-
-		currentDate := time.Now().Truncate(24 * time.Hour)
-
-		order := model.Order{
-			SubmissionID: req.SubmissionID,
-			UserID:       nil,
-			VehicleID:    nil,
-			HelperID:     nil,
-			ClientName:   req.ClientName,
-			ClientPhone:  req.ClientPhone,
-			Origin:       req.Origin,
-			Destination:  req.Destination,
-			TotalAmount:  req.TotalAmount,
-			Details:      req.Details,
-			Status:       "pending",
-			Type:         req.Type,
-			Date:         currentDate,
-			MeetingDate:  req.MeetingDate,
-		}
-		txErr = gorm.G[model.Order](tx).Create(ctx, &order)
-		if txErr != nil {
-			return txErr
+	stops := buildOrderStops(0, req.Stops)
+	if len(stops) > 0 {
+		if errs := validateOrderStops(req.Stops); len(errs) > 0 {
+			utils.RespondWithErrors(c, http.StatusBadRequest, errs, "Invalid order route")
+			return
 		}
 
-		orderToken := model.OrderToken{
-			OrderID: order.ID,
-			Token:   token,
-			Expiry:  nil,
-		}
-		txErr = gorm.G[model.OrderToken](tx).Create(ctx, &orderToken)
-		if txErr != nil {
-			return txErr
-		}
+		req.Origin, req.Destination = routeEndpoints(stops)
+	}
 
-		return nil
+	claims := c.MustGet("claims").(*model.EmployeeClaims)
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		_, txErr := createOrder(tx, req, stops, claims.UserID)
+		return txErr
 	})
 
+	if errors.Is(err, errSubmissionHasOrder) {
+		utils.RespondWithCustomError(c, http.StatusConflict, "The submission already has an order", "Submission already has an order")
+		return
+	}
+
 	if err != nil {
 		utils.RespondWithInternalError(c, "Error creating order")
 		return
@@ -89,10 +72,13 @@ func CreateOrderHandler(c *gin.Context) {
 }
 
 // @Summary		Get all orders in the system
-// @Description	Returns a list of all orders, if the user is a driver or helper it returns only their associated orders
+// @Description	Returns a list of all orders, users without the orders:read:all permission only get the orders assigned to them
 // @Tags		orders
 // @Produce		json
+// @Produce		text/csv
+// @Produce		application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
 // @Param       status query string false "Order status"
+// @Param		format query string false "Export format" Enums(json, csv, xlsx)
 // @Success		200	{object} model.ApiResponse "List of orders"
 // @Failure		500	{object} model.ApiResponse "Error retrieving orders"
 // @Router		/orders/ [get]
@@ -100,48 +86,62 @@ func GetOrdersHandler(c *gin.Context) {
 	claims := c.MustGet("claims").(*model.EmployeeClaims)
 	status := c.Query("status")
 
-	var orders []model.Order
-	var err error
-
-	if claims.Role == "admin" {
-		if status == "" {
-			err = database.DB.Find(&orders).Error
-		} else {
-			err = database.DB.Where("status = ?", status).Find(&orders).Error
-		}
-
-		if err != nil {
-			utils.RespondWithInternalError(c, "Error fetching orders")
-			return
-		}
+	permissions, ok := userPermissions(c)
+	if !ok {
+		return
+	}
 
-		utils.RespondWithSuccess(c, http.StatusOK, orders, "Orders fetched successfully")
+	query := database.DB.Preload("Items")
+	switch {
+	case permissions.HasAny(model.PermissionOrdersReadAll):
+	case permissions.HasAny(model.PermissionOrdersReadOwn):
+		query = query.Where("user_id = ? OR helper_id = ?", claims.UserID, claims.UserID)
+	default:
+		utils.RespondWithUnathorizedError(c)
 		return
 	}
 
-	if claims.Role == "driver" {
-		err = database.DB.Where("user_id = ?", claims.UserID).Find(&orders).Error
-		if err != nil {
-			utils.RespondWithInternalError(c, "Error fetching orders")
-			return
-		}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
 
-		utils.RespondWithSuccess(c, http.StatusOK, orders, "Orders fetched successfully")
+	var orders []model.Order
+	if err := query.Find(&orders).Error; err != nil {
+		utils.RespondWithInternalError(c, "Error fetching orders")
 		return
 	}
 
-	if claims.Role == "helper" {
-		err = database.DB.Where("helper_id = ?", claims.UserID).Find(&orders).Error
-		if err != nil {
-			utils.RespondWithInternalError(c, "Error fetching orders")
-			return
-		}
-
-		utils.RespondWithSuccess(c, http.StatusOK, orders, "Orders fetched successfully")
+	if err := computeOrderTotals(orders); err != nil {
+		utils.RespondWithInternalError(c, "Error fetching orders")
 		return
 	}
 
-	utils.RespondWithUnathorizedError(c)
+	utils.RespondWithExport(c, orders, ordersExport(orders), "Orders fetched successfully", "ordenes")
+}
+
+// Filas de la exportación de órdenes con sus totales calculados
+func ordersExport(orders []model.Order) []model.OrderExportDTO {
+	rows := make([]model.OrderExportDTO, len(orders))
+	for i, order := range orders {
+		rows[i] = model.OrderExportDTO{
+			ID:            order.ID,
+			ClientName:    order.ClientName,
+			ClientPhone:   order.ClientPhone,
+			Origin:        order.Origin,
+			Destination:   order.Destination,
+			TotalAmount:   order.TotalAmount,
+			Details:       order.Details,
+			Status:        order.Status,
+			Type:          order.Type,
+			Date:          order.Date,
+			MeetingDate:   order.MeetingDate,
+			TotalWeightKg: order.TotalWeightKg,
+			AmountPaid:    order.AmountPaid,
+			BalanceDue:    order.BalanceDue,
+			PaymentStatus: order.PaymentStatus,
+		}
+	}
+	return rows
 }
 
 // @Summary		Get one order by ID
@@ -155,25 +155,35 @@ func GetOrdersHandler(c *gin.Context) {
 // @Router		/orders/{id} [get]
 func GetOrderHandler(c *gin.Context) {
 	var order model.Order
-	claims := c.MustGet("claims").(*model.EmployeeClaims)
 
 	id := c.Param("id")
-	err := database.DB.Where("id = ?", id).First(&order).Error
+	err := database.DB.Preload("Items").Where("id = ?", id).First(&order).Error
 	if err != nil {
 		utils.RespondWithInternalError(c, "Error fetching order")
 		return
 	}
 
-	if claims.Role == "driver" && (order.UserID == nil || *order.UserID != claims.UserID) {
-		utils.RespondWithUnathorizedError(c)
+	if !authorizeOrderRead(c, &order) {
 		return
 	}
 
-	utils.RespondWithSuccess(c, http.StatusOK, order, "Order fetched successfully")
+	order.Stops, err = loadOrderStops(database.DB, &order)
+	if err != nil {
+		utils.RespondWithInternalError(c, "Error fetching order")
+		return
+	}
+
+	orders := []model.Order{order}
+	if err = computeOrderTotals(orders); err != nil {
+		utils.RespondWithInternalError(c, "Error fetching order")
+		return
+	}
+
+	utils.RespondWithSuccess(c, http.StatusOK, orders[0], "Order fetched successfully")
 }
 
 // @Summary		Update one order
-// @Description	Updates one order's data
+// @Description	Updates one order's data. The driver, helper and vehicle are changed through the assignment endpoint
 // @Tags		orders
 // @Produce		json
 // @Param       id path int true "Order ID"
@@ -182,6 +192,7 @@ func GetOrderHandler(c *gin.Context) {
 // @Failure		400 {object} model.ApiResponse "Invalid request format"
 // @Failure		403 {object} model.ApiResponse "Insufficient permissions"
 // @Failure		404 {object} model.ApiResponse "Order not found"
+// @Failure		409 {object} model.ApiResponse "Scheduling conflict"
 // @Failure		500 {object} model.ApiResponse "Error updating order"
 // @Router		/orders/{id} [put]
 func UpdateOrderHandler(c *gin.Context) {
@@ -206,6 +217,69 @@ func UpdateOrderHandler(c *gin.Context) {
 		return
 	}
 
+	var stops []model.OrderStop
+	if req.Stops != nil {
+		if routeRank(order.Status) > routeRank(model.OrderStatusAssigned) {
+			utils.RespondWithCustomError(
+				c,
+				http.StatusConflict,
+				fmt.Sprintf("The route can't be changed while the order is %s", order.Status),
+				"Invalid order route",
+			)
+			return
+		}
+
+		if errs := validateOrderStops(req.Stops); len(errs) > 0 {
+			utils.RespondWithErrors(c, http.StatusBadRequest, errs, "Invalid order route")
+			return
+		}
+
+		stops = buildOrderStops(order.ID, req.Stops)
+		req.Origin, req.Destination = routeEndpoints(stops)
+	}
+
+	// El personal y el vehículo solo se cambian con la asignación, que valida la agenda y registra el cambio de estado
+	sameID := func(requested, current *uint) bool {
+		return requested == nil || (current != nil && *requested == *current)
+	}
+	if !sameID(req.UserID, order.UserID) || !sameID(req.VehicleID, order.VehicleID) || !sameID(req.HelperID, order.HelperID) {
+		utils.RespondWithCustomError(
+			c,
+			http.StatusBadRequest,
+			"The driver, helper and vehicle can only be changed through the order assignment",
+			"Invalid request format",
+		)
+		return
+	}
+
+	assignment := orderAssignment{
+		UserID:    order.UserID,
+		VehicleID: order.VehicleID,
+		HelperID:  order.HelperID,
+	}
+
+	var cargoKg float64
+	if req.Items != nil {
+		for _, item := range buildOrderItems(order.ID, req.Items) {
+			cargoKg += item.TotalWeightKg()
+		}
+	} else {
+		cargoKg, err = orderCargoKg(order.ID)
+		if err != nil {
+			utils.RespondWithInternalError(c, "Error updating order")
+			return
+		}
+	}
+
+	// Solo se revalida la asignación si cambia la fecha o la carga
+	newDay, _ := utils.DayBounds(req.MeetingDate)
+	currentDay, _ := utils.DayBounds(order.MeetingDate)
+	revalidate := !newDay.Equal(currentDay) || req.Items != nil
+
+	phoneChanged := utils.NormalizePhone(req.ClientPhone) != utils.NormalizePhone(order.ClientPhone)
+	originChanged := req.Origin != order.Origin
+	destinationChanged := req.Destination != order.Destination
+
 	order.ClientName = req.ClientName
 	order.ClientPhone = req.ClientPhone
 	order.Origin = req.Origin
@@ -214,24 +288,61 @@ func UpdateOrderHandler(c *gin.Context) {
 	order.Type = req.Type
 	order.MeetingDate = req.MeetingDate
 
-	if req.UserID != nil {
-		order.UserID = req.UserID
-	}
-
-	if req.VehicleID != nil {
-		order.VehicleID = req.VehicleID
-	}
-
-	if req.HelperID != nil {
-		order.HelperID = req.HelperID
-	}
 
 	if req.Details != nil {
 		order.Details = *req.Details
 	}
 
-	err = database.DB.Save(&order).Error
-	if err != nil {
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if revalidate {
+			if txErr := checkAssignment(tx, &order, assignment, order.MeetingDate, cargoKg); txErr != nil {
+				return txErr
+			}
+		}
+
+		if order.CustomerID == nil || phoneChanged {
+			customerID, txErr := resolveCustomer(tx, order.ClientName, order.ClientPhone)
+			if txErr != nil {
+				return txErr
+			}
+			order.CustomerID = customerID
+		}
+
+		// El estado y la asignación no se modifican aquí, así no se sobrescriben los cambios de otras peticiones
+		txErr := tx.Model(&order).
+			Select("client_name", "client_phone", "origin", "destination", "total_amount", "type", "meeting_date", "details", "customer_id").
+			Updates(&order).Error
+		if txErr != nil {
+			return txErr
+		}
+
+		if txErr := updateOrderRoute(tx, &order, stops, originChanged, destinationChanged); txErr != nil {
+			return txErr
+		}
+
+		if req.Items == nil {
+			return nil
+		}
+
+		// Los artículos enviados reemplazan por completo a los anteriores
+		if txErr := tx.Where("order_id = ?", order.ID).Delete(&model.OrderItem{}).Error; txErr != nil {
+			return txErr
+		}
+
+		if len(req.Items) == 0 {
+			return nil
+		}
+
+		items := buildOrderItems(order.ID, req.Items)
+		return tx.Create(&items).Error
+	})
+
+	var rejected *assignmentError
+	switch {
+	case errors.As(err, &rejected):
+		respondAssignmentError(c, rejected)
+		return
+	case err != nil:
 		utils.RespondWithInternalError(c, "Error updating order")
 		return
 	}
@@ -240,14 +351,16 @@ func UpdateOrderHandler(c *gin.Context) {
 }
 
 // @Summary		Assigns a driver and vehicle
-// @Description	Updates the order's data to include the driver and vehicle ID
+// @Description	Updates the order's data to include the driver and vehicle ID after checking availability and double-bookings
 // @Tags		orders
 // @Produce		json
 // @Param       id path int true "Order ID"
+// @Param		assignment body model.AssignOrderDTO true "Driver, helper and vehicle IDs"
 // @Success		200	{object} model.ApiResponse "Order assigned successfully"
 // @Failure		400 {object} model.ApiResponse "Invalid request format"
 // @Failure		403 {object} model.ApiResponse "Insufficient permissions"
 // @Failure		404 {object} model.ApiResponse "Order not found"
+// @Failure		409 {object} model.ApiResponse "Invalid status transition or scheduling conflict"
 // @Failure		500 {object} model.ApiResponse "Error assigning order"
 // @Router		/orders/{id}/assign [patch]
 func AssignOrderHandler(c *gin.Context) {
@@ -257,6 +370,7 @@ func AssignOrderHandler(c *gin.Context) {
 		return
 	}
 
+	claims := c.MustGet("claims").(*model.EmployeeClaims)
 	id := c.Param("id")
 	var order model.Order
 	var err error
@@ -272,34 +386,74 @@ func AssignOrderHandler(c *gin.Context) {
 		return
 	}
 
-	order.UserID = &req.UserID
-	order.VehicleID = &req.VehicleID
-	order.HelperID = &req.HelperID
-	order.Status = "assigned"
+	permissions, ok := userPermissions(c)
+	if !ok {
+		return
+	}
+
+	previousStatus := order.Status
+	if previousStatus != model.OrderStatusAssigned && !previousStatus.CanTransitionTo(model.OrderStatusAssigned, permissions) {
+		utils.RespondWithCustomError(
+			c,
+			http.StatusConflict,
+			"Order can't be assigned in its current status",
+			"Invalid status transition",
+		)
+		return
+	}
 
-	err = database.DB.Save(&order).Error
+	assignment := orderAssignment{
+		UserID:    &req.UserID,
+		VehicleID: &req.VehicleID,
+		HelperID:  &req.HelperID,
+	}
+	cargoKg, err := orderCargoKg(order.ID)
 	if err != nil {
 		utils.RespondWithInternalError(c, "Error assigning order")
 		return
 	}
 
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if txErr := checkAssignment(tx, &order, assignment, order.MeetingDate, cargoKg); txErr != nil {
+			return txErr
+		}
+		return assignOrder(tx, &order, assignment, claims.UserID)
+	})
+
+	var rejected *assignmentError
+	switch {
+	case errors.As(err, &rejected):
+		respondAssignmentError(c, rejected)
+		return
+	case errors.Is(err, errOrderStatusChanged):
+		respondOrderStatusChanged(c)
+		return
+	case err != nil:
+		utils.RespondWithInternalError(c, "Error assigning order")
+		return
+	}
+
 	utils.RespondWithSuccess(c, http.StatusOK, nil, "Order assigned successfully")
 }
 
 // @Summary		Changes and order's status
-// @Description	Updates the order's data to the provided status
+// @Description	Moves the order to the provided status if the transition is allowed for the user's role
 // @Tags		orders
 // @Produce		json
 // @Param       id path int true "Order ID"
 // @Param		status body model.OrderStatusDTO true "Updated order status"
 // @Success		200	{object} model.ApiResponse "Order status updated successfully"
 // @Failure		400 {object} model.ApiResponse "Invalid request format"
+// @Failure		403 {object} model.ApiResponse "Insufficient permissions"
+// @Failure		404 {object} model.ApiResponse "Order not found"
+// @Failure		409 {object} model.ApiResponse "Invalid status transition or missing proof of delivery"
 // @Failure		500 {object} model.ApiResponse "Error updating order status"
 // @Router		/orders/{id}/status [patch]
 func ChangeOrderStatusHandler(c *gin.Context) {
 	var req model.OrderStatusDTO
 	var err error
 
+	claims := c.MustGet("claims").(*model.EmployeeClaims)
 	id := c.Param("id")
 	err = c.ShouldBindJSON(&req)
 	if err != nil {
@@ -307,33 +461,245 @@ func ChangeOrderStatusHandler(c *gin.Context) {
 		return
 	}
 
-	ctx := context.Background()
+	var order model.Order
+	err = database.DB.Where("id = ?", id).First(&order).Error
+	if err != nil {
+		utils.RespondWithCustomError(
+			c,
+			http.StatusNotFound,
+			"Order not found",
+			"Something went wrong",
+		)
+		return
+	}
 
-	if req.Status != "delivered" {
-		_, err = gorm.G[model.Order](database.DB).Where("id = ?", id).Update(ctx, "status", req.Status)
+	if !authorizeOrderOperation(c, &order) {
+		return
+	}
 
-	} else {
-		err = database.DB.Transaction(func(tx *gorm.DB) error {
-			_, txErr := gorm.G[model.Order](database.DB).Where("id = ?", id).Update(ctx, "status", req.Status)
-			if txErr != nil {
-				return txErr
-			}
+	permissions, ok := userPermissions(c)
+	if !ok {
+		return
+	}
+
+	if !order.Status.CanTransitionTo(req.Status, permissions) {
+		utils.RespondWithCustomError(
+			c,
+			http.StatusConflict,
+			fmt.Sprintf("Can't change status from %s to %s", order.Status, req.Status),
+			"Invalid status transition",
+		)
+		return
+	}
+
+	if req.Status == model.OrderStatusDelivered && !requireProofOfDelivery(c, order.ID) {
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		return setOrderStatus(tx, order.ID, order.Status, req.Status, claims.UserID, nil)
+	})
+
+	switch {
+	case errors.Is(err, errOrderStatusChanged):
+		respondOrderStatusChanged(c)
+		return
+	case err != nil:
+		utils.RespondWithInternalError(c, "Error updating order status")
+		return
+	}
+
+	utils.RespondWithSuccess(c, http.StatusOK, nil, "Order status updated successfully")
+}
+
+// @Summary		Cancels an order
+// @Description	Moves the order to cancelled, cancels its submission and expires the tracking token. The assigned driver can cancel it once assigned
+// @Tags		orders
+// @Accept		json
+// @Produce		json
+// @Param       id path int true "Order ID"
+// @Param		cancel body model.CancelOrderDTO true "Cancellation reason"
+// @Success		200	{object} model.ApiResponse "Order cancelled successfully"
+// @Failure		400 {object} model.ApiResponse "Invalid request format"
+// @Failure		403 {object} model.ApiResponse "Insufficient permissions"
+// @Failure		404 {object} model.ApiResponse "Order not found"
+// @Failure		409 {object} model.ApiResponse "Invalid status transition"
+// @Failure		500 {object} model.ApiResponse "Error cancelling order"
+// @Router		/orders/{id}/cancel [patch]
+func CancelOrderHandler(c *gin.Context) {
+	var req model.CancelOrderDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+
+	closeOrder(c, model.OrderStatusCancelled, req.Reason, "Order cancelled successfully")
+}
+
+// @Summary		Marks an order as failed
+// @Description	Moves the order to failed or no_show when the pickup could not be completed
+// @Tags		orders
+// @Accept		json
+// @Produce		json
+// @Param       id path int true "Order ID"
+// @Param		failure body model.OrderFailureDTO true "Failure status and reason"
+// @Success		200	{object} model.ApiResponse "Order marked as failed"
+// @Failure		400 {object} model.ApiResponse "Invalid request format"
+// @Failure		403 {object} model.ApiResponse "Insufficient permissions"
+// @Failure		404 {object} model.ApiResponse "Order not found"
+// @Failure		409 {object} model.ApiResponse "Invalid status transition"
+// @Failure		500 {object} model.ApiResponse "Error updating order status"
+// @Router		/orders/{id}/failure [patch]
+func FailOrderHandler(c *gin.Context) {
+	var req model.OrderFailureDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+
+	closeOrder(c, req.Status, req.Reason, "Order marked as failed")
+}
+
+// @Summary		Reschedules an order
+// @Description	Sets a new meeting date, releases the assigned staff and vehicle and moves the order back to pending
+// @Tags		orders
+// @Accept		json
+// @Produce		json
+// @Param       id path int true "Order ID"
+// @Param		reschedule body model.RescheduleOrderDTO true "New meeting date"
+// @Success		200	{object} model.ApiResponse "Order rescheduled successfully"
+// @Failure		400 {object} model.ApiResponse "Invalid request format"
+// @Failure		404 {object} model.ApiResponse "Order not found"
+// @Failure		409 {object} model.ApiResponse "Invalid status transition"
+// @Failure		500 {object} model.ApiResponse "Error rescheduling order"
+// @Router		/orders/{id}/reschedule [patch]
+func RescheduleOrderHandler(c *gin.Context) {
+	var req model.RescheduleOrderDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+
+	claims := c.MustGet("claims").(*model.EmployeeClaims)
+	id := c.Param("id")
 
-			_, txErr = gorm.G[model.OrderToken](database.DB).Where("order_id = ?", id).Update(ctx, "expiry", time.Now().Add(3*24*time.Hour))
+	var order model.Order
+	if err := database.DB.Where("id = ?", id).First(&order).Error; err != nil {
+		utils.RespondWithCustomError(
+			c,
+			http.StatusNotFound,
+			"Order not found",
+			"Something went wrong",
+		)
+		return
+	}
+
+	permissions, ok := userPermissions(c)
+	if !ok {
+		return
+	}
+
+	previousStatus := order.Status
+	if previousStatus != model.OrderStatusPending && !previousStatus.CanTransitionTo(model.OrderStatusPending, permissions) {
+		utils.RespondWithCustomError(
+			c,
+			http.StatusConflict,
+			fmt.Sprintf("Can't reschedule an order with status %s", order.Status),
+			"Invalid status transition",
+		)
+		return
+	}
+
+	ctx := context.Background()
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// Solo se reprograma si la orden sigue en el estado con el que se validó
+		result := tx.Model(&model.Order{}).Where("id = ? AND status = ?", order.ID, previousStatus).Updates(map[string]any{
+			"meeting_date": req.MeetingDate,
+			"status":       model.OrderStatusPending,
+			"user_id":      nil,
+			"vehicle_id":   nil,
+			"helper_id":    nil,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != 1 {
+			return errOrderStatusChanged
+		}
+
+		if previousStatus != model.OrderStatusPending {
+			txErr := recordOrderStatus(tx, order.ID, previousStatus, model.OrderStatusPending, claims.UserID, req.Reason)
 			if txErr != nil {
 				return txErr
 			}
+		}
 
-			return nil
-		})
+		// La ruta se recorre de nuevo desde la primera parada
+		txErr := tx.Model(&model.OrderStop{}).Where("order_id = ?", order.ID).Updates(map[string]any{
+			"status":       model.StopStatusPending,
+			"completed_at": nil,
+		}).Error
+		if txErr != nil {
+			return txErr
+		}
+
+		_, txErr = gorm.G[model.Submission](tx).Where("id = ?", order.SubmissionID).Update(ctx, "status", model.FormStatusApproved)
+		if txErr != nil {
+			return txErr
+		}
+
+		return tx.Model(&model.OrderToken{}).Where("order_id = ?", order.ID).Update("expiry", nil).Error
+	})
+
+	switch {
+	case errors.Is(err, errOrderStatusChanged):
+		respondOrderStatusChanged(c)
+		return
+	case err != nil:
+		utils.RespondWithInternalError(c, "Error rescheduling order")
+		return
 	}
 
+	utils.RespondWithSuccess(c, http.StatusOK, nil, "Order rescheduled successfully")
+}
+
+// @Summary		Get an order's status history
+// @Description	Returns the timeline of status changes of one order, oldest first
+// @Tags		orders
+// @Produce		json
+// @Param       id path int true "Order ID"
+// @Success		200	{object} model.ApiResponse "Order history fetched successfully"
+// @Failure		403	{object} model.ApiResponse "Insufficient permissions"
+// @Failure		404	{object} model.ApiResponse "Order not found"
+// @Failure		500	{object} model.ApiResponse "Error fetching order history"
+// @Router		/orders/{id}/history [get]
+func GetOrderHistoryHandler(c *gin.Context) {
+	id := c.Param("id")
+
+	var order model.Order
+	err := database.DB.Where("id = ?", id).First(&order).Error
 	if err != nil {
-		utils.RespondWithInternalError(c, "Error updating order status")
+		utils.RespondWithCustomError(
+			c,
+			http.StatusNotFound,
+			"Order not found",
+			"Something went wrong",
+		)
 		return
 	}
 
-	utils.RespondWithSuccess(c, http.StatusOK, nil, "Order status updated successfully")
+	if !authorizeOrderRead(c, &order) {
+		return
+	}
+
+	var history []model.OrderStatusHistory
+	err = database.DB.Where("order_id = ?", order.ID).Order("changed_at ASC, id ASC").Find(&history).Error
+	if err != nil {
+		utils.RespondWithInternalError(c, "Error fetching order history")
+		return
+	}
+
+	utils.RespondWithSuccess(c, http.StatusOK, history, "Order history fetched successfully")
 }
 
 // @Summary		Returns an order status for client tracking
@@ -372,12 +738,24 @@ func OrderTrackingHandler(c *gin.Context) {
 		return
 	}
 
+	stops, err := loadOrderStops(database.DB, &order)
+	if err != nil {
+		utils.RespondWithInternalError(c, "Could not retrieve order")
+		return
+	}
+
 	orderTracked := model.OrderTrackingDTO{
 		Origin:      order.Origin,
 		Destination: order.Destination,
-		Status:      order.Status,
+		Status:      string(order.Status),
 		Type:        order.Type,
 	}
+	orderTracked.Stops, orderTracked.CurrentStop = trackingStops(stops)
+
+	// Solo hay una parada en curso mientras la orden está asignada o en ruta
+	if routeRank(order.Status) < 0 {
+		orderTracked.CurrentStop = nil
+	}
 
 	utils.RespondWithSuccess(c, http.StatusOK, orderTracked, "Order retrieved successfully")
 }
@@ -403,7 +781,6 @@ func OrderTrackingHandler(c *gin.Context) {
 // @Failure		500	{object} model.ApiResponse "Error retrieving token"
 // @Router		/orders/{id}/token [get]
 func GetOrderTokenHandler(c *gin.Context) {
-	claims := c.MustGet("claims").(*model.EmployeeClaims)
 	id := c.Param("id")
 
 	var order model.Order
@@ -418,8 +795,7 @@ func GetOrderTokenHandler(c *gin.Context) {
 		return
 	}
 
-	if claims.Role == "driver" && (order.UserID == nil || *order.UserID != claims.UserID) {
-		utils.RespondWithUnathorizedError(c)
+	if !authorizeOrderRead(c, &order) {
 		return
 	}
 
@@ -435,4 +811,222 @@ func GetOrderTokenHandler(c *gin.Context) {
 	}
 
 	utils.RespondWithSuccess(c, http.StatusOK, response, "Token retrieved successfully")
-}
\ No newline at end of file
+}
+
+// Crea una orden pendiente junto con sus artículos, paradas y token de seguimiento
+// Marca como aprobado el envío de formulario del que proviene y liga ambos al cliente según su teléfono
+// Las cotizaciones del envío que seguían abiertas quedan reemplazadas por la orden
+// Retorna errSubmissionHasOrder si el envío ya tiene una orden
+func createOrder(tx *gorm.DB, req model.AcceptSubmissionDTO, stops []model.OrderStop, userID uint) (model.Order, error) {
+	token, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return model.Order{}, err
+	}
+
+	// Actualizar el envío primero bloquea su fila, así dos peticiones simultáneas no crean dos órdenes
+	ctx := context.Background()
+	_, err = gorm.G[model.Submission](tx).Where("id = ?", req.SubmissionID).Update(ctx, "status", model.FormStatusApproved)
+	if err != nil {
+		return model.Order{}, err
+	}
+
+	var existing int64
+	if err = tx.Model(&model.Order{}).Where("submission_id = ?", req.SubmissionID).Count(&existing).Error; err != nil {
+		return model.Order{}, err
+	}
+	if existing > 0 {
+		return model.Order{}, errSubmissionHasOrder
+	}
+
+	err = tx.Model(&model.Quote{}).
+		Where("submission_id = ? AND status = ?", req.SubmissionID, model.QuoteStatusDraft).
+		Update("status", model.QuoteStatusSuperseded).Error
+	if err != nil {
+		return model.Order{}, err
+	}
+
+	customerID, err := resolveCustomer(tx, req.ClientName, req.ClientPhone)
+	if err != nil {
+		return model.Order{}, err
+	}
+
+	// El envío queda ligado al cliente si el formulario no permitió identificarlo
+	err = tx.Model(&model.Submission{}).
+		Where("id = ? AND customer_id IS NULL", req.SubmissionID).
+		Update("customer_id", customerID).Error
+	if err != nil {
+		return model.Order{}, err
+	}
+
+	currentDate := time.Now().Truncate(24 * time.Hour)
+
+	order := model.Order{
+		SubmissionID: req.SubmissionID,
+		UserID:       nil,
+		VehicleID:    nil,
+		HelperID:     nil,
+		ClientName:   req.ClientName,
+		ClientPhone:  req.ClientPhone,
+		Origin:       req.Origin,
+		Destination:  req.Destination,
+		TotalAmount:  req.TotalAmount,
+		Details:      req.Details,
+		Status:       model.OrderStatusPending,
+		Type:         req.Type,
+		Date:         currentDate,
+		MeetingDate:  req.MeetingDate,
+		QuoteID:      req.QuoteID,
+		CustomerID:   customerID,
+	}
+	err = gorm.G[model.Order](tx).Create(ctx, &order)
+	if err != nil {
+		return model.Order{}, err
+	}
+
+	err = recordOrderStatus(tx, order.ID, "", model.OrderStatusPending, userID, nil)
+	if err != nil {
+		return model.Order{}, err
+	}
+
+	if len(req.Items) > 0 {
+		items := buildOrderItems(order.ID, req.Items)
+		if err = tx.Create(&items).Error; err != nil {
+			return model.Order{}, err
+		}
+	}
+
+	// Sin paradas explícitas la ruta es el origen y destino de la orden
+	if len(stops) == 0 {
+		stops = defaultOrderStops(&order)
+	}
+	for i := range stops {
+		stops[i].OrderID = order.ID
+	}
+	if err = tx.Create(&stops).Error; err != nil {
+		return model.Order{}, err
+	}
+
+	orderToken := model.OrderToken{
+		OrderID: order.ID,
+		Token:   token,
+		Expiry:  nil,
+	}
+	err = gorm.G[model.OrderToken](tx).Create(ctx, &orderToken)
+	return order, err
+}
+
+// Registra un cambio de estado en el historial de la orden
+// Recibe la transacción, la orden, el estado anterior, el nuevo, el usuario que realizó el cambio y el motivo opcional
+func recordOrderStatus(tx *gorm.DB, orderID uint, from, to model.OrderStatus, userID uint, reason *string) error {
+	entry := model.OrderStatusHistory{
+		OrderID:    orderID,
+		FromStatus: from,
+		ToStatus:   to,
+		Reason:     reason,
+	}
+
+	if userID != 0 {
+		entry.ChangedBy = &userID
+	}
+
+	return tx.Create(&entry).Error
+}
+
+// Cambia el estado de una orden y lo registra en el historial
+// Solo se actualiza si la orden sigue en el estado con el que se validó la transición; si cambió retorna errOrderStatusChanged
+// Al entregarse la orden, el token de seguimiento expira en tres días
+func setOrderStatus(tx *gorm.DB, orderID uint, from, to model.OrderStatus, userID uint, reason *string) error {
+	ctx := context.Background()
+	updated, err := gorm.G[model.Order](tx).Where("id = ? AND status = ?", orderID, from).Update(ctx, "status", to)
+	if err != nil {
+		return err
+	}
+	if updated != 1 {
+		return errOrderStatusChanged
+	}
+
+	err = recordOrderStatus(tx, orderID, from, to, userID, reason)
+	if err != nil {
+		return err
+	}
+
+	if to == model.OrderStatusDelivered {
+		_, err = gorm.G[model.OrderToken](tx).Where("order_id = ?", orderID).Update(ctx, "expiry", time.Now().Add(3*24*time.Hour))
+	}
+
+	return err
+}
+
+// Responde que la orden cambió de estado desde que se leyó, por lo que la transición ya no es válida
+func respondOrderStatusChanged(c *gin.Context) {
+	utils.RespondWithCustomError(
+		c,
+		http.StatusConflict,
+		"The order status changed while processing the request, reload it and try again",
+		"Invalid status transition",
+	)
+}
+
+// Cierra una orden sin completarla (cancelada, fallida o cliente ausente)
+// Cancela el envío asociado y expira el token de seguimiento igual que una entrega
+func closeOrder(c *gin.Context, status model.OrderStatus, reason string, message string) {
+	claims := c.MustGet("claims").(*model.EmployeeClaims)
+	id := c.Param("id")
+
+	var order model.Order
+	if err := database.DB.Where("id = ?", id).First(&order).Error; err != nil {
+		utils.RespondWithCustomError(
+			c,
+			http.StatusNotFound,
+			"Order not found",
+			"Something went wrong",
+		)
+		return
+	}
+
+	if !authorizeOrderOperation(c, &order) {
+		return
+	}
+
+	permissions, ok := userPermissions(c)
+	if !ok {
+		return
+	}
+
+	if !order.Status.CanTransitionTo(status, permissions) {
+		utils.RespondWithCustomError(
+			c,
+			http.StatusConflict,
+			fmt.Sprintf("Can't change status from %s to %s", order.Status, status),
+			"Invalid status transition",
+		)
+		return
+	}
+
+	ctx := context.Background()
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		txErr := setOrderStatus(tx, order.ID, order.Status, status, claims.UserID, &reason)
+		if txErr != nil {
+			return txErr
+		}
+
+		_, txErr = gorm.G[model.Submission](tx).Where("id = ?", order.SubmissionID).Update(ctx, "status", model.FormStatusCancelled)
+		if txErr != nil {
+			return txErr
+		}
+
+		_, txErr = gorm.G[model.OrderToken](tx).Where("order_id = ?", order.ID).Update(ctx, "expiry", time.Now().Add(3*24*time.Hour))
+		return txErr
+	})
+
+	switch {
+	case errors.Is(err, errOrderStatusChanged):
+		respondOrderStatusChanged(c)
+		return
+	case err != nil:
+		utils.RespondWithInternalError(c, "Error updating order status")
+		return
+	}
+
+	utils.RespondWithSuccess(c, http.StatusOK, nil, message)
+}
@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"dapa/app/model"
+	"dapa/app/utils"
+	"dapa/database"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errOrderCancelled        = errors.New("order is cancelled")
+	errPaymentExceedsBalance = errors.New("payment exceeds balance due")
+)
+
+// Columnas del reporte financiero a partir de los pagos recibidos
+const financialReportColumns = "payments.order_id, payments.paid_at AS date, orders.type, payments.amount AS total_amount, " +
+	"payments.method AS payment_method, COALESCE(users.name || ' ' || users.last_name, '') AS \"user\""
+
+// @Summary		Get the payments of an order
+// @Description	Returns the payments registered for the order with its balance due and payment status
+// @Tags		payments
+// @Produce		json
+// @Param       id path int true "Order ID"
+// @Success		200	{object} model.ApiResponse "Order payments"
+// @Failure		403	{object} model.ApiResponse "Insufficient permissions"
+// @Failure		404	{object} model.ApiResponse "Order not found"
+// @Failure		500	{object} model.ApiResponse "Error fetching payments"
+// @Router		/orders/{id}/payments [get]
+func GetOrderPaymentsHandler(c *gin.Context) {
+	var order model.Order
+	if err := database.DB.Where("id = ?", c.Param("id")).First(&order).Error; err != nil {
+		utils.RespondWithCustomError(
+			c,
+			http.StatusNotFound,
+			"Order not found",
+			"Something went wrong",
+		)
+		return
+	}
+
+	if !authorizeOrderRead(c, &order) {
+		return
+	}
+
+	var payments []model.Payment
+	if err := database.DB.Where("order_id = ?", order.ID).Order("paid_at, id").Find(&payments).Error; err != nil {
+		utils.RespondWithInternalError(c, "Error fetching payments")
+		return
+	}
+
+	var paid float64
+	for _, payment := range payments {
+		if payment.VoidedAt == nil {
+			paid += payment.Amount
+		}
+	}
+	order.ApplyPayments(paid)
+
+	response := gin.H{
+		"payments":      payments,
+		"totalAmount":   order.TotalAmount,
+		"amountPaid":    order.AmountPaid,
+		"balanceDue":    order.BalanceDue,
+		"paymentStatus": order.PaymentStatus,
+	}
+
+	utils.RespondWithSuccess(c, http.StatusOK, response, "Payments fetched successfully")
+}
+
+// @Summary		Registers a payment for an order
+// @Description	Registers a deposit or partial payment; drivers can only register payments for their own orders
+// @Tags		payments
+// @Accept		json
+// @Produce		json
+// @Param       id path int true "Order ID"
+// @Param		payment body model.PaymentDTO true "Payment information"
+// @Success		201	{object} model.ApiResponse "Payment registered successfully"
+// @Failure		400	{object} model.ApiResponse "Invalid request format"
+// @Failure		403	{object} model.ApiResponse "Insufficient permissions"
+// @Failure		404	{object} model.ApiResponse "Order not found"
+// @Failure		409	{object} model.ApiResponse "Payment exceeds balance due"
+// @Failure		500	{object} model.ApiResponse "Error registering payment"
+// @Router		/orders/{id}/payments [post]
+func CreatePaymentHandler(c *gin.Context) {
+	var req model.PaymentDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+
+	claims := c.MustGet("claims").(*model.EmployeeClaims)
+
+	var order model.Order
+	if err := database.DB.Where("id = ?", c.Param("id")).First(&order).Error; err != nil {
+		utils.RespondWithCustomError(
+			c,
+			http.StatusNotFound,
+			"Order not found",
+			"Something went wrong",
+		)
+		return
+	}
+
+	if !authorizeOrderOperation(c, &order) {
+		return
+	}
+
+	paidAt := time.Now()
+	if req.PaidAt != nil {
+		paidAt = *req.PaidAt
+	}
+
+	payment := model.Payment{
+		OrderID:    order.ID,
+		Amount:     req.Amount,
+		Method:     req.Method,
+		PaidAt:     paidAt,
+		Reference:  req.Reference,
+		IsDeposit:  req.IsDeposit,
+		ReceivedBy: &claims.UserID,
+	}
+
+	// La orden queda bloqueada hasta registrar el pago para que dos cobros simultáneos no excedan el saldo
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, order.ID).Error; err != nil {
+			return err
+		}
+
+		if order.Status == model.OrderStatusCancelled {
+			return errOrderCancelled
+		}
+
+		paid, err := orderPayments(tx, []uint{order.ID})
+		if err != nil {
+			return err
+		}
+
+		order.ApplyPayments(paid[order.ID])
+		if req.Amount > order.BalanceDue {
+			return errPaymentExceedsBalance
+		}
+
+		return tx.Create(&payment).Error
+	})
+
+	switch {
+	case errors.Is(err, errOrderCancelled):
+		utils.RespondWithCustomError(
+			c,
+			http.StatusConflict,
+			"Payments can't be registered for cancelled orders",
+			"Order is cancelled",
+		)
+		return
+	case errors.Is(err, errPaymentExceedsBalance):
+		utils.RespondWithCustomError(
+			c,
+			http.StatusConflict,
+			fmt.Sprintf("The payment of %.2f exceeds the balance due of %.2f", req.Amount, order.BalanceDue),
+			"Payment exceeds balance due",
+		)
+		return
+	case err != nil:
+		utils.RespondWithInternalError(c, "Error registering payment")
+		return
+	}
+
+	utils.RespondWithSuccess(c, http.StatusCreated, payment, "Payment registered successfully")
+}
+
+// @Summary		Voids a payment
+// @Description	Voids a payment registered by mistake; the payment is kept for the record but no longer counts toward the balance or income
+// @Tags		payments
+// @Accept		json
+// @Produce		json
+// @Param       id path int true "Order ID"
+// @Param       paymentId path int true "Payment ID"
+// @Param		void body model.VoidPaymentDTO true "Void reason"
+// @Success		200	{object} model.ApiResponse "Payment voided successfully"
+// @Failure		400	{object} model.ApiResponse "Invalid request format"
+// @Failure		403	{object} model.ApiResponse "Insufficient permissions"
+// @Failure		404	{object} model.ApiResponse "Payment not found"
+// @Failure		409	{object} model.ApiResponse "Payment already voided"
+// @Failure		500	{object} model.ApiResponse "Error voiding payment"
+// @Router		/orders/{id}/payments/{paymentId}/void [post]
+func VoidPaymentHandler(c *gin.Context) {
+	var req model.VoidPaymentDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+
+	claims := c.MustGet("claims").(*model.EmployeeClaims)
+
+	var payment model.Payment
+	err := database.DB.Where("id = ? AND order_id = ?", c.Param("paymentId"), c.Param("id")).First(&payment).Error
+	if err != nil {
+		utils.RespondWithCustomError(
+			c,
+			http.StatusNotFound,
+			"Payment not found",
+			"Something went wrong",
+		)
+		return
+	}
+
+	now := time.Now()
+	result := database.DB.Model(&model.Payment{}).
+		Where("id = ? AND voided_at IS NULL", payment.ID).
+		Updates(map[string]any{
+			"voided_at":   now,
+			"voided_by":   claims.UserID,
+			"void_reason": req.Reason,
+		})
+	if result.Error != nil {
+		utils.RespondWithInternalError(c, "Error voiding payment")
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		utils.RespondWithCustomError(
+			c,
+			http.StatusConflict,
+			"The payment was already voided",
+			"Payment already voided",
+		)
+		return
+	}
+
+	payment.VoidedAt = &now
+	payment.VoidedBy = &claims.UserID
+	payment.VoidReason = &req.Reason
+
+	utils.RespondWithSuccess(c, http.StatusOK, payment, "Payment voided successfully")
+}
+
+// Suma los pagos vigentes de cada orden
+// Retorna un mapa con el monto pagado por ID de orden
+func orderPayments(db *gorm.DB, orderIDs []uint) (map[uint]float64, error) {
+	paid := make(map[uint]float64, len(orderIDs))
+	if len(orderIDs) == 0 {
+		return paid, nil
+	}
+
+	var sums []struct {
+		OrderID uint
+		Amount  float64
+	}
+	err := db.Model(&model.Payment{}).
+		Scopes(model.ActivePayments).
+		Select("order_id, SUM(amount) AS amount").
+		Where("order_id IN ?", orderIDs).
+		Group("order_id").
+		Scan(&sums).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sum := range sums {
+		paid[sum.OrderID] = sum.Amount
+	}
+	return paid, nil
+}
+
+// Consulta base de los pagos vigentes junto con su orden y el conductor asignado
+func collectedPaymentsQuery() *gorm.DB {
+	return database.DB.Model(&model.Payment{}).
+		Scopes(model.ActivePayments).
+		Joins("JOIN orders ON orders.id = payments.order_id").
+		Joins("LEFT JOIN users ON users.id = orders.user_id")
+}
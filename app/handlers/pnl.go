@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"dapa/app/model"
+	"dapa/app/utils"
+	"dapa/database"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary		Get the profit and loss statement
+// @Description	Returns the revenue collected per order type, the expenses per type and the gross and net profit of the period, compared with the previous period and the same period of last year. Cost of services are the expenses linked to an order or vehicle
+// @Tags		reports
+// @Produce		json
+// @Produce		text/csv
+// @Produce		application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param		period query string false "Period of the statement" Enums(month, quarter, year, custom)
+// @Param		date query string false "Any date within the month, quarter or year; defaults to today (YYYY-MM-DD)"
+// @Param		startDate query string false "First day of a custom period (YYYY-MM-DD)"
+// @Param		endDate query string false "Last day of a custom period (YYYY-MM-DD)"
+// @Param		groupBy query string false "Includes the statement of each month" Enums(month)
+// @Param		format query string false "Export format" Enums(json, csv, xlsx)
+// @Success		200	{object} model.ApiResponse "Profit and loss statement"
+// @Failure		400	{object} model.ApiResponse "Invalid period"
+// @Failure		500	{object} model.ApiResponse "Error fetching profit and loss statement"
+// @Router		/reports/pnl [get]
+func ProfitAndLossReport(c *gin.Context) {
+	period, ok := parseReportPeriod(c)
+	if !ok {
+		return
+	}
+
+	groupBy := c.Query("groupBy")
+	if groupBy != "" && groupBy != "month" {
+		utils.RespondWithCustomError(c, http.StatusBadRequest, "Statements can only be grouped by month", "Invalid period")
+		return
+	}
+
+	var report model.PnLReportDTO
+	var err error
+	if report.PnLStatement, err = buildPnLStatement(period); err != nil {
+		utils.RespondWithInternalError(c, "Error fetching profit and loss statement")
+		return
+	}
+
+	previous, err := buildPnLStatement(period.Previous())
+	if err != nil {
+		utils.RespondWithInternalError(c, "Error fetching profit and loss statement")
+		return
+	}
+	report.PreviousPeriod = model.NewPnLComparison(report.PnLStatement, previous)
+
+	lastYear, err := buildPnLStatement(period.LastYear())
+	if err != nil {
+		utils.RespondWithInternalError(c, "Error fetching profit and loss statement")
+		return
+	}
+	report.LastYear = model.NewPnLComparison(report.PnLStatement, lastYear)
+
+	if groupBy == "month" {
+		for _, month := range period.Months() {
+			statement, err := buildPnLStatement(month)
+			if err != nil {
+				utils.RespondWithInternalError(c, "Error fetching profit and loss statement")
+				return
+			}
+			report.Months = append(report.Months, statement)
+		}
+	}
+
+	utils.RespondWithExport(c, report, pnlTable(report), "Profit and loss statement fetched successfully", "estado-de-resultados")
+}
+
+// Lee el periodo solicitado; por defecto es el mes actual
+// Retorna false si algún parámetro no es válido, después de enviar la respuesta de error
+func parseReportPeriod(c *gin.Context) (model.ReportPeriod, bool) {
+	kind := model.PeriodKind(c.DefaultQuery("period", string(model.PeriodMonth)))
+
+	if kind == model.PeriodCustom {
+		start, err := time.ParseInLocation("2006-01-02", c.Query("startDate"), time.Local)
+		if err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, err, "Invalid start date format")
+			return model.ReportPeriod{}, false
+		}
+
+		end, err := time.ParseInLocation("2006-01-02", c.Query("endDate"), time.Local)
+		if err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, err, "Invalid end date format")
+			return model.ReportPeriod{}, false
+		}
+
+		period, err := model.NewCustomPeriod(start, end)
+		if err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, err, "Invalid period")
+			return period, false
+		}
+		return period, true
+	}
+
+	date := time.Now()
+	if value := c.Query("date"); value != "" {
+		var err error
+		if date, err = time.ParseInLocation("2006-01-02", value, time.Local); err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, err, "Invalid date format")
+			return model.ReportPeriod{}, false
+		}
+	}
+
+	period, err := model.NewReportPeriod(kind, date)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err, "Invalid period")
+		return period, false
+	}
+	return period, true
+}
+
+// Calcula el estado de resultados del periodo
+// Los ingresos son los pagos cobrados en el periodo, igual que en el control financiero
+func buildPnLStatement(period model.ReportPeriod) (model.PnLStatement, error) {
+	var revenue []model.PnLLine
+	err := collectedPaymentsQuery().
+		Select("orders.type AS label, SUM(payments.amount) AS amount").
+		Where("payments.paid_at >= ? AND payments.paid_at < ?", period.Start, period.End).
+		Group("orders.type").
+		Order("amount DESC").
+		Scan(&revenue).Error
+	if err != nil {
+		return model.PnLStatement{}, err
+	}
+
+	var expenses []struct {
+		Label  string
+		Direct bool
+		Amount float64
+	}
+	err = database.DB.Model(&model.Expense{}).
+		Select("expense_types.type AS label, (expenses.order_id IS NOT NULL OR expenses.vehicle_id IS NOT NULL) AS direct, SUM(expenses.amount) AS amount").
+		Joins("JOIN expense_types ON expense_types.id = expenses.type_id").
+		Where("expenses.date >= ? AND expenses.date < ?", period.Start, period.End).
+		Group("expense_types.type, direct").
+		Order("amount DESC").
+		Scan(&expenses).Error
+	if err != nil {
+		return model.PnLStatement{}, err
+	}
+
+	var costOfServices, operatingExpenses []model.PnLLine
+	for _, expense := range expenses {
+		line := model.PnLLine{Label: expense.Label, Amount: expense.Amount}
+		if expense.Direct {
+			costOfServices = append(costOfServices, line)
+		} else {
+			operatingExpenses = append(operatingExpenses, line)
+		}
+	}
+
+	return model.NewPnLStatement(period, revenue, costOfServices, operatingExpenses), nil
+}
+
+// Construye la tabla de exportación del estado de resultados
+// Cada concepto es una fila y cada periodo una columna: los meses, si se agruparon, el total y las comparaciones
+func pnlTable(report model.PnLReportDTO) utils.Table {
+	table := utils.Table{Headers: []string{"Sección", "Concepto"}}
+	for _, month := range report.Months {
+		table.Headers = append(table.Headers, month.Period.Start.Format("2006-01"))
+	}
+	table.Headers = append(table.Headers, "Total del periodo", "Periodo anterior", "Mismo periodo del año anterior")
+
+	statements := append(slices.Clone(report.Months), report.PnLStatement, report.PreviousPeriod.PnLStatement, report.LastYear.PnLStatement)
+
+	addRow := func(section, concept string, value func(model.PnLStatement) float64) {
+		row := []any{section, concept}
+		for _, statement := range statements {
+			row = append(row, value(statement))
+		}
+		table.Rows = append(table.Rows, row)
+	}
+
+	addLines := func(section, total string, lines func(model.PnLStatement) []model.PnLLine, totalValue func(model.PnLStatement) float64) {
+		var labels []string
+		for _, statement := range statements {
+			for _, line := range lines(statement) {
+				if !slices.Contains(labels, line.Label) {
+					labels = append(labels, line.Label)
+				}
+			}
+		}
+
+		for _, label := range labels {
+			addRow(section, label, func(statement model.PnLStatement) float64 {
+				var amount float64
+				for _, line := range lines(statement) {
+					if line.Label == label {
+						amount += line.Amount
+					}
+				}
+				return amount
+			})
+		}
+		addRow(section, total, totalValue)
+	}
+
+	addLines("Ingresos", "Total de ingresos",
+		func(s model.PnLStatement) []model.PnLLine { return s.Revenue },
+		func(s model.PnLStatement) float64 { return s.TotalRevenue })
+	addLines("Costos de servicio", "Total de costos de servicio",
+		func(s model.PnLStatement) []model.PnLLine { return s.CostOfServices },
+		func(s model.PnLStatement) float64 { return s.TotalCostOfServices })
+	addRow("Utilidad bruta", "Utilidad bruta", func(s model.PnLStatement) float64 { return s.GrossProfit })
+	addLines("Gastos de operación", "Total de gastos de operación",
+		func(s model.PnLStatement) []model.PnLLine { return s.OperatingExpenses },
+		func(s model.PnLStatement) float64 { return s.TotalOperatingExpenses })
+	addRow("Utilidad neta", "Utilidad neta", func(s model.PnLStatement) float64 { return s.NetProfit })
+
+	return table
+}
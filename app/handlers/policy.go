@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"dapa/app/model"
+	"dapa/app/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Políticas de acceso a los recursos
+// Un permiso general da acceso a cualquier recurso; sin él, el usuario solo accede a los recursos que le pertenecen
+
+// Determina si el usuario puede ver una orden
+// Con orders:read:all puede ver cualquiera; con orders:read:own solo las que tiene asignadas como piloto o ayudante
+func canReadOrder(permissions model.PermissionSet, claims *model.EmployeeClaims, order *model.Order) bool {
+	if permissions.HasAny(model.PermissionOrdersReadAll) {
+		return true
+	}
+	return permissions.HasAny(model.PermissionOrdersReadOwn) && isAssignedTo(order, claims.UserID)
+}
+
+// Determina si el usuario puede trabajar una orden: cambiar su estado, registrar pagos o entregarla
+// Con orders:manage puede trabajar cualquiera; con orders:progress solo las que tiene asignadas como piloto o ayudante
+// Los cambios de estado que puede realizar dependen además de los permisos de cada transición
+func canOperateOrder(permissions model.PermissionSet, claims *model.EmployeeClaims, order *model.Order) bool {
+	if permissions.HasAny(model.PermissionOrdersManage) {
+		return true
+	}
+	return permissions.HasAny(model.PermissionOrdersProgress) && isAssignedTo(order, claims.UserID)
+}
+
+// Determina si el usuario puede ver un envío del formulario
+// Con submissions:manage puede ver cualquiera; si no, solo los de las órdenes que puede ver
+func canReadSubmission(permissions model.PermissionSet, claims *model.EmployeeClaims, orders []model.Order) bool {
+	if permissions.HasAny(model.PermissionSubmissionsManage) {
+		return true
+	}
+
+	for i := range orders {
+		if canReadOrder(permissions, claims, &orders[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Determina si el usuario puede acceder a los datos de otro usuario: a los suyos siempre y a los demás con el permiso indicado
+func canAccessUser(permissions model.PermissionSet, claims *model.EmployeeClaims, userID uint, permission model.Permission) bool {
+	return userID == claims.UserID || permissions.HasAny(permission)
+}
+
+// Determina si el usuario puede actualizar los datos de otro usuario con los datos indicados
+// Cambiar el rol o el correo requiere users:manage y nadie puede cambiar su propio rol, para que no se otorgue permisos a sí mismo
+// El correo identifica la cuenta al iniciar sesión y al recuperar la contraseña, así que no se cambia sin users:manage
+func canUpdateUser(permissions model.PermissionSet, claims *model.EmployeeClaims, user *model.User, req *model.UserDTO) bool {
+	if !canAccessUser(permissions, claims, user.ID, model.PermissionUsersManage) {
+		return false
+	}
+	if req.Email != user.Email && !permissions.HasAny(model.PermissionUsersManage) {
+		return false
+	}
+	if req.Role == user.Role {
+		return true
+	}
+	return permissions.HasAny(model.PermissionUsersManage) && user.ID != claims.UserID
+}
+
+// Determina si el usuario puede ver y subir los archivos adjuntos de un recurso
+// Cada empleado puede adjuntar sus propios documentos, como la licencia de conducir, y los de las órdenes que tiene asignadas
+func canAccessAttachments(permissions model.PermissionSet, claims *model.EmployeeClaims, owner model.AttachmentOwner, ownerID uint, order *model.Order) bool {
+	switch owner {
+	case model.AttachmentOwnerUser:
+		return canAccessUser(permissions, claims, ownerID, model.PermissionUsersManage)
+	case model.AttachmentOwnerOrder:
+		return canOperateOrder(permissions, claims, order)
+	case model.AttachmentOwnerExpense:
+		return permissions.HasAny(model.PermissionExpensesManage)
+	case model.AttachmentOwnerVehicle:
+		return permissions.HasAny(model.PermissionVehiclesManage)
+	}
+	return false
+}
+
+// Indica si el usuario está asignado a la orden como piloto o ayudante
+func isAssignedTo(order *model.Order, userID uint) bool {
+	return (order.UserID != nil && *order.UserID == userID) || (order.HelperID != nil && *order.HelperID == userID)
+}
+
+// Verifica que el usuario autenticado pueda ver la orden
+// Envía la respuesta de error y retorna false si no puede
+func authorizeOrderRead(c *gin.Context, order *model.Order) bool {
+	return authorize(c, func(permissions model.PermissionSet, claims *model.EmployeeClaims) bool {
+		return canReadOrder(permissions, claims, order)
+	})
+}
+
+// Verifica que el usuario autenticado pueda trabajar la orden
+// Envía la respuesta de error y retorna false si no puede
+func authorizeOrderOperation(c *gin.Context, order *model.Order) bool {
+	return authorize(c, func(permissions model.PermissionSet, claims *model.EmployeeClaims) bool {
+		return canOperateOrder(permissions, claims, order)
+	})
+}
+
+// Evalúa una política con los permisos y las claims del usuario autenticado
+// Envía la respuesta de error y retorna false si la política no se cumple
+func authorize(c *gin.Context, policy func(model.PermissionSet, *model.EmployeeClaims) bool) bool {
+	claims := c.MustGet("claims").(*model.EmployeeClaims)
+
+	permissions, ok := userPermissions(c)
+	if !ok {
+		return false
+	}
+
+	if !policy(permissions, claims) {
+		utils.RespondWithUnathorizedError(c)
+		return false
+	}
+	return true
+}
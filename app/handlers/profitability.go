@@ -0,0 +1,307 @@
+package handlers
+
+import (
+	"cmp"
+	"dapa/app/model"
+	"dapa/app/utils"
+	"dapa/database"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Columnas por las que se pueden ordenar los reportes de rentabilidad
+var profitabilitySorts = []string{"revenue", "costs", "margin", "marginPct"}
+
+// Filtros y orden de un reporte de rentabilidad
+type profitabilityQuery struct {
+	start     time.Time
+	end       time.Time
+	orderType string
+	sort      string
+	desc      bool
+}
+
+// @Summary		Get profitability per order
+// @Description	Returns the revenue, direct costs and margin of each delivered order within the date range; revenue is the amount collected for the order and costs are the expenses linked to it
+// @Tags		reports
+// @Produce		json
+// @Produce		text/csv
+// @Produce		application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param		startDate query string false "Start of the service date range (YYYY-MM-DD)"
+// @Param		endDate query string false "End of the service date range (YYYY-MM-DD)"
+// @Param		type query string false "Order type"
+// @Param		sort query string false "Sort column" Enums(date, revenue, costs, margin, marginPct)
+// @Param		order query string false "Sort direction" Enums(asc, desc)
+// @Param		format query string false "Export format" Enums(json, csv, xlsx)
+// @Success		200	{object} model.ApiResponse "Profitability per order"
+// @Failure		400	{object} model.ApiResponse "Invalid filters"
+// @Failure		500	{object} model.ApiResponse "Error fetching profitability"
+// @Router		/reports/profitability/orders [get]
+func OrderProfitabilityReport(c *gin.Context) {
+	query, ok := parseProfitabilityQuery(c, append([]string{"date"}, profitabilitySorts...))
+	if !ok {
+		return
+	}
+
+	var orders []model.Order
+	if err := query.deliveredOrders().Find(&orders).Error; err != nil {
+		utils.RespondWithInternalError(c, "Error fetching profitability")
+		return
+	}
+
+	ids := make([]uint, len(orders))
+	for i, order := range orders {
+		ids[i] = order.ID
+	}
+
+	// Los ingresos son los pagos cobrados, igual que en el control financiero y el estado de resultados
+	paid, err := orderPayments(database.DB, ids)
+	if err != nil {
+		utils.RespondWithInternalError(c, "Error fetching profitability")
+		return
+	}
+
+	expenses, err := orderExpenses(ids)
+	if err != nil {
+		utils.RespondWithInternalError(c, "Error fetching profitability")
+		return
+	}
+
+	costs := make(map[uint]float64, len(orders))
+	for _, expense := range expenses {
+		costs[*expense.OrderID] += expense.Amount
+	}
+
+	report := make([]model.OrderProfitabilityDTO, len(orders))
+	for i, order := range orders {
+		report[i] = model.OrderProfitabilityDTO{
+			OrderID:       order.ID,
+			Date:          order.MeetingDate,
+			Type:          order.Type,
+			ClientName:    order.ClientName,
+			VehicleID:     order.VehicleID,
+			Profitability: model.NewProfitability(paid[order.ID], costs[order.ID]),
+		}
+	}
+
+	sortProfitability(report, query, func(row model.OrderProfitabilityDTO) float64 {
+		if query.sort == "date" {
+			return float64(row.Date.Unix())
+		}
+		return profitabilityValue(row.Profitability, query.sort)
+	})
+
+	utils.RespondWithExport(c, report, report, "Order profitability fetched successfully", "rentabilidad-ordenes")
+}
+
+// @Summary		Get profitability per vehicle
+// @Description	Returns the revenue, direct costs and margin of each vehicle within the date range; revenue is the amount collected for its orders and costs are the expenses linked to the vehicle or to its orders. When filtering by type only the expenses of orders of that type are considered
+// @Tags		reports
+// @Produce		json
+// @Produce		text/csv
+// @Produce		application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param		startDate query string false "Start of the date range (YYYY-MM-DD)"
+// @Param		endDate query string false "End of the date range (YYYY-MM-DD)"
+// @Param		type query string false "Order type"
+// @Param		sort query string false "Sort column" Enums(revenue, costs, margin, marginPct)
+// @Param		order query string false "Sort direction" Enums(asc, desc)
+// @Param		format query string false "Export format" Enums(json, csv, xlsx)
+// @Success		200	{object} model.ApiResponse "Profitability per vehicle"
+// @Failure		400	{object} model.ApiResponse "Invalid filters"
+// @Failure		500	{object} model.ApiResponse "Error fetching profitability"
+// @Router		/reports/profitability/vehicles [get]
+func VehicleProfitabilityReport(c *gin.Context) {
+	query, ok := parseProfitabilityQuery(c, profitabilitySorts)
+	if !ok {
+		return
+	}
+
+	var orders []model.Order
+	if err := query.deliveredOrders().Where("vehicle_id IS NOT NULL").Find(&orders).Error; err != nil {
+		utils.RespondWithInternalError(c, "Error fetching profitability")
+		return
+	}
+
+	ids := make([]uint, len(orders))
+	for i, order := range orders {
+		ids[i] = order.ID
+	}
+
+	paid, err := orderPayments(database.DB, ids)
+	if err != nil {
+		utils.RespondWithInternalError(c, "Error fetching profitability")
+		return
+	}
+
+	revenue := map[uint]float64{}
+	costs := map[uint]float64{}
+	trips := map[uint]int{}
+	orderVehicle := make(map[uint]uint, len(orders))
+	for _, order := range orders {
+		orderVehicle[order.ID] = *order.VehicleID
+		revenue[*order.VehicleID] += paid[order.ID]
+		trips[*order.VehicleID]++
+	}
+
+	expenses, err := orderExpenses(ids)
+	if err != nil {
+		utils.RespondWithInternalError(c, "Error fetching profitability")
+		return
+	}
+
+	// Los gastos de una orden se atribuyen al vehículo indicado en el gasto o, si no tiene, al de la orden
+	for _, expense := range expenses {
+		vehicleID := orderVehicle[*expense.OrderID]
+		if expense.VehicleID != nil {
+			vehicleID = *expense.VehicleID
+		}
+		costs[vehicleID] += expense.Amount
+	}
+
+	// Los gastos propios del vehículo no pertenecen a ningún tipo de orden
+	if query.orderType == "" {
+		var vehicleExpenses []model.Expense
+		db := database.DB.Where("order_id IS NULL AND vehicle_id IS NOT NULL")
+		if !query.start.IsZero() {
+			db = db.Where("date >= ?", query.start)
+		}
+		if !query.end.IsZero() {
+			db = db.Where("date < ?", query.end.AddDate(0, 0, 1))
+		}
+
+		if err = db.Find(&vehicleExpenses).Error; err != nil {
+			utils.RespondWithInternalError(c, "Error fetching profitability")
+			return
+		}
+
+		for _, expense := range vehicleExpenses {
+			costs[*expense.VehicleID] += expense.Amount
+		}
+	}
+
+	vehicleIDs := make([]uint, 0, len(costs)+len(revenue))
+	for id := range revenue {
+		vehicleIDs = append(vehicleIDs, id)
+	}
+	for id := range costs {
+		if _, ok := revenue[id]; !ok {
+			vehicleIDs = append(vehicleIDs, id)
+		}
+	}
+	slices.Sort(vehicleIDs)
+
+	var vehicles []model.Vehicle
+	if err = database.DB.Where("id IN ?", vehicleIDs).Find(&vehicles).Error; err != nil {
+		utils.RespondWithInternalError(c, "Error fetching profitability")
+		return
+	}
+
+	report := make([]model.VehicleProfitabilityDTO, len(vehicles))
+	for i, vehicle := range vehicles {
+		report[i] = model.VehicleProfitabilityDTO{
+			VehicleID:     vehicle.ID,
+			Vehicle:       fmt.Sprintf("%s %s", vehicle.Brand, vehicle.Model),
+			LicensePlate:  vehicle.LicensePlate,
+			Orders:        trips[vehicle.ID],
+			Profitability: model.NewProfitability(revenue[vehicle.ID], costs[vehicle.ID]),
+		}
+	}
+
+	sortProfitability(report, query, func(row model.VehicleProfitabilityDTO) float64 {
+		return profitabilityValue(row.Profitability, query.sort)
+	})
+
+	utils.RespondWithExport(c, report, report, "Vehicle profitability fetched successfully", "rentabilidad-vehiculos")
+}
+
+// Lee el rango de fechas, el tipo de orden y el orden solicitados
+// Por defecto se ordena por margen de forma descendente
+// Retorna false si algún parámetro no es válido, después de enviar la respuesta de error
+func parseProfitabilityQuery(c *gin.Context, sorts []string) (profitabilityQuery, bool) {
+	direction := c.DefaultQuery("order", "desc")
+	query := profitabilityQuery{
+		orderType: c.Query("type"),
+		sort:      c.DefaultQuery("sort", "margin"),
+		desc:      direction == "desc",
+	}
+
+	var err error
+	if value := c.Query("startDate"); value != "" {
+		if query.start, err = time.Parse("2006-01-02", value); err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, err, "Invalid start date format")
+			return query, false
+		}
+	}
+
+	if value := c.Query("endDate"); value != "" {
+		if query.end, err = time.Parse("2006-01-02", value); err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, err, "Invalid end date format")
+			return query, false
+		}
+	}
+
+	if !slices.Contains(sorts, query.sort) {
+		utils.RespondWithCustomError(c, http.StatusBadRequest, fmt.Sprintf("Can't sort by %s", query.sort), "Invalid filters")
+		return query, false
+	}
+
+	if direction != "asc" && direction != "desc" {
+		utils.RespondWithCustomError(c, http.StatusBadRequest, "Sort direction must be asc or desc", "Invalid filters")
+		return query, false
+	}
+
+	return query, true
+}
+
+// Consulta de las órdenes entregadas que cumplen con los filtros, según su fecha de servicio
+func (q profitabilityQuery) deliveredOrders() *gorm.DB {
+	db := database.DB.Where("status = ?", model.OrderStatusDelivered)
+	if !q.start.IsZero() {
+		db = db.Where("meeting_date >= ?", q.start)
+	}
+	if !q.end.IsZero() {
+		db = db.Where("meeting_date < ?", q.end.AddDate(0, 0, 1))
+	}
+	if q.orderType != "" {
+		db = db.Where("type = ?", q.orderType)
+	}
+	return db
+}
+
+// Obtiene los gastos vinculados a las órdenes indicadas
+func orderExpenses(orderIDs []uint) ([]model.Expense, error) {
+	var expenses []model.Expense
+	if len(orderIDs) == 0 {
+		return expenses, nil
+	}
+
+	err := database.DB.Where("order_id IN ?", orderIDs).Find(&expenses).Error
+	return expenses, err
+}
+
+func profitabilityValue(p model.Profitability, column string) float64 {
+	switch column {
+	case "revenue":
+		return p.Revenue
+	case "costs":
+		return p.Costs
+	case "marginPct":
+		return p.MarginPct
+	}
+	return p.Margin
+}
+
+// Ordena las filas de un reporte según el valor de la columna solicitada
+func sortProfitability[T any](rows []T, query profitabilityQuery, value func(T) float64) {
+	slices.SortStableFunc(rows, func(a, b T) int {
+		if query.desc {
+			return cmp.Compare(value(b), value(a))
+		}
+		return cmp.Compare(value(a), value(b))
+	})
+}
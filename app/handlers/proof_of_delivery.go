@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"dapa/app/model"
+	"dapa/app/utils"
+	"dapa/database"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Cantidad máxima de fotografías en una constancia de entrega
+const maxDeliveryPhotos = 5
+
+// @Summary		Submits the proof of delivery of an order
+// @Description	Registers the recipient name, signature and photos of the delivery; it's required to mark the order as delivered and replaces any previous proof
+// @Tags		orders
+// @Accept		multipart/form-data
+// @Produce		json
+// @Param       id path int true "Order ID"
+// @Param		recipientName formData string true "Name of the person who received the cargo"
+// @Param		notes formData string false "Delivery notes"
+// @Param		signature formData file true "Signature image"
+// @Param		photos formData file false "Delivery photos"
+// @Success		201	{object} model.ApiResponse "Proof of delivery submitted successfully"
+// @Failure		400	{object} model.ApiResponse "Invalid request format"
+// @Failure		403	{object} model.ApiResponse "Insufficient permissions"
+// @Failure		404	{object} model.ApiResponse "Order not found"
+// @Failure		409	{object} model.ApiResponse "Order not ready for delivery"
+// @Failure		413	{object} model.ApiResponse "File is too large"
+// @Failure		500	{object} model.ApiResponse "Error saving proof of delivery"
+// @Router		/orders/{id}/proof-of-delivery [post]
+func SubmitProofOfDeliveryHandler(c *gin.Context) {
+	claims := c.MustGet("claims").(*model.EmployeeClaims)
+
+	limitUploadBody(c, maxDeliveryPhotos+1)
+	form, err := c.MultipartForm()
+	if err != nil {
+		respondUploadError(c, err)
+		return
+	}
+
+	recipientName := strings.TrimSpace(c.PostForm("recipientName"))
+	notes := strings.TrimSpace(c.PostForm("notes"))
+	signature, signatureErr := c.FormFile("signature")
+	photos := form.File["photos"]
+
+	var errs []string
+	if recipientName == "" || utf8.RuneCountInString(recipientName) > 100 {
+		errs = append(errs, "Recipient name is required and can't exceed 100 characters")
+	}
+	if utf8.RuneCountInString(notes) > 255 {
+		errs = append(errs, "Notes can't exceed 255 characters")
+	}
+	if signatureErr != nil {
+		errs = append(errs, "Signature image is required")
+	}
+	if len(photos) > maxDeliveryPhotos {
+		errs = append(errs, fmt.Sprintf("A proof of delivery can't have more than %d photos", maxDeliveryPhotos))
+	}
+
+	if len(errs) > 0 {
+		utils.RespondWithErrors(c, http.StatusBadRequest, errs, "Invalid request format")
+		return
+	}
+
+	var order model.Order
+	if err = database.DB.Where("id = ?", c.Param("id")).First(&order).Error; err != nil {
+		utils.RespondWithCustomError(
+			c,
+			http.StatusNotFound,
+			"Order not found",
+			"Something went wrong",
+		)
+		return
+	}
+
+	if !authorizeOrderOperation(c, &order) {
+		return
+	}
+
+	if order.Status != model.OrderStatusCollected {
+		utils.RespondWithCustomError(
+			c,
+			http.StatusConflict,
+			"The proof of delivery can only be submitted once the cargo has been collected",
+			"Order not ready for delivery",
+		)
+		return
+	}
+
+	proof := model.ProofOfDelivery{
+		OrderID:       order.ID,
+		RecipientName: recipientName,
+		DeliveredAt:   time.Now(),
+		SubmittedBy:   claims.UserID,
+	}
+	if notes != "" {
+		proof.Notes = &notes
+	}
+
+	uploads := append([]*multipart.FileHeader{signature}, photos...)
+	for i, header := range uploads {
+		file, err := uploadFile(header, fmt.Sprintf("orders/%d/delivery", order.ID), imageTypes)
+		if err != nil {
+			deleteStoredFiles(proof.Files)
+			respondUploadError(c, err)
+			return
+		}
+
+		file.Category = model.AttachmentCategoryPhoto
+		if i == 0 {
+			file.Category = model.AttachmentCategorySignature
+		}
+		file.UploadedBy = &claims.UserID
+		proof.Files = append(proof.Files, file)
+	}
+
+	var previous model.ProofOfDelivery
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if txErr := tx.Preload("Files").Where("order_id = ?", order.ID).Limit(1).Find(&previous).Error; txErr != nil {
+			return txErr
+		}
+
+		if previous.ID != 0 {
+			txErr := tx.Where("owner_type = ? AND owner_id = ?", model.AttachmentOwnerProofOfDelivery, previous.ID).
+				Delete(&model.Attachment{}).Error
+			if txErr != nil {
+				return txErr
+			}
+			if txErr = tx.Delete(&previous).Error; txErr != nil {
+				return txErr
+			}
+		}
+
+		return tx.Create(&proof).Error
+	})
+
+	if err != nil {
+		deleteStoredFiles(proof.Files)
+		utils.RespondWithInternalError(c, "Error saving proof of delivery")
+		return
+	}
+
+	// Los archivos de la constancia reemplazada solo se eliminan cuando la nueva quedó guardada
+	deleteStoredFiles(previous.Files)
+
+	if err = signAttachments(proof.Files); err != nil {
+		utils.RespondWithInternalError(c, "Error saving proof of delivery")
+		return
+	}
+
+	utils.RespondWithSuccess(c, http.StatusCreated, proof, "Proof of delivery submitted successfully")
+}
+
+// @Summary		Get the proof of delivery of an order
+// @Description	Returns the recipient, notes and files registered when the order was delivered, with signed download URLs
+// @Tags		orders
+// @Produce		json
+// @Param       id path int true "Order ID"
+// @Success		200	{object} model.ApiResponse "Proof of delivery"
+// @Failure		403	{object} model.ApiResponse "Insufficient permissions"
+// @Failure		404	{object} model.ApiResponse "Proof of delivery not found"
+// @Failure		500	{object} model.ApiResponse "Error fetching proof of delivery"
+// @Router		/orders/{id}/proof-of-delivery [get]
+func GetProofOfDeliveryHandler(c *gin.Context) {
+	var order model.Order
+	if err := database.DB.Where("id = ?", c.Param("id")).First(&order).Error; err != nil {
+		utils.RespondWithCustomError(
+			c,
+			http.StatusNotFound,
+			"Order not found",
+			"Something went wrong",
+		)
+		return
+	}
+
+	if !authorizeOrderRead(c, &order) {
+		return
+	}
+
+	var proof model.ProofOfDelivery
+	err := database.DB.Preload("Files").Where("order_id = ?", order.ID).First(&proof).Error
+	if err != nil {
+		utils.RespondWithCustomError(
+			c,
+			http.StatusNotFound,
+			"Proof of delivery not found",
+			"Something went wrong",
+		)
+		return
+	}
+
+	if err = signAttachments(proof.Files); err != nil {
+		utils.RespondWithInternalError(c, "Error fetching proof of delivery")
+		return
+	}
+
+	utils.RespondWithSuccess(c, http.StatusOK, proof, "Proof of delivery fetched successfully")
+}
+
+// Verifica que la orden tenga una constancia de entrega
+// Envía la respuesta de conflicto y retorna false si no la tiene o si falla la consulta
+func requireProofOfDelivery(c *gin.Context, orderID uint) bool {
+	var count int64
+	if err := database.DB.Model(&model.ProofOfDelivery{}).Where("order_id = ?", orderID).Count(&count).Error; err != nil {
+		utils.RespondWithInternalError(c, "Error updating order status")
+		return false
+	}
+
+	if count == 0 {
+		utils.RespondWithCustomError(
+			c,
+			http.StatusConflict,
+			"A proof of delivery is required to mark the order as delivered",
+			"Proof of delivery required",
+		)
+		return false
+	}
+
+	return true
+}
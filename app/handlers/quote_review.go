@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"dapa/app/model"
+	"dapa/app/utils"
+	"dapa/database"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var (
+	errQuoteAnswered      = errors.New("quote already answered")
+	errSubmissionHasOrder = errors.New("submission already has an order")
+)
+
+// @Summary		Get quote link token
+// @Description	Returns the token the client uses to review and answer a quote
+// @Tags		quotes
+// @Produce		json
+// @Param       id path int true "Quote ID"
+// @Success		200	{object} model.ApiResponse "Token retrieved successfully"
+// @Failure		404	{object} model.ApiResponse "Token not found"
+// @Router		/quotes/{id}/token [get]
+func GetQuoteTokenHandler(c *gin.Context) {
+	var quoteToken model.QuoteToken
+	if err := database.DB.Where("quote_id = ?", c.Param("id")).First(&quoteToken).Error; err != nil {
+		utils.RespondWithCustomError(
+			c,
+			http.StatusNotFound,
+			"Quote token not found",
+			"Something went wrong",
+		)
+		return
+	}
+
+	response := gin.H{
+		"token":  quoteToken.Token,
+		"expiry": quoteToken.Expiry,
+	}
+
+	utils.RespondWithSuccess(c, http.StatusOK, response, "Token retrieved successfully")
+}
+
+// @Summary		Returns a quote for the client
+// @Description	Returns the quote associated to the token so the client can review it
+// @Tags		quotes
+// @Produce		json
+// @Param		token query string true "Quote token"
+// @Success		200	{object} model.ApiResponse "Quote retrieved successfully"
+// @Failure		400 {object} model.ApiResponse "Token is required"
+// @Failure		404 {object} model.ApiResponse "Quote not found"
+// @Failure		410 {object} model.ApiResponse "Quote has expired"
+// @Router		/quotes/review [get]
+func ReviewQuoteHandler(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		utils.RespondWithError(c, http.StatusBadRequest, nil, "Token is required")
+		return
+	}
+
+	var quote model.Quote
+	var quoteToken model.QuoteToken
+	if !findQuoteByToken(c, token, &quote, &quoteToken) {
+		return
+	}
+
+	review := model.QuoteReviewDTO{
+		Version:     quote.Version,
+		Status:      quote.Status,
+		ClientName:  quote.ClientName,
+		Origin:      quote.Origin,
+		Destination: quote.Destination,
+		Details:     quote.Details,
+		OrderType:   quote.OrderType,
+		MeetingDate: quote.MeetingDate,
+		Breakdown:   quote.PriceBreakdown,
+		Total:       quote.Total,
+		ExpiresAt:   quoteToken.Expiry,
+	}
+
+	utils.RespondWithSuccess(c, http.StatusOK, review, "Quote retrieved successfully")
+}
+
+// @Summary		Accepts a quote from its link
+// @Description	Accepts the quote associated to the token and creates the order with its data
+// @Tags		quotes
+// @Accept		json
+// @Produce		json
+// @Param		token body model.QuoteTokenDTO true "Quote token"
+// @Success		201	{object} model.ApiResponse "Quote accepted successfully"
+// @Failure		400 {object} model.ApiResponse "Invalid request format"
+// @Failure		404 {object} model.ApiResponse "Quote not found"
+// @Failure		409 {object} model.ApiResponse "Quote already answered"
+// @Failure		410 {object} model.ApiResponse "Quote has expired"
+// @Failure		500 {object} model.ApiResponse "Error accepting quote"
+// @Router		/quotes/review/accept [post]
+func AcceptQuoteByTokenHandler(c *gin.Context) {
+	var req model.QuoteTokenDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+
+	var quote model.Quote
+	var quoteToken model.QuoteToken
+	if !findQuoteByToken(c, req.Token, &quote, &quoteToken) || !checkQuoteOpen(c, &quote) {
+		return
+	}
+
+	var order model.Order
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if txErr := answerQuote(tx, &quote, model.QuoteStatusAccepted, nil); txErr != nil {
+			return txErr
+		}
+
+		var txErr error
+		order, txErr = createOrder(tx, quoteOrderRequest(&quote), nil, 0)
+		return txErr
+	})
+
+	if err != nil {
+		respondQuoteAnswerError(c, err, "Error accepting quote")
+		return
+	}
+
+	// El cliente recibe el token para dar seguimiento a su orden
+	var orderToken model.OrderToken
+	if err := database.DB.Where("order_id = ?", order.ID).First(&orderToken).Error; err != nil {
+		utils.RespondWithInternalError(c, "Error accepting quote")
+		return
+	}
+
+	response := gin.H{
+		"trackingToken": orderToken.Token,
+	}
+
+	utils.RespondWithSuccess(c, http.StatusCreated, response, "Quote accepted successfully")
+}
+
+// @Summary		Declines a quote from its link
+// @Description	Declines the quote associated to the token with an optional reason
+// @Tags		quotes
+// @Accept		json
+// @Produce		json
+// @Param		decline body model.DeclineQuoteDTO true "Quote token and reason"
+// @Success		200	{object} model.ApiResponse "Quote declined successfully"
+// @Failure		400 {object} model.ApiResponse "Invalid request format"
+// @Failure		404 {object} model.ApiResponse "Quote not found"
+// @Failure		409 {object} model.ApiResponse "Quote already answered"
+// @Failure		410 {object} model.ApiResponse "Quote has expired"
+// @Failure		500 {object} model.ApiResponse "Error declining quote"
+// @Router		/quotes/review/decline [post]
+func DeclineQuoteByTokenHandler(c *gin.Context) {
+	var req model.DeclineQuoteDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+
+	var quote model.Quote
+	var quoteToken model.QuoteToken
+	if !findQuoteByToken(c, req.Token, &quote, &quoteToken) || !checkQuoteOpen(c, &quote) {
+		return
+	}
+
+	if err := answerQuote(database.DB, &quote, model.QuoteStatusDeclined, req.Reason); err != nil {
+		respondQuoteAnswerError(c, err, "Error declining quote")
+		return
+	}
+
+	utils.RespondWithSuccess(c, http.StatusOK, nil, "Quote declined successfully")
+}
+
+// Busca la cotización asociada a un token
+// Retorna false si no existe o si sigue abierta con el enlace vencido, después de enviar la respuesta de error
+func findQuoteByToken(c *gin.Context, token string, quote *model.Quote, quoteToken *model.QuoteToken) bool {
+	if err := database.DB.Where("token = ?", token).First(quoteToken).Error; err != nil {
+		utils.RespondWithCustomError(
+			c,
+			http.StatusNotFound,
+			"Quote not found",
+			"Something went wrong",
+		)
+		return false
+	}
+
+	if err := database.DB.Where("id = ?", quoteToken.QuoteID).First(quote).Error; err != nil {
+		utils.RespondWithInternalError(c, "Could not retrieve quote")
+		return false
+	}
+
+	// Las cotizaciones ya respondidas se pueden consultar aunque el enlace haya vencido
+	open := quote.Status == model.QuoteStatusDraft
+	if quote.Status == model.QuoteStatusExpired || (open && time.Now().After(quoteToken.Expiry)) {
+		utils.RespondWithCustomError(c, http.StatusGone, "Quote expired", "The time to answer this quote has expired")
+		return false
+	}
+
+	return true
+}
+
+// Verifica que la cotización aún pueda aceptarse o rechazarse
+// Retorna false si ya fue respondida o reemplazada, después de enviar la respuesta de error
+func checkQuoteOpen(c *gin.Context, quote *model.Quote) bool {
+	if quote.Status == model.QuoteStatusDraft {
+		return true
+	}
+
+	utils.RespondWithCustomError(
+		c,
+		http.StatusConflict,
+		fmt.Sprintf("This quote can't be answered because it is %s", quote.Status),
+		"Quote already answered",
+	)
+	return false
+}
+
+// Registra la respuesta del cliente a una cotización
+// Solo se actualiza si sigue siendo un borrador, así dos respuestas simultáneas no se aplican ambas
+// Retorna errQuoteAnswered si otra petición la respondió primero
+func answerQuote(tx *gorm.DB, quote *model.Quote, status model.QuoteStatus, reason *string) error {
+	now := time.Now()
+
+	result := tx.Model(&model.Quote{}).
+		Where("id = ? AND status = ?", quote.ID, model.QuoteStatusDraft).
+		Updates(map[string]any{
+			"status":         status,
+			"decline_reason": reason,
+			"responded_at":   now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected != 1 {
+		return errQuoteAnswered
+	}
+
+	quote.Status = status
+	quote.DeclineReason = reason
+	quote.RespondedAt = &now
+	return nil
+}
+
+// Envía la respuesta de error de una cotización que no se pudo responder
+func respondQuoteAnswerError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, errQuoteAnswered):
+		utils.RespondWithCustomError(c, http.StatusConflict, "This quote was already answered", "Quote already answered")
+	case errors.Is(err, errSubmissionHasOrder):
+		utils.RespondWithCustomError(c, http.StatusConflict, "The submission already has an order", "Submission already has an order")
+	default:
+		utils.RespondWithInternalError(c, message)
+	}
+}
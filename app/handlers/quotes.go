@@ -0,0 +1,550 @@
+package handlers
+
+import (
+	"dapa/app/model"
+	"dapa/app/utils"
+	"dapa/database"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Días en que el cliente puede responder una cotización antes de que expire
+const quoteValidityDays = 7
+
+// Intentos para guardar una versión cuando otra petición toma el mismo número al mismo tiempo
+const quoteVersionAttempts = 3
+
+// @Summary		Get all pricing rules
+// @Description	Returns the pricing rule configured for each order type
+// @Tags		quotes
+// @Produce		json
+// @Success		200	{object} model.ApiResponse "List of pricing rules"
+// @Failure		500	{object} model.ApiResponse "Error fetching pricing rules"
+// @Router		/pricing-rules [get]
+func GetPricingRulesHandler(c *gin.Context) {
+	var rules []model.PricingRule
+	if err := database.DB.Order("order_type").Find(&rules).Error; err != nil {
+		utils.RespondWithInternalError(c, "Error fetching pricing rules")
+		return
+	}
+
+	utils.RespondWithSuccess(c, http.StatusOK, rules, "Pricing rules fetched successfully")
+}
+
+// @Summary		Creates a pricing rule
+// @Description	Configures the fees and surcharges used to quote one order type
+// @Tags		quotes
+// @Accept		json
+// @Produce		json
+// @Param		rule body model.PricingRuleDTO true "Pricing rule"
+// @Success		201	{object} model.ApiResponse "Pricing rule created successfully"
+// @Failure		400	{object} model.ApiResponse "Invalid request format"
+// @Failure		409	{object} model.ApiResponse "Pricing rule already exists"
+// @Failure		500	{object} model.ApiResponse "Error creating pricing rule"
+// @Router		/pricing-rules [post]
+func CreatePricingRuleHandler(c *gin.Context) {
+	var req model.PricingRuleDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+
+	var count int64
+	if err := database.DB.Model(&model.PricingRule{}).Where("order_type = ?", req.OrderType).Count(&count).Error; err != nil {
+		utils.RespondWithInternalError(c, "Error creating pricing rule")
+		return
+	}
+
+	if count > 0 {
+		utils.RespondWithCustomError(
+			c,
+			http.StatusConflict,
+			fmt.Sprintf("A pricing rule for %s already exists", req.OrderType),
+			"Pricing rule already exists",
+		)
+		return
+	}
+
+	rule := model.PricingRule{IsActive: true}
+	applyPricingRule(&rule, req)
+
+	if err := database.DB.Create(&rule).Error; err != nil {
+		utils.RespondWithInternalError(c, "Error creating pricing rule")
+		return
+	}
+
+	utils.RespondWithSuccess(c, http.StatusCreated, rule, "Pricing rule created successfully")
+}
+
+// @Summary		Updates a pricing rule
+// @Description	Updates the fees of one order type; existing quotes keep the price they were issued with
+// @Tags		quotes
+// @Accept		json
+// @Produce		json
+// @Param       id path int true "Pricing rule ID"
+// @Param		rule body model.PricingRuleDTO true "Pricing rule"
+// @Success		200	{object} model.ApiResponse "Pricing rule updated successfully"
+// @Failure		400	{object} model.ApiResponse "Invalid request format"
+// @Failure		404	{object} model.ApiResponse "Pricing rule not found"
+// @Failure		500	{object} model.ApiResponse "Error updating pricing rule"
+// @Router		/pricing-rules/{id} [put]
+func UpdatePricingRuleHandler(c *gin.Context) {
+	var req model.PricingRuleDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+
+	var rule model.PricingRule
+	if err := database.DB.Where("id = ?", c.Param("id")).First(&rule).Error; err != nil {
+		utils.RespondWithCustomError(
+			c,
+			http.StatusNotFound,
+			"Pricing rule not found",
+			"Something went wrong",
+		)
+		return
+	}
+
+	applyPricingRule(&rule, req)
+
+	if err := database.DB.Save(&rule).Error; err != nil {
+		utils.RespondWithInternalError(c, "Error updating pricing rule")
+		return
+	}
+
+	utils.RespondWithSuccess(c, http.StatusOK, rule, "Pricing rule updated successfully")
+}
+
+// @Summary		Previews the price of a move
+// @Description	Calculates the price breakdown with the pricing rule of the order type without storing a quote
+// @Tags		quotes
+// @Accept		json
+// @Produce		json
+// @Param		quote body model.QuotePreviewDTO true "Move information"
+// @Success		200	{object} model.ApiResponse "Price breakdown"
+// @Failure		400	{object} model.ApiResponse "Invalid request format"
+// @Failure		404	{object} model.ApiResponse "Pricing rule not found"
+// @Failure		500	{object} model.ApiResponse "Error calculating quote"
+// @Router		/quotes/preview [post]
+func PreviewQuoteHandler(c *gin.Context) {
+	var req model.QuotePreviewDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+
+	breakdown, ok := calculateQuote(c, req)
+	if !ok {
+		return
+	}
+
+	utils.RespondWithSuccess(c, http.StatusOK, breakdown, "Quote calculated successfully")
+}
+
+// @Summary		Creates a quote for a submission
+// @Description	Stores a new version of the submission's quote; the previous open version is superseded
+// @Tags		quotes
+// @Accept		json
+// @Produce		json
+// @Param		quote body model.CreateQuoteDTO true "Quote information"
+// @Success		201	{object} model.ApiResponse "Quote created successfully"
+// @Failure		400	{object} model.ApiResponse "Invalid request format"
+// @Failure		404	{object} model.ApiResponse "Submission or pricing rule not found"
+// @Failure		409	{object} model.ApiResponse "Submission already has an order"
+// @Failure		500	{object} model.ApiResponse "Error creating quote"
+// @Router		/quotes [post]
+func CreateQuoteHandler(c *gin.Context) {
+	var req model.CreateQuoteDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+
+	var submission model.Submission
+	if err := database.DB.Where("id = ?", req.SubmissionID).First(&submission).Error; err != nil {
+		utils.RespondWithCustomError(
+			c,
+			http.StatusNotFound,
+			"Submission not found",
+			"Something went wrong",
+		)
+		return
+	}
+
+	breakdown, ok := calculateQuote(c, req.QuotePreviewDTO)
+	if !ok {
+		return
+	}
+
+	claims := c.MustGet("claims").(*model.EmployeeClaims)
+	quote := model.Quote{
+		SubmissionID:   submission.ID,
+		ClientName:     req.ClientName,
+		ClientPhone:    req.ClientPhone,
+		Origin:         req.Origin,
+		Destination:    req.Destination,
+		Details:        req.Details,
+		OrderType:      req.OrderType,
+		MeetingDate:    req.MeetingDate,
+		DistanceKm:     req.DistanceKm,
+		WeightKg:       quoteWeightKg(req.QuotePreviewDTO),
+		Helpers:        req.Helpers,
+		PriceBreakdown: breakdown,
+		OverrideAmount: req.OverrideAmount,
+		OverrideReason: req.OverrideReason,
+		CreatedBy:      &claims.UserID,
+	}
+
+	if !saveQuoteVersion(c, &quote) {
+		return
+	}
+
+	utils.RespondWithSuccess(c, http.StatusCreated, quote, "Quote created successfully")
+}
+
+// @Summary		Get the quotes of a submission
+// @Description	Returns every version of the submission's quote, newest first
+// @Tags		quotes
+// @Produce		json
+// @Param       id path int true "Submission ID"
+// @Success		200	{object} model.ApiResponse "List of quotes"
+// @Failure		500	{object} model.ApiResponse "Error fetching quotes"
+// @Router		/form/submissions/{id}/quotes [get]
+func GetSubmissionQuotesHandler(c *gin.Context) {
+	var quotes []model.Quote
+	if err := database.DB.Where("submission_id = ?", c.Param("id")).Order("version DESC").Find(&quotes).Error; err != nil {
+		utils.RespondWithInternalError(c, "Error fetching quotes")
+		return
+	}
+
+	utils.RespondWithSuccess(c, http.StatusOK, quotes, "Quotes fetched successfully")
+}
+
+// @Summary		Get one quote by ID
+// @Description	Returns the quote with its price breakdown
+// @Tags		quotes
+// @Produce		json
+// @Param       id path int true "Quote ID"
+// @Success		200	{object} model.ApiResponse "Quote"
+// @Failure		404	{object} model.ApiResponse "Quote not found"
+// @Router		/quotes/{id} [get]
+func GetQuoteHandler(c *gin.Context) {
+	var quote model.Quote
+	if err := database.DB.Where("id = ?", c.Param("id")).First(&quote).Error; err != nil {
+		utils.RespondWithCustomError(
+			c,
+			http.StatusNotFound,
+			"Quote not found",
+			"Something went wrong",
+		)
+		return
+	}
+
+	utils.RespondWithSuccess(c, http.StatusOK, quote, "Quote fetched successfully")
+}
+
+// @Summary		Overrides the price of a quote
+// @Description	Creates a new version of a draft quote with a manual price and the reason for the change
+// @Tags		quotes
+// @Accept		json
+// @Produce		json
+// @Param       id path int true "Quote ID"
+// @Param		override body model.OverrideQuoteDTO true "Manual price and reason"
+// @Success		201	{object} model.ApiResponse "Quote overridden successfully"
+// @Failure		400	{object} model.ApiResponse "Invalid request format"
+// @Failure		404	{object} model.ApiResponse "Quote not found"
+// @Failure		409	{object} model.ApiResponse "Quote can't be changed"
+// @Failure		500	{object} model.ApiResponse "Error overriding quote"
+// @Router		/quotes/{id}/override [patch]
+func OverrideQuoteHandler(c *gin.Context) {
+	var req model.OverrideQuoteDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+
+	var quote model.Quote
+	if !findDraftQuote(c, &quote) {
+		return
+	}
+
+	claims := c.MustGet("claims").(*model.EmployeeClaims)
+	quote.ID = 0
+	quote.OverrideAmount = &req.Amount
+	quote.OverrideReason = &req.Reason
+	quote.CreatedBy = &claims.UserID
+
+	if !saveQuoteVersion(c, &quote) {
+		return
+	}
+
+	utils.RespondWithSuccess(c, http.StatusCreated, quote, "Quote overridden successfully")
+}
+
+// @Summary		Accepts a quote
+// @Description	Marks a draft quote as accepted by the client so an order can be created from it
+// @Tags		quotes
+// @Produce		json
+// @Param       id path int true "Quote ID"
+// @Success		200	{object} model.ApiResponse "Quote accepted successfully"
+// @Failure		404	{object} model.ApiResponse "Quote not found"
+// @Failure		409	{object} model.ApiResponse "Quote can't be changed"
+// @Failure		500	{object} model.ApiResponse "Error accepting quote"
+// @Router		/quotes/{id}/accept [patch]
+func AcceptQuoteHandler(c *gin.Context) {
+	var quote model.Quote
+	if !findDraftQuote(c, &quote) {
+		return
+	}
+
+	if err := answerQuote(database.DB, &quote, model.QuoteStatusAccepted, nil); err != nil {
+		respondQuoteAnswerError(c, err, "Error accepting quote")
+		return
+	}
+
+	utils.RespondWithSuccess(c, http.StatusOK, quote, "Quote accepted successfully")
+}
+
+// Copia los datos recibidos a la regla de precios
+func applyPricingRule(rule *model.PricingRule, dto model.PricingRuleDTO) {
+	rule.OrderType = dto.OrderType
+	rule.BaseFee = dto.BaseFee
+	rule.PerKmRate = dto.PerKmRate
+	rule.PerKgRate = dto.PerKgRate
+	rule.HelperFee = dto.HelperFee
+	rule.WeekendSurchargePct = dto.WeekendSurchargePct
+	rule.AfterHoursSurchargePct = dto.AfterHoursSurchargePct
+	rule.WorkdayStartHour = dto.WorkdayStartHour
+	rule.WorkdayEndHour = dto.WorkdayEndHour
+	rule.MinimumCharge = dto.MinimumCharge
+
+	if dto.IsActive != nil {
+		rule.IsActive = *dto.IsActive
+	}
+}
+
+// Peso total del traslado; si se envían artículos se usa la suma de sus pesos
+func quoteWeightKg(dto model.QuotePreviewDTO) float64 {
+	if len(dto.Items) == 0 {
+		return dto.WeightKg
+	}
+
+	var weightKg float64
+	for _, item := range buildOrderItems(0, dto.Items) {
+		weightKg += item.TotalWeightKg()
+	}
+	return weightKg
+}
+
+// Calcula el desglose del precio con la regla activa del tipo de orden
+// Retorna false si no hay regla configurada, después de enviar la respuesta de error
+func calculateQuote(c *gin.Context, dto model.QuotePreviewDTO) (model.PriceBreakdown, bool) {
+	var rule model.PricingRule
+	err := database.DB.Where("order_type = ? AND is_active = ?", dto.OrderType, true).First(&rule).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.RespondWithCustomError(
+			c,
+			http.StatusNotFound,
+			fmt.Sprintf("There is no active pricing rule for %s orders", dto.OrderType),
+			"Pricing rule not found",
+		)
+		return model.PriceBreakdown{}, false
+	}
+
+	if err != nil {
+		utils.RespondWithInternalError(c, "Error calculating quote")
+		return model.PriceBreakdown{}, false
+	}
+
+	breakdown := rule.Calculate(model.PricingInput{
+		DistanceKm:  dto.DistanceKm,
+		WeightKg:    quoteWeightKg(dto),
+		Helpers:     dto.Helpers,
+		MeetingDate: dto.MeetingDate,
+	})
+	return breakdown, true
+}
+
+// Busca la cotización indicada en la ruta y verifica que aún sea un borrador
+// Retorna false si no existe o ya fue respondida, después de enviar la respuesta de error
+func findDraftQuote(c *gin.Context, quote *model.Quote) bool {
+	if err := database.DB.Where("id = ?", c.Param("id")).First(quote).Error; err != nil {
+		utils.RespondWithCustomError(
+			c,
+			http.StatusNotFound,
+			"Quote not found",
+			"Something went wrong",
+		)
+		return false
+	}
+
+	if quote.Status != model.QuoteStatusDraft {
+		utils.RespondWithCustomError(
+			c,
+			http.StatusConflict,
+			fmt.Sprintf("Can't change a quote with status %s", quote.Status),
+			"Quote can't be changed",
+		)
+		return false
+	}
+
+	return true
+}
+
+// Guarda la cotización como la versión más reciente de su envío y genera el enlace para el cliente
+// Las versiones abiertas anteriores quedan reemplazadas
+// Retorna false si el envío ya tiene una orden o falla el guardado, después de enviar la respuesta de error
+func saveQuoteVersion(c *gin.Context, quote *model.Quote) bool {
+	var orders int64
+	if err := database.DB.Model(&model.Order{}).Where("submission_id = ?", quote.SubmissionID).Count(&orders).Error; err != nil {
+		utils.RespondWithInternalError(c, "Error saving quote")
+		return false
+	}
+
+	if orders > 0 {
+		utils.RespondWithCustomError(
+			c,
+			http.StatusConflict,
+			"The submission already has an order, its quote can't be changed",
+			"Submission already has an order",
+		)
+		return false
+	}
+
+	token, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		utils.RespondWithInternalError(c, "Error saving quote")
+		return false
+	}
+
+	quote.Status = model.QuoteStatusDraft
+	quote.Total = quote.FinalAmount()
+
+	// El número de versión está protegido por un índice único; si otra petición lo toma primero se vuelve a calcular
+	for attempt := 0; attempt < quoteVersionAttempts; attempt++ {
+		quote.ID = 0
+		err = createQuoteVersion(quote, token)
+		if !errors.Is(err, gorm.ErrDuplicatedKey) {
+			break
+		}
+	}
+
+	if err != nil {
+		utils.RespondWithInternalError(c, "Error saving quote")
+		return false
+	}
+
+	return true
+}
+
+// Guarda la cotización con el siguiente número de versión de su envío junto con su enlace
+// Reemplaza las versiones abiertas anteriores y reabre el envío si había expirado
+func createQuoteVersion(quote *model.Quote, token string) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		var version int
+		txErr := tx.Model(&model.Quote{}).
+			Where("submission_id = ?", quote.SubmissionID).
+			Select("COALESCE(MAX(version), 0)").
+			Scan(&version).Error
+		if txErr != nil {
+			return txErr
+		}
+
+		txErr = tx.Model(&model.Quote{}).
+			Where("submission_id = ? AND status IN ?", quote.SubmissionID, []model.QuoteStatus{model.QuoteStatusDraft, model.QuoteStatusAccepted}).
+			Update("status", model.QuoteStatusSuperseded).Error
+		if txErr != nil {
+			return txErr
+		}
+
+		// Una nueva versión reabre el envío si su cotización anterior había expirado
+		txErr = tx.Model(&model.Submission{}).
+			Where("id = ? AND status = ?", quote.SubmissionID, model.FormStatusExpired).
+			Update("status", model.FormStatusPending).Error
+		if txErr != nil {
+			return txErr
+		}
+
+		quote.Version = version + 1
+		if txErr = tx.Create(quote).Error; txErr != nil {
+			return txErr
+		}
+
+		quoteToken := model.QuoteToken{
+			QuoteID: quote.ID,
+			Token:   token,
+			Expiry:  time.Now().AddDate(0, 0, quoteValidityDays),
+		}
+		return tx.Create(&quoteToken).Error
+	})
+}
+
+// Completa los datos de la orden a partir de una cotización aceptada
+// Retorna false si la cotización no existe, no fue aceptada o ya se usó, después de enviar la respuesta de error
+func applyAcceptedQuote(c *gin.Context, req *model.AcceptSubmissionDTO) bool {
+	var quote model.Quote
+	if err := database.DB.Where("id = ?", *req.QuoteID).First(&quote).Error; err != nil {
+		utils.RespondWithCustomError(
+			c,
+			http.StatusNotFound,
+			"Quote not found",
+			"Something went wrong",
+		)
+		return false
+	}
+
+	if quote.Status != model.QuoteStatusAccepted {
+		utils.RespondWithCustomError(
+			c,
+			http.StatusConflict,
+			fmt.Sprintf("Only accepted quotes can become orders, this one is %s", quote.Status),
+			"Quote not accepted",
+		)
+		return false
+	}
+
+	var orders int64
+	if err := database.DB.Model(&model.Order{}).Where("quote_id = ?", quote.ID).Count(&orders).Error; err != nil {
+		utils.RespondWithInternalError(c, "Error creating order")
+		return false
+	}
+
+	if orders > 0 {
+		utils.RespondWithCustomError(
+			c,
+			http.StatusConflict,
+			"An order was already created from this quote",
+			"Quote already used",
+		)
+		return false
+	}
+
+	fromQuote := quoteOrderRequest(&quote)
+	fromQuote.Items = req.Items
+	fromQuote.Stops = req.Stops
+	*req = fromQuote
+
+	return true
+}
+
+// Datos de la orden que se crea a partir de una cotización
+func quoteOrderRequest(quote *model.Quote) model.AcceptSubmissionDTO {
+	return model.AcceptSubmissionDTO{
+		QuoteID:      &quote.ID,
+		SubmissionID: quote.SubmissionID,
+		ClientName:   quote.ClientName,
+		ClientPhone:  quote.ClientPhone,
+		Origin:       quote.Origin,
+		Destination:  quote.Destination,
+		TotalAmount:  quote.Total,
+		Details:      quote.Details,
+		Type:         quote.OrderType,
+		MeetingDate:  quote.MeetingDate,
+	}
+}
@@ -11,48 +11,38 @@ import (
 )
 
 // @Summary		Get financial report
-// @Description	Returns a financial report of all delivered orders
+// @Description	Returns the payments collected for every order
 // @Tags		reports
 // @Produce		json
+// @Produce		text/csv
+// @Produce		application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param		format query string false "Export format" Enums(json, csv, xlsx)
 // @Success		200	{object} model.ApiResponse "Financial report"
 // @Failure		500	{object} model.ApiResponse "Error retrieving financial report"
 // @Router		/reports/financial [get]
 func FinancialReport(c *gin.Context) {
-	var orders []model.Order
-	err := database.DB.Where("status = ?", "delivered").Find(&orders).Error
+	var report []model.FinancialReportDTO
+	err := collectedPaymentsQuery().
+		Select(financialReportColumns).
+		Order("payments.paid_at").
+		Scan(&report).Error
 	if err != nil {
-		utils.RespondWithInternalError(c, "Error fetching orders")
+		utils.RespondWithInternalError(c, "Error fetching payments")
 		return
 	}
 
-	var report []model.FinancialReportDTO
-	for _, order := range orders {
-		var user model.User
-		if order.UserID != nil {
-			err := database.DB.Where("id = ?", order.UserID).First(&user).Error
-			if err != nil {
-				utils.RespondWithInternalError(c, "Error fetching user")
-				return
-			}
-		}
-
-		report = append(report, model.FinancialReportDTO{
-			Date:        order.Date,
-			Type:        order.Type,
-			TotalAmount: order.TotalAmount,
-			User:        user.Name + " " + user.LastName,
-		})
-	}
-
-	utils.RespondWithSuccess(c, http.StatusOK, report, "Financial report fetched successfully")
+	utils.RespondWithExport(c, report, report, "Financial report fetched successfully", "reporte-financiero")
 }
 
 // @Summary		Get financial report by date range
-// @Description	Returns a financial report of all delivered orders within a specific date range
+// @Description	Returns the payments collected within a specific date range
 // @Tags		reports
 // @Produce		json
+// @Produce		text/csv
+// @Produce		application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
 // @Param		startDate query string true "Start date for the report"
 // @Param		endDate query string true "End date for the report"
+// @Param		format query string false "Export format" Enums(json, csv, xlsx)
 // @Success		200	{object} model.ApiResponse "Financial report"
 // @Failure		400	{object} model.ApiResponse "Invalid date format"
 // @Failure		500	{object} model.ApiResponse "Error retrieving financial report"
@@ -72,45 +62,32 @@ func FinancialReportByDate(c *gin.Context) {
 		return
 	}
 
-	var orders []model.Order
-	err = database.DB.Where("status = ? AND date BETWEEN ? AND ?", "delivered", startDate, endDate).Find(&orders).Error
+	var report []model.FinancialReportDTO
+	err = collectedPaymentsQuery().
+		Select(financialReportColumns).
+		Where("payments.paid_at BETWEEN ? AND ?", startDate, endDate).
+		Order("payments.paid_at").
+		Scan(&report).Error
 	if err != nil {
-		utils.RespondWithInternalError(c, "Error fetching orders")
+		utils.RespondWithInternalError(c, "Error fetching payments")
 		return
 	}
 
-	if len(orders) == 0 {
-		utils.RespondWithSuccess(c, http.StatusOK, []model.FinancialReportDTO{}, "No financial data available for the given date range")
+	if len(report) == 0 {
+		utils.RespondWithExport(c, []model.FinancialReportDTO{}, report, "No financial data available for the given date range", "reporte-financiero")
 		return
 	}
 
-
-	var report []model.FinancialReportDTO
-	for _, order := range orders {
-		var user model.User
-		if order.UserID != nil {
-			err := database.DB.Where("id = ?", order.UserID).First(&user).Error
-			if err != nil {
-				utils.RespondWithInternalError(c, "Error fetching user")
-				return
-			}
-		}
-
-		report = append(report, model.FinancialReportDTO{
-			Date:        order.Date,
-			Type:        order.Type,
-			TotalAmount: order.TotalAmount,
-			User:        user.Name + " " + user.LastName,
-		})
-	}
-
-	utils.RespondWithSuccess(c, http.StatusOK, report, "Financial report fetched successfully")
+	utils.RespondWithExport(c, report, report, "Financial report fetched successfully", "reporte-financiero")
 }
 
 // @Summary		Get drivers report
 // @Description	Returns a report of drivers with delivered orders
 // @Tags		reports
 // @Produce		json
+// @Produce		text/csv
+// @Produce		application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param		format query string false "Export format" Enums(json, csv, xlsx)
 // @Success		200	{object} model.ApiResponse "Drivers report"
 // @Failure		500	{object} model.ApiResponse "Error retrieving drivers report"
 // @Router		/reports/drivers [get]
@@ -164,22 +141,25 @@ func DriversReport(c *gin.Context) {
 		})
 	}
 
-	utils.RespondWithSuccess(c, http.StatusOK, report, "Drivers report fetched successfully")
+	utils.RespondWithExport(c, report, report, "Drivers report fetched successfully", "reporte-pilotos")
 }
 
 // @Summary		Get total income report
-// @Description	Returns the total income from all delivered orders
+// @Description	Returns the total income collected from payments
 // @Tags		reports
 // @Produce		json
+// @Produce		text/csv
+// @Produce		application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param		format query string false "Export format" Enums(json, csv, xlsx)
 // @Success		200	{object} model.ApiResponse "Total income report"
 // @Failure		500	{object} model.ApiResponse "Error retrieving total income report"
 // @Router		/reports/income [get]
 func TotalIncomeReport(c *gin.Context) {
 	var totalIncome float64
 	err := database.DB.
-			Model(&model.Order{}).
-			Where("status = ?", "delivered").
-			Select("COALESCE(SUM(total_amount), 0)").
+			Model(&model.Payment{}).
+			Scopes(model.ActivePayments).
+			Select("COALESCE(SUM(amount), 0)").
 			Row().
 			Scan(&totalIncome)
 	if err != nil {
@@ -191,67 +171,91 @@ func TotalIncomeReport(c *gin.Context) {
 		TotalIncome: totalIncome,
 	}
 
-	utils.RespondWithSuccess(c, http.StatusOK, report, "Total income report fetched successfully")
+	utils.RespondWithExport(c, report, report, "Total income report fetched successfully", "ingresos-totales")
 }
 
 // @Summary		Get completed quotations chart
-// @Description	Returns data for the completed quotations chart
+// @Description	Returns the approved submissions in each interval of the range
 // @Tags		reports
 // @Produce		json
+// @Produce		text/csv
+// @Produce		application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param		from query string false "First day of the range, defaults to 12 months ago (YYYY-MM-DD)"
+// @Param		to query string false "Last day of the range, defaults to today (YYYY-MM-DD)"
+// @Param		tz query string false "Timezone used to group the data, defaults to America/Guatemala"
+// @Param		granularity query string false "Interval of each point" Enums(day, week, month, quarter)
+// @Param		format query string false "Export format" Enums(json, csv, xlsx)
 // @Success		200	{object} model.ApiResponse "Completed quotations chart data"
+// @Failure		400	{object} model.ApiResponse "Invalid chart range"
 // @Failure		500	{object} model.ApiResponse "Error retrieving completed quotations chart data"
 // @Router		/reports/completed-quotations [get]
 func CompletedQuotationsChart(c *gin.Context) {
-	var results []struct {
-		Month string
-		Count int
+	chartRange, ok := parseChartRange(c, 12)
+	if !ok {
+		return
 	}
+
+	from, to := chartRange.Bounds()
+	var totals []dailyTotal
 	err := database.DB.Model(&model.Submission{}).
-		Select("TO_CHAR(submitted_at, 'Month YYYY') as month, COUNT(*) as count").
-		Where("status = ?", model.FormStatusApproved).
-		Group("month").
-		Order("month").
-		Scan(&results).Error
+		Select("? AS day, COUNT(*) AS total", dayExpr("submitted_at", chartRange, true)).
+		Where("status = ? AND submitted_at >= ? AND submitted_at < ?", model.FormStatusApproved, from, to).
+		Group("day").
+		Scan(&totals).Error
 
 	if err != nil {
 		utils.RespondWithInternalError(c, "Error fetching completed quotations")
 		return
 	}
 
-	var categories []string
-	var data []int
-	for _, result := range results {
-		categories = append(categories, result.Month)
-		data = append(data, result.Count)
+	data, err := bucketDailyTotals[int](chartRange, totals)
+	if err != nil {
+		utils.RespondWithInternalError(c, "Error fetching completed quotations")
+		return
 	}
 
 	chartData := model.CompletedQuotationsDTO{
 		Series: []struct {
-			Data []int  `json:"data"`
+			Data []int `json:"data"`
 		}{
 			{Data: data},
 		},
-		Categories: categories,
+		Categories: chartRange.Categories(),
 	}
 
-	utils.RespondWithSuccess(c, http.StatusOK, chartData, "Completed quotations chart data fetched successfully")
+	utils.RespondWithExport(c, chartData, bucketTable(chartRange, "Cotizaciones completadas", data), "Completed quotations chart data fetched successfully", "cotizaciones-completadas")
 }
 
 // @Summary		Get quotations status chart
-// @Description	Returns data for the quotations status chart
+// @Description	Returns the submissions of each status received within the range
 // @Tags		reports
 // @Produce		json
+// @Produce		text/csv
+// @Produce		application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param		from query string false "First day of the range, defaults to the start of the month (YYYY-MM-DD)"
+// @Param		to query string false "Last day of the range, defaults to today (YYYY-MM-DD)"
+// @Param		tz query string false "Timezone of the range, defaults to America/Guatemala"
+// @Param		format query string false "Export format" Enums(json, csv, xlsx)
 // @Success		200	{object} model.ApiResponse "Quotations status chart data"
+// @Failure		400	{object} model.ApiResponse "Invalid chart range"
 // @Failure		500	{object} model.ApiResponse "Error retrieving quotations status chart data"
 // @Router		/reports/quotations-status [get]
 func QuotationsStatusChart(c *gin.Context) {
+	chartRange, ok := parseChartRange(c, 1)
+	if !ok {
+		return
+	}
+
+	from, to := chartRange.Bounds()
 	var results []struct {
-		Status string
-		Count  int
+		Status string `export:"Estado"`
+		Count  int    `export:"Cantidad"`
 	}
 	err := database.DB.Model(&model.Submission{}).
 		Select("status, COUNT(*) as count").
+		Where("submitted_at >= ? AND submitted_at < ?", from, to).
 		Group("status").
+		Order("status").
 		Scan(&results).Error
 
 	if err != nil {
@@ -259,59 +263,92 @@ func QuotationsStatusChart(c *gin.Context) {
 		return
 	}
 
-	var series []float64
-	var labels []string
+	series := []float64{}
+	labels := []string{}
 	for _, result := range results {
 		series = append(series, float64(result.Count))
 		labels = append(labels, result.Status)
 	}
 
 	chartData := model.QuotationsStatusDTO{
-		Series: series,
+		Series:     series,
 		Categories: labels,
 	}
 
-	utils.RespondWithSuccess(c, http.StatusOK, chartData, "Quotations status chart data fetched successfully")
+	utils.RespondWithExport(c, chartData, results, "Quotations status chart data fetched successfully", "estado-cotizaciones")
 }
 
 // @Summary		Get drivers performance chart
-// @Description	Returns data for the drivers performance chart
+// @Description	Returns the completed trips and fulfillment rate of each driver within the range
 // @Tags		reports
 // @Produce		json
+// @Produce		text/csv
+// @Produce		application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param		from query string false "First day of the range, defaults to the start of the month (YYYY-MM-DD)"
+// @Param		to query string false "Last day of the range, defaults to today (YYYY-MM-DD)"
+// @Param		tz query string false "Timezone of the range, defaults to America/Guatemala"
+// @Param		format query string false "Export format" Enums(json, csv, xlsx)
 // @Success		200	{object} model.ApiResponse "Drivers performance chart data"
+// @Failure		400	{object} model.ApiResponse "Invalid chart range"
 // @Failure		500	{object} model.ApiResponse "Error retrieving drivers performance chart data"
 // @Router		/reports/drivers-performance [get]
 func DriversPerformanceChart(c *gin.Context) {
-	now := time.Now()
-	startOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
-	endOfMonth := startOfMonth.AddDate(0, 1, 0).Add(-time.Nanosecond)
+	chartRange, ok := parseChartRange(c, 1)
+	if !ok {
+		return
+	}
+	from, to := chartRange.DateBounds()
+
+	roles, err := rolesWhere(model.PermissionSet.CanDrive)
+	if err != nil {
+		utils.RespondWithInternalError(c, "Error fetching drivers")
+		return
+	}
 
 	var drivers []model.User
-	err := database.DB.Where("role = ?", "driver").Find(&drivers).Error
+	err = database.DB.Where("role IN ?", roles).Order("name, last_name").Find(&drivers).Error
 	if err != nil {
 		utils.RespondWithInternalError(c, "Error fetching drivers")
 		return
 	}
 
-	var categories []string
-	var completedTripsData []int
-	var fulfillmentRateData []float64
+	categories := []string{}
+	completedTripsData := []int{}
+	fulfillmentRateData := []float64{}
+	var rows []struct {
+		Driver          string  `export:"Piloto"`
+		CompletedTrips  int     `export:"Viajes completados"`
+		FulfillmentRate float64 `export:"Tasa de cumplimiento (%)"`
+	}
 
 	for _, driver := range drivers {
 		categories = append(categories, driver.Name+" "+driver.LastName)
 
 		var deliveredCount int64
-		database.DB.Model(&model.Order{}).Where("user_id = ? AND status = ? AND date BETWEEN ? AND ?", driver.ID, "delivered", startOfMonth, endOfMonth).Count(&deliveredCount)
+		err = database.DB.Model(&model.Order{}).Where("user_id = ? AND status = ? AND date >= ? AND date < ?", driver.ID, model.OrderStatusDelivered, from, to).Count(&deliveredCount).Error
+		if err != nil {
+			utils.RespondWithInternalError(c, "Error fetching drivers performance")
+			return
+		}
 		completedTripsData = append(completedTripsData, int(deliveredCount))
 
 		var pendingCount int64
-		database.DB.Model(&model.Order{}).Where("user_id = ? AND status = ? AND date BETWEEN ? AND ?", driver.ID, "pending", startOfMonth, endOfMonth).Count(&pendingCount)
+		err = database.DB.Model(&model.Order{}).Where("user_id = ? AND status IN ? AND date >= ? AND date < ?", driver.ID, []model.OrderStatus{model.OrderStatusPending, model.OrderStatusFailed, model.OrderStatusNoShow}, from, to).Count(&pendingCount).Error
+		if err != nil {
+			utils.RespondWithInternalError(c, "Error fetching drivers performance")
+			return
+		}
 
 		var fulfillmentRate float64
 		if deliveredCount+pendingCount > 0 {
 			fulfillmentRate = (float64(deliveredCount) / float64(deliveredCount+pendingCount)) * 100
 		}
 		fulfillmentRateData = append(fulfillmentRateData, fulfillmentRate)
+		rows = append(rows, struct {
+			Driver          string  `export:"Piloto"`
+			CompletedTrips  int     `export:"Viajes completados"`
+			FulfillmentRate float64 `export:"Tasa de cumplimiento (%)"`
+		}{driver.Name + " " + driver.LastName, int(deliveredCount), fulfillmentRate})
 	}
 
 	chartData := gin.H{
@@ -322,30 +359,40 @@ func DriversPerformanceChart(c *gin.Context) {
 		"categories": categories,
 	}
 
-	utils.RespondWithSuccess(c, http.StatusOK, chartData, "Drivers performance chart data fetched successfully")
+	utils.RespondWithExport(c, chartData, rows, "Drivers performance chart data fetched successfully", "desempeno-pilotos")
 }
 
 // @Summary		Get drivers trip participation chart
-// @Description	Returns data for the drivers trip participation chart
+// @Description	Returns the delivered orders of each driver within the range
 // @Tags		reports
 // @Produce		json
+// @Produce		text/csv
+// @Produce		application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param		from query string false "First day of the range, defaults to the start of the month (YYYY-MM-DD)"
+// @Param		to query string false "Last day of the range, defaults to today (YYYY-MM-DD)"
+// @Param		tz query string false "Timezone of the range, defaults to America/Guatemala"
+// @Param		format query string false "Export format" Enums(json, csv, xlsx)
 // @Success		200	{object} model.ApiResponse "Drivers trip participation chart data"
+// @Failure		400	{object} model.ApiResponse "Invalid chart range"
 // @Failure		500	{object} model.ApiResponse "Error retrieving drivers trip participation chart data"
 // @Router		/reports/drivers-trip-participation [get]
 func DriversTripParticipationChart(c *gin.Context) {
-	now := time.Now()
-	startOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
-	endOfMonth := startOfMonth.AddDate(0, 1, 0).Add(-time.Nanosecond)
+	chartRange, ok := parseChartRange(c, 1)
+	if !ok {
+		return
+	}
+	from, to := chartRange.DateBounds()
 
 	var results []struct {
-		Driver string
-		Count  int
+		Driver string `export:"Piloto"`
+		Count  int    `export:"Viajes"`
 	}
 	err := database.DB.Model(&model.Order{}).
 		Select("users.name || ' ' || users.last_name as driver, COUNT(*) as count").
 		Joins("join users on users.id = orders.user_id").
-		Where("orders.status = ? AND orders.date BETWEEN ? AND ?", "delivered", startOfMonth, endOfMonth).
+		Where("orders.status = ? AND orders.date >= ? AND orders.date < ?", model.OrderStatusDelivered, from, to).
 		Group("driver").
+		Order("driver").
 		Scan(&results).Error
 
 	if err != nil {
@@ -353,8 +400,8 @@ func DriversTripParticipationChart(c *gin.Context) {
 		return
 	}
 
-	var series []int
-	var categories []string
+	series := []int{}
+	categories := []string{}
 	for _, result := range results {
 		series = append(series, result.Count)
 		categories = append(categories, result.Driver)
@@ -365,7 +412,7 @@ func DriversTripParticipationChart(c *gin.Context) {
 		"categories": categories,
 	}
 
-	utils.RespondWithSuccess(c, http.StatusOK, chartData, "Drivers trip participation chart data fetched successfully")
+	utils.RespondWithExport(c, chartData, results, "Drivers trip participation chart data fetched successfully", "participacion-pilotos")
 }
 
 func FinancialControlIncome(c *gin.Context) {
@@ -392,40 +439,32 @@ func FinancialControlIncome(c *gin.Context) {
 
 	// Resultado intermedio
 	var results []struct {
-		Date          time.Time `json:"date"`
-		InType        string    `json:"in_type"`
-		Amount        float64   `json:"amount"`
-		PaymentMethod string    `json:"payment_method"`
-		Assigned      string    `json:"assigned"`
-		Description   string    `json:"description"`
+		Date          time.Time `json:"date" export:"Fecha"`
+		InType        string    `json:"in_type" export:"Tipo de servicio"`
+		Amount        float64   `json:"amount" export:"Monto"`
+		PaymentMethod string    `json:"payment_method" export:"Método de pago"`
+		Assigned      string    `json:"assigned" export:"Responsable"`
+		Description   string    `json:"description" export:"Descripción"`
 	}
 
-	q := database.DB.Model(&model.Order{}).
-		Select("orders.date, orders.type as in_type, orders.total_amount as amount, orders.details as description, COALESCE(users.name || ' ' || users.last_name, 'Sin responsable') as assigned, users.id as user_id").
-		Joins("left join users on users.id = orders.user_id").
-		Where("orders.status = ?", "delivered")
+	// Cada pago recibido es un ingreso
+	q := collectedPaymentsQuery().
+		Select("payments.paid_at as date, orders.type as in_type, payments.amount, payments.method as payment_method, orders.details as description, COALESCE(users.name || ' ' || users.last_name, 'Sin responsable') as assigned")
 
 	if !startDate.IsZero() && !endDate.IsZero() {
-		q = q.Where("orders.date BETWEEN ? AND ?", startDate, endDate)
+		q = q.Where("payments.paid_at BETWEEN ? AND ?", startDate, endDate)
 	} else if !startDate.IsZero() {
-		q = q.Where("orders.date >= ?", startDate)
+		q = q.Where("payments.paid_at >= ?", startDate)
 	} else if !endDate.IsZero() {
-		q = q.Where("orders.date <= ?", endDate)
+		q = q.Where("payments.paid_at <= ?", endDate)
 	}
 
-	if err := q.Order("orders.date desc").Scan(&results).Error; err != nil {
+	if err := q.Order("payments.paid_at desc").Scan(&results).Error; err != nil {
 		utils.RespondWithInternalError(c, "Error fetching income records")
 		return
 	}
 
-	// PaymentMethod no está en la entidad Order actualmente, dejar como vacío o N/A
-	for i := range results {
-		if results[i].PaymentMethod == "" {
-			results[i].PaymentMethod = "N/A"
-		}
-	}
-
-	utils.RespondWithSuccess(c, http.StatusOK, results, "Financial income control fetched successfully")
+	utils.RespondWithExport(c, results, results, "Financial income control fetched successfully", "control-ingresos")
 }
 
 func FinancialControlSpending(c *gin.Context) {
@@ -451,12 +490,12 @@ func FinancialControlSpending(c *gin.Context) {
 	}
 
 	var results []struct {
-		Date          time.Time `json:"date"`
-		ExType        string    `json:"ex_type"`
-		Assigned      string    `json:"assigned"`
-		Description   string    `json:"description"`
-		PaymentMethod string    `json:"payment_method"`
-		Amount        float64   `json:"amount"`
+		Date          time.Time `json:"date" export:"Fecha"`
+		ExType        string    `json:"ex_type" export:"Tipo de gasto"`
+		Assigned      string    `json:"assigned" export:"Responsable"`
+		Description   string    `json:"description" export:"Descripción"`
+		PaymentMethod string    `json:"payment_method" export:"Método de pago"`
+		Amount        float64   `json:"amount" export:"Monto"`
 	}
 
 	q := database.DB.Model(&model.Expense{}).
@@ -492,12 +531,12 @@ func FinancialControlSpending(c *gin.Context) {
 			assigned = "temporal"
 		}
 		results = append(results, struct {
-			Date          time.Time `json:"date"`
-			ExType        string    `json:"ex_type"`
-			Assigned      string    `json:"assigned"`
-			Description   string    `json:"description"`
-			PaymentMethod string    `json:"payment_method"`
-			Amount        float64   `json:"amount"`
+			Date          time.Time `json:"date" export:"Fecha"`
+			ExType        string    `json:"ex_type" export:"Tipo de gasto"`
+			Assigned      string    `json:"assigned" export:"Responsable"`
+			Description   string    `json:"description" export:"Descripción"`
+			PaymentMethod string    `json:"payment_method" export:"Método de pago"`
+			Amount        float64   `json:"amount" export:"Monto"`
 		}{
 			Date:          r.Date,
 			ExType:        r.ExType,
@@ -508,73 +547,93 @@ func FinancialControlSpending(c *gin.Context) {
 		})
 	}
 
-	utils.RespondWithSuccess(c, http.StatusOK, results, "Financial spending control fetched successfully")
+	utils.RespondWithExport(c, results, results, "Financial spending control fetched successfully", "control-egresos")
 }
 
 // @Summary		Get income per month
-// @Description	Returns the total income for each month
+// @Description	Returns the income collected in each interval of the range
 // @Tags		reports
 // @Produce		json
+// @Produce		text/csv
+// @Produce		application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param		from query string false "First day of the range, defaults to 12 months ago (YYYY-MM-DD)"
+// @Param		to query string false "Last day of the range, defaults to today (YYYY-MM-DD)"
+// @Param		tz query string false "Timezone used to group the data, defaults to America/Guatemala"
+// @Param		granularity query string false "Interval of each point" Enums(day, week, month, quarter)
+// @Param		format query string false "Export format" Enums(json, csv, xlsx)
 // @Success		200	{object} model.ApiResponse "Income per month"
+// @Failure		400	{object} model.ApiResponse "Invalid chart range"
 // @Failure		500	{object} model.ApiResponse "Error retrieving income per month"
 // @Router		/reports/income-per-month [get]
 func IncomePerMonth(c *gin.Context) {
-	var results []struct {
-		Month  string
-		Amount float64
+	chartRange, ok := parseChartRange(c, 12)
+	if !ok {
+		return
 	}
-	err := database.DB.Model(&model.Order{}).
-		Select("TO_CHAR(date, 'Month YYYY') as month, SUM(total_amount) as amount").
-		Where("status = ?", "delivered").
-		Group("month").
-		Order("month").
-		Scan(&results).Error
+
+	from, to := chartRange.DateBounds()
+	var totals []dailyTotal
+	err := database.DB.Model(&model.Payment{}).
+		Scopes(model.ActivePayments).
+		Select("? AS day, SUM(amount) AS total", dayExpr("paid_at", chartRange, false)).
+		Where("paid_at >= ? AND paid_at < ?", from, to).
+		Group("day").
+		Scan(&totals).Error
 
 	if err != nil {
 		utils.RespondWithInternalError(c, "Error fetching income per month")
 		return
 	}
 
-	if len(results) == 0 {
-		utils.RespondWithSuccess(c, http.StatusOK, model.IncomePerMonthDTO{}, "No income data available")
+	data, err := bucketDailyTotals[float64](chartRange, totals)
+	if err != nil {
+		utils.RespondWithInternalError(c, "Error fetching income per month")
 		return
 	}
 
-	var categories []string
-	var data []float64
-	for _, result := range results {
-		categories = append(categories, result.Month)
-		data = append(data, result.Amount)
-	}
-
 	chartData := model.IncomePerMonthDTO{
 		Series: []struct {
 			Data []float64 `json:"data"`
 		}{
 			{Data: data},
 		},
-		Categories: categories,
+		Categories: chartRange.Categories(),
 	}
 
-	utils.RespondWithSuccess(c, http.StatusOK, chartData, "Income per month fetched successfully")
+	utils.RespondWithExport(c, chartData, bucketTable(chartRange, "Ingresos", data), "Income per month fetched successfully", "ingresos-por-periodo")
 }
 
 // @Summary		Get expenses per type
-// @Description	Returns the total expenses for each expense type
+// @Description	Returns the total expenses of each expense type within the range
 // @Tags		reports
 // @Produce		json
+// @Produce		text/csv
+// @Produce		application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param		from query string false "First day of the range, defaults to 12 months ago (YYYY-MM-DD)"
+// @Param		to query string false "Last day of the range, defaults to today (YYYY-MM-DD)"
+// @Param		tz query string false "Timezone of the range, defaults to America/Guatemala"
+// @Param		format query string false "Export format" Enums(json, csv, xlsx)
 // @Success		200	{object} model.ApiResponse "Expenses per type"
+// @Failure		400	{object} model.ApiResponse "Invalid chart range"
 // @Failure		500	{object} model.ApiResponse "Error retrieving expenses per type"
 // @Router		/reports/expenses-per-type [get]
 func ExpensesPerType(c *gin.Context) {
+	chartRange, ok := parseChartRange(c, 12)
+	if !ok {
+		return
+	}
+	from, to := chartRange.DateBounds()
+
 	var results []struct {
-		Type   string
-		Amount float64
+		Type   string  `export:"Tipo de gasto"`
+		Amount float64 `export:"Monto"`
 	}
 	err := database.DB.Model(&model.Expense{}).
 		Select("expense_types.type, SUM(expenses.amount) as amount").
 		Joins("join expense_types on expense_types.id = expenses.type_id").
+		Where("expenses.date >= ? AND expenses.date < ?", from, to).
 		Group("expense_types.type").
+		Order("amount DESC").
 		Scan(&results).Error
 
 	if err != nil {
@@ -582,13 +641,8 @@ func ExpensesPerType(c *gin.Context) {
 		return
 	}
 
-	if len(results) == 0 {
-		utils.RespondWithSuccess(c, http.StatusOK, model.ExpensesPerTypeDTO{}, "No expense data available")
-		return
-	}
-
-	var series []float64
-	var labels []string
+	series := []float64{}
+	labels := []string{}
 	for _, result := range results {
 		series = append(series, result.Amount)
 		labels = append(labels, result.Type)
@@ -599,72 +653,92 @@ func ExpensesPerType(c *gin.Context) {
 		Labels: labels,
 	}
 
-	utils.RespondWithSuccess(c, http.StatusOK, chartData, "Expenses per type fetched successfully")
+	utils.RespondWithExport(c, chartData, results, "Expenses per type fetched successfully", "egresos-por-tipo")
 }
 
 // @Summary		Get expenses per month
-// @Description	Returns the total expenses for each month
+// @Description	Returns the total expenses in each interval of the range
 // @Tags		reports
 // @Produce		json
+// @Produce		text/csv
+// @Produce		application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param		from query string false "First day of the range, defaults to 12 months ago (YYYY-MM-DD)"
+// @Param		to query string false "Last day of the range, defaults to today (YYYY-MM-DD)"
+// @Param		tz query string false "Timezone used to group the data, defaults to America/Guatemala"
+// @Param		granularity query string false "Interval of each point" Enums(day, week, month, quarter)
+// @Param		format query string false "Export format" Enums(json, csv, xlsx)
 // @Success		200	{object} model.ApiResponse "Expenses per month"
+// @Failure		400	{object} model.ApiResponse "Invalid chart range"
 // @Failure		500	{object} model.ApiResponse "Error retrieving expenses per month"
 // @Router		/reports/expenses-per-month [get]
 func ExpensesPerMonth(c *gin.Context) {
-	var results []struct {
-		Month  string
-		Amount float64
+	chartRange, ok := parseChartRange(c, 12)
+	if !ok {
+		return
 	}
+
+	// La fecha del gasto se registra sin hora, por lo que se agrupa como columna de tipo fecha
+	from, to := chartRange.DateBounds()
+	var totals []dailyTotal
 	err := database.DB.Model(&model.Expense{}).
-		Select("TO_CHAR(date, 'Month YYYY') as month, SUM(amount) as amount").
-		Group("month").
-		Order("month").
-		Scan(&results).Error
+		Select("? AS day, SUM(amount) AS total", dayExpr("date", chartRange, false)).
+		Where("date >= ? AND date < ?", from, to).
+		Group("day").
+		Scan(&totals).Error
 
 	if err != nil {
 		utils.RespondWithInternalError(c, "Error fetching expenses per month")
 		return
 	}
 
-	if len(results) == 0 {
-		utils.RespondWithSuccess(c, http.StatusOK, model.ExpensesPerMonthDTO{}, "No expense data available")
+	data, err := bucketDailyTotals[float64](chartRange, totals)
+	if err != nil {
+		utils.RespondWithInternalError(c, "Error fetching expenses per month")
 		return
 	}
 
-	var categories []string
-	var data []float64
-	for _, result := range results {
-		categories = append(categories, result.Month)
-		data = append(data, result.Amount)
-	}
-
 	chartData := model.ExpensesPerMonthDTO{
 		Series: []struct {
 			Data []float64 `json:"data"`
 		}{
 			{Data: data},
 		},
-		Categories: categories,
+		Categories: chartRange.Categories(),
 	}
 
-	utils.RespondWithSuccess(c, http.StatusOK, chartData, "Expenses per month fetched successfully")
+	utils.RespondWithExport(c, chartData, bucketTable(chartRange, "Egresos", data), "Expenses per month fetched successfully", "egresos-por-periodo")
 }
 
 // @Summary		Get order type distribution
-// @Description	Returns the distribution of order types
+// @Description	Returns the delivered orders of each type within the range
 // @Tags		reports
 // @Produce		json
+// @Produce		text/csv
+// @Produce		application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param		from query string false "First day of the range, defaults to 12 months ago (YYYY-MM-DD)"
+// @Param		to query string false "Last day of the range, defaults to today (YYYY-MM-DD)"
+// @Param		tz query string false "Timezone of the range, defaults to America/Guatemala"
+// @Param		format query string false "Export format" Enums(json, csv, xlsx)
 // @Success		200	{object} model.ApiResponse "Order type distribution"
+// @Failure		400	{object} model.ApiResponse "Invalid chart range"
 // @Failure		500	{object} model.ApiResponse "Error retrieving order type distribution"
 // @Router		/reports/order-type-distribution [get]
 func OrderTypeDistribution(c *gin.Context) {
+	chartRange, ok := parseChartRange(c, 12)
+	if !ok {
+		return
+	}
+	from, to := chartRange.DateBounds()
+
 	var results []struct {
-		OrderType string `json:"order_type"`
-		Count     int    `json:"count"`
+		OrderType string `json:"order_type" export:"Tipo de servicio"`
+		Count     int    `json:"count" export:"Órdenes entregadas"`
 	}
 	err := database.DB.Model(&model.Order{}).
 		Select("type as order_type, COUNT(*) as count").
-		Where("status = ?", "delivered").
+		Where("status = ? AND date >= ? AND date < ?", model.OrderStatusDelivered, from, to).
 		Group("type").
+		Order("count DESC").
 		Scan(&results).Error
 
 	if err != nil {
@@ -672,22 +746,17 @@ func OrderTypeDistribution(c *gin.Context) {
 		return
 	}
 
-	if len(results) == 0 {
-		utils.RespondWithSuccess(c, http.StatusOK, model.OrderTypeDistributionDTO{}, "No order type data available")
-		return
-	}
-
-	var series []int
-	var categories []string
+	series := []int{}
+	categories := []string{}
 	for _, result := range results {
 		series = append(series, result.Count)
 		categories = append(categories, result.OrderType)
 	}
 
 	chartData := model.OrderTypeDistributionDTO{
-		Series: series,
+		Series:     series,
 		Categories: categories,
 	}
 
-	utils.RespondWithSuccess(c, http.StatusOK, chartData, "Order type distribution fetched successfully")
+	utils.RespondWithExport(c, chartData, results, "Order type distribution fetched successfully", "tipos-de-orden")
 }
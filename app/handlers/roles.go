@@ -0,0 +1,310 @@
+package handlers
+
+import (
+	"dapa/app/middlewares"
+	"dapa/app/model"
+	"dapa/app/utils"
+	"dapa/database"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var errRoleExists = errors.New("role already exists")
+
+// @Summary		Get all permissions
+// @Description	Returns the catalog of permissions that can be granted to a role
+// @Tags		roles
+// @Produce		json
+// @Success		200	{object} model.ApiResponse "List of permissions"
+// @Router		/permissions [get]
+func GetPermissionsHandler(c *gin.Context) {
+	utils.RespondWithSuccess(c, http.StatusOK, model.Permissions, "Permissions fetched successfully")
+}
+
+// @Summary		Get all roles
+// @Description	Returns every role with its permissions
+// @Tags		roles
+// @Produce		json
+// @Success		200	{object} model.ApiResponse "List of roles"
+// @Failure		500	{object} model.ApiResponse "Error fetching roles"
+// @Router		/roles [get]
+func GetRolesHandler(c *gin.Context) {
+	var roles []model.Role
+	if err := database.DB.Preload("Permissions").Order("name").Find(&roles).Error; err != nil {
+		utils.RespondWithInternalError(c, "Error fetching roles")
+		return
+	}
+
+	utils.RespondWithSuccess(c, http.StatusOK, roles, "Roles fetched successfully")
+}
+
+// @Summary		Create a role
+// @Description	Creates a role with the given permissions
+// @Tags		roles
+// @Accept		json
+// @Produce		json
+// @Param		role body model.RoleDTO true "Role data"
+// @Success		201	{object} model.ApiResponse "Role created successfully"
+// @Failure		400	{object} model.ApiResponse "Invalid request format"
+// @Failure		409	{object} model.ApiResponse "Role already exists"
+// @Failure		500	{object} model.ApiResponse "Error creating role"
+// @Router		/roles [post]
+func CreateRoleHandler(c *gin.Context) {
+	var req model.RoleDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+
+	if !validatePermissions(c, req.Permissions) {
+		return
+	}
+
+	var existing int64
+	if err := database.DB.Model(&model.Role{}).Where("name = ?", req.Name).Count(&existing).Error; err != nil {
+		utils.RespondWithInternalError(c, "Error creating role")
+		return
+	}
+	if existing > 0 {
+		utils.RespondWithConflict(c, nil, fmt.Sprintf("Role %s already exists", req.Name), "Role already exists")
+		return
+	}
+
+	role := model.Role{Name: req.Name, Description: req.Description, Permissions: rolePermissions(req.Permissions)}
+	if err := database.DB.Create(&role).Error; err != nil {
+		utils.RespondWithInternalError(c, "Error creating role")
+		return
+	}
+
+	utils.RespondWithSuccess(c, http.StatusCreated, role, "Role created successfully")
+}
+
+// @Summary		Update a role
+// @Description	Replaces the name, description and permissions of a role. System roles can't be renamed and the admin role always keeps every permission
+// @Tags		roles
+// @Accept		json
+// @Produce		json
+// @Param		id path int true "Role ID"
+// @Param		role body model.RoleDTO true "Role data"
+// @Success		200	{object} model.ApiResponse "Role updated successfully"
+// @Failure		400	{object} model.ApiResponse "Invalid request format"
+// @Failure		404	{object} model.ApiResponse "Role not found"
+// @Failure		409	{object} model.ApiResponse "Role can't be changed"
+// @Failure		500	{object} model.ApiResponse "Error updating role"
+// @Router		/roles/{id} [put]
+func UpdateRoleHandler(c *gin.Context) {
+	var req model.RoleDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err, "Invalid request format")
+		return
+	}
+
+	var role model.Role
+	if err := database.DB.Where("id = ?", c.Param("id")).First(&role).Error; err != nil {
+		utils.RespondWithCustomError(c, http.StatusNotFound, "Role not found", "Something went wrong")
+		return
+	}
+
+	if !validatePermissions(c, req.Permissions) {
+		return
+	}
+
+	if role.System && req.Name != role.Name {
+		utils.RespondWithConflict(c, nil, "System roles can't be renamed", "Role can't be changed")
+		return
+	}
+
+	// Evita que los administradores pierdan el acceso al sistema
+	if role.Name == "admin" && len(req.Permissions) != len(model.Permissions) {
+		utils.RespondWithConflict(c, nil, "The admin role must keep every permission", "Role can't be changed")
+		return
+	}
+
+	previousName := role.Name
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if req.Name != previousName {
+			var existing int64
+			if err := tx.Model(&model.Role{}).Where("name = ?", req.Name).Count(&existing).Error; err != nil {
+				return err
+			}
+			if existing > 0 {
+				return errRoleExists
+			}
+
+			// Los usuarios guardan el nombre del rol, por lo que se renombra también en ellos
+			if err := tx.Model(&model.User{}).Where("role = ?", previousName).Update("role", req.Name).Error; err != nil {
+				return err
+			}
+		}
+
+		role.Name = req.Name
+		role.Description = req.Description
+		if err := tx.Omit("Permissions").Save(&role).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("role_id = ?", role.ID).Delete(&model.RolePermission{}).Error; err != nil {
+			return err
+		}
+		role.Permissions = rolePermissions(req.Permissions)
+		if len(role.Permissions) == 0 {
+			return nil
+		}
+		for i := range role.Permissions {
+			role.Permissions[i].RoleID = role.ID
+		}
+		return tx.Create(&role.Permissions).Error
+	})
+	if errors.Is(err, errRoleExists) {
+		utils.RespondWithConflict(c, nil, fmt.Sprintf("Role %s already exists", req.Name), "Role already exists")
+		return
+	}
+	if err != nil {
+		utils.RespondWithInternalError(c, "Error updating role")
+		return
+	}
+
+	utils.RespondWithSuccess(c, http.StatusOK, role, "Role updated successfully")
+}
+
+// @Summary		Delete a role
+// @Description	Deletes a role that isn't a system role and isn't assigned to any active user
+// @Tags		roles
+// @Produce		json
+// @Param		id path int true "Role ID"
+// @Success		200	{object} model.ApiResponse "Role deleted successfully"
+// @Failure		404	{object} model.ApiResponse "Role not found"
+// @Failure		409	{object} model.ApiResponse "Role can't be deleted"
+// @Failure		500	{object} model.ApiResponse "Error deleting role"
+// @Router		/roles/{id} [delete]
+func DeleteRoleHandler(c *gin.Context) {
+	var role model.Role
+	if err := database.DB.Where("id = ?", c.Param("id")).First(&role).Error; err != nil {
+		utils.RespondWithCustomError(c, http.StatusNotFound, "Role not found", "Something went wrong")
+		return
+	}
+
+	if role.System {
+		utils.RespondWithConflict(c, nil, "System roles can't be deleted", "Role can't be deleted")
+		return
+	}
+
+	var users int64
+	if err := database.DB.Model(&model.User{}).Where("role = ? AND is_active = ?", role.Name, true).Count(&users).Error; err != nil {
+		utils.RespondWithInternalError(c, "Error deleting role")
+		return
+	}
+	if users > 0 {
+		utils.RespondWithConflict(c, nil, fmt.Sprintf("Role is assigned to %d users", users), "Role can't be deleted")
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("role_id = ?", role.ID).Delete(&model.RolePermission{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&role).Error
+	})
+	if err != nil {
+		utils.RespondWithInternalError(c, "Error deleting role")
+		return
+	}
+
+	utils.RespondWithSuccess(c, http.StatusOK, nil, "Role deleted successfully")
+}
+
+// Retorna los permisos del usuario autenticado
+// Envía la respuesta de error y retorna false si no se pudieron consultar
+func userPermissions(c *gin.Context) (model.PermissionSet, bool) {
+	permissions, err := middlewares.Permissions(c)
+	if err != nil {
+		utils.RespondWithInternalError(c, "Error fetching permissions")
+		return nil, false
+	}
+	return permissions, true
+}
+
+// Retorna los permisos del rol indicado, o un conjunto vacío si el rol no existe
+func findRolePermissions(db *gorm.DB, name string) (model.PermissionSet, error) {
+	var role model.Role
+	if err := db.Preload("Permissions").Where("name = ?", name).Limit(1).Find(&role).Error; err != nil {
+		return nil, err
+	}
+	return role.PermissionSet(), nil
+}
+
+// Retorna los permisos de cada rol por nombre
+func allRolePermissions() (map[string]model.PermissionSet, error) {
+	var roles []model.Role
+	if err := database.DB.Preload("Permissions").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+
+	permissions := make(map[string]model.PermissionSet, len(roles))
+	for _, role := range roles {
+		permissions[role.Name] = role.PermissionSet()
+	}
+	return permissions, nil
+}
+
+// Retorna los nombres de los roles cuyos permisos cumplen la condición
+func rolesWhere(match func(model.PermissionSet) bool) ([]string, error) {
+	roles, err := allRolePermissions()
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for name, permissions := range roles {
+		if match(permissions) {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// Verifica que el rol exista antes de asignarlo a un usuario
+// Envía la respuesta de error y retorna false si no existe
+func validateRole(c *gin.Context, name string) bool {
+	var roles int64
+	if err := database.DB.Model(&model.Role{}).Where("name = ?", name).Count(&roles).Error; err != nil {
+		utils.RespondWithInternalError(c, "Error fetching roles")
+		return false
+	}
+
+	if roles == 0 {
+		utils.RespondWithCustomError(c, http.StatusBadRequest, fmt.Sprintf("Role %s doesn't exist", name), "Invalid request format")
+		return false
+	}
+	return true
+}
+
+// Verifica que los permisos existan en el catálogo y no se repitan
+// Envía la respuesta de error y retorna false si alguno no es válido
+func validatePermissions(c *gin.Context, permissions []model.Permission) bool {
+	seen := make(model.PermissionSet, len(permissions))
+	for _, permission := range permissions {
+		if !permission.IsValid() {
+			utils.RespondWithCustomError(c, http.StatusBadRequest, fmt.Sprintf("Permission %s doesn't exist", permission), "Invalid request format")
+			return false
+		}
+		if seen[permission] {
+			utils.RespondWithCustomError(c, http.StatusBadRequest, fmt.Sprintf("Permission %s is repeated", permission), "Invalid request format")
+			return false
+		}
+		seen[permission] = true
+	}
+	return true
+}
+
+func rolePermissions(permissions []model.Permission) []model.RolePermission {
+	result := make([]model.RolePermission, len(permissions))
+	for i, permission := range permissions {
+		result[i] = model.RolePermission{Permission: permission}
+	}
+	return result
+}
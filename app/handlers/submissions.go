@@ -5,9 +5,12 @@ import (
 	"dapa/app/utils"
 	"dapa/database"
 	"net/http"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // @Summary		Creates a form submission
@@ -55,7 +58,21 @@ func CreateSubmissionHandler(c *gin.Context) {
 
 	submission.Answers = answers
 
-	if err := database.DB.Create(&submission).Error; err != nil {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		name, phone, txErr := submissionClient(tx, answers)
+		if txErr != nil {
+			return txErr
+		}
+
+		submission.CustomerID, txErr = resolveCustomer(tx, name, phone)
+		if txErr != nil {
+			return txErr
+		}
+
+		return tx.Create(&submission).Error
+	})
+
+	if err != nil {
 		utils.RespondWithInternalError(c, "Error creating submission")
 		return
 	}
@@ -67,6 +84,9 @@ func CreateSubmissionHandler(c *gin.Context) {
 // @Description	Fetches all the created submissions
 // @Tags		form
 // @Produce		json
+// @Produce		text/csv
+// @Produce		application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param		format query string false "Export format" Enums(json, csv, xlsx)
 // @Success		200	{object} model.ApiResponse "Submissions fetched successfully"
 // @Failure		500	{object} model.ApiResponse "Error fetching submissions"
 // @Router		/form/submissions [get]
@@ -84,15 +104,16 @@ func GetSubmissionsHandler(c *gin.Context) {
 		return
 	}
 
-	utils.RespondWithSuccess(c, http.StatusOK, submissions, "Submissions fetched successfully")
+	utils.RespondWithExport(c, submissions, submissionsTable(submissions), "Submissions fetched successfully", "envios")
 }
 
 // @Summary		Gets a form submission
-// @Description	Fetches the submission with the specified ID
+// @Description	Fetches the submission with the specified ID. Staff without the submissions:manage permission can only fetch the submissions of the orders they can read
 // @Tags		form
 // @Produce		json
 // @Param		id path int true "Submission ID"
 // @Success		200	{object} model.ApiResponse "Submission fetched successfully"
+// @Failure		403	{object} model.ApiResponse "Insufficient permissions"
 // @Failure		500	{object} model.ApiResponse "Error fetching submission"
 // @Router		/form/submissions/{id} [get]
 func GetSubmissionHandler(c *gin.Context) {
@@ -110,6 +131,20 @@ func GetSubmissionHandler(c *gin.Context) {
 		return
 	}
 
+	// El personal asignado a la orden creada a partir del envío también puede verlo
+	var orders []model.Order
+	if err := database.DB.Where("submission_id = ?", submission.ID).Find(&orders).Error; err != nil {
+		utils.RespondWithInternalError(c, "Error fetching submission")
+		return
+	}
+
+	allowed := authorize(c, func(permissions model.PermissionSet, claims *model.EmployeeClaims) bool {
+		return canReadSubmission(permissions, claims, orders)
+	})
+	if !allowed {
+		return
+	}
+
 	utils.RespondWithSuccess(c, http.StatusOK, submission, "Submission fetched successfully")
 }
 
@@ -138,3 +173,80 @@ func UpdateSubmissionStatusHandler(c *gin.Context) {
 
 	utils.RespondWithSuccess(c, http.StatusOK, nil, "Submission updated successfully")
 }
+
+// Obtiene el nombre y teléfono del cliente a partir de las respuestas a las preguntas fijas del formulario
+func submissionClient(tx *gorm.DB, answers []model.Answer) (string, string, error) {
+	var questions []model.Question
+	err := tx.Where("question IN ?", []string{model.QuestionClientName, model.QuestionClientPhone}).Find(&questions).Error
+	if err != nil {
+		return "", "", err
+	}
+
+	var name, phone string
+	for _, question := range questions {
+		for _, answer := range answers {
+			if answer.QuestionID != question.ID || answer.Answer == nil {
+				continue
+			}
+
+			if question.Question == model.QuestionClientName {
+				name = *answer.Answer
+			} else {
+				phone = *answer.Answer
+			}
+		}
+	}
+
+	return name, phone, nil
+}
+
+// Construye la tabla de exportación de los envíos con una columna por cada pregunta respondida
+// Las respuestas de opción múltiple se separan con comas
+func submissionsTable(submissions []model.Submission) utils.Table {
+	var questions []model.Question
+	seen := map[uint]bool{}
+	for _, submission := range submissions {
+		for _, answer := range submission.Answers {
+			if !seen[answer.QuestionID] {
+				seen[answer.QuestionID] = true
+				questions = append(questions, answer.Question)
+			}
+		}
+	}
+
+	// Las columnas siguen el orden del formulario y no el de las respuestas
+	sort.SliceStable(questions, func(i, j int) bool {
+		return questions[i].Position < questions[j].Position
+	})
+	columns := make(map[uint]int, len(questions))
+	for i, question := range questions {
+		columns[question.ID] = i
+	}
+
+	table := utils.Table{Headers: []string{"Envío", "Fecha de envío", "Estado"}}
+	for _, question := range questions {
+		table.Headers = append(table.Headers, question.Question)
+	}
+
+	for _, submission := range submissions {
+		row := make([]any, len(table.Headers))
+		row[0], row[1], row[2] = submission.ID, submission.SubmittedAt, string(submission.Status)
+
+		for _, answer := range submission.Answers {
+			value := ""
+			if answer.Answer != nil {
+				value = *answer.Answer
+			}
+			if len(answer.Options) > 0 {
+				options := make([]string, len(answer.Options))
+				for i, option := range answer.Options {
+					options[i] = option.Option
+				}
+				value = strings.Join(options, ", ")
+			}
+			row[3+columns[answer.QuestionID]] = value
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return table
+}
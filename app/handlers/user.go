@@ -5,8 +5,10 @@ import (
 	"dapa/app/utils"
 	"dapa/database"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 
 	"time"
 )
@@ -31,18 +33,30 @@ func GetUsersHandler(c *gin.Context) {
 }
 
 // @Summary		Get user by ID
-// @Description	Returns the user information based on the given ID.
+// @Description	Returns the user information based on the given ID. Users without the users:read permission can only fetch themselves.
 // @Tags		users
 // @Produce		json
 // @Param		id path int true "User ID"
 // @Success		200	{object} model.User "User found"
+// @Failure		400	{object} model.ApiResponse "Invalid user ID"
 // @Failure		403	{object} model.ApiResponse "Insufficient permissions"
 // @Failure		500	{object} model.ApiResponse "Error fetching user"
 // @Router		/users/{id} [get]
 func GetUserHandler(c *gin.Context) {
-	var user model.User
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err, "Invalid user ID")
+		return
+	}
+
+	allowed := authorize(c, func(permissions model.PermissionSet, claims *model.EmployeeClaims) bool {
+		return canAccessUser(permissions, claims, uint(id), model.PermissionUsersRead)
+	})
+	if !allowed {
+		return
+	}
 
-	id := c.Param("id")
+	var user model.User
 	if err := database.DB.
 		Where("id = ? AND is_active = ?", id, true).
 		First(&user).Error; err != nil {
@@ -55,7 +69,7 @@ func GetUserHandler(c *gin.Context) {
 }
 
 // @Summary		Update user by ID
-// @Description	Updates a user's data. Only accessible by admin employees.
+// @Description	Updates a user's data. Users without the users:manage permission can only update themselves and can't change their email, and nobody can change their own role.
 // @Tags			users
 // @Accept			json
 // @Produce			json
@@ -74,7 +88,12 @@ func UpdateUserHandler(c *gin.Context) {
 		return
 	}
 
-	id := c.Param("id")
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err, "Invalid user ID")
+		return
+	}
+
 	var user model.User
 	if err := database.DB.Where("id = ? AND is_active = ?", id, true).First(&user).Error; err != nil {
 		utils.RespondWithCustomError(
@@ -86,20 +105,32 @@ func UpdateUserHandler(c *gin.Context) {
 		return
 	}
 
+	allowed := authorize(c, func(permissions model.PermissionSet, claims *model.EmployeeClaims) bool {
+		return canUpdateUser(permissions, claims, &user, &req)
+	})
+	if !allowed {
+		return
+	}
+
+	if !validateRole(c, req.Role) {
+		return
+	}
+
+	// Solo se actualizan los datos del perfil; la contraseña y el estado de la cuenta no cambian
 	updated := model.User{
-		ID:                    user.ID,
 		Name:                  req.Name,
 		LastName:              req.LastName,
 		Phone:                 req.Phone,
 		Email:                 req.Email,
 		Role:                  req.Role,
 		LicenseExpirationDate: req.LicenseExpirationDate,
-		IsActive:              true,
-		CreatedAt:             user.CreatedAt,
 		LastModifiedAt:        time.Now(),
 	}
 
-	if err := database.DB.Save(&updated).Error; err != nil {
+	err = database.DB.Model(&user).
+		Select("name", "last_name", "phone", "email", "role", "license_expiration_date", "last_modified_at").
+		Updates(&updated).Error
+	if err != nil {
 		utils.RespondWithInternalError(c, "Error updating user")
 		return
 	}
@@ -117,14 +148,26 @@ func UpdateUserHandler(c *gin.Context) {
 // @Failure		    500 {object} model.ApiResponse "Error deleting user"
 // @Router			/users/{id} [delete]
 func DeleteUserHandler(c *gin.Context) {
-	id := c.Param("id")
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err, "Invalid user ID")
+		return
+	}
 
-	err := database.DB.Model(&model.User{}).
-		Where("id = ?", id).
-		Updates(map[string]any{
-			"deleted_at": time.Now(),
-			"is_active":  false,
-		}).Error
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.User{}).
+			Where("id = ?", id).
+			Updates(map[string]any{
+				"deleted_at": time.Now(),
+				"is_active":  false,
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		// El usuario desactivado pierde las sesiones abiertas de inmediato
+		return revokeSessions(tx, uint(id))
+	})
 
 	if err != nil {
 		utils.RespondWithInternalError(c, "Error deleting user")
@@ -133,3 +176,39 @@ func DeleteUserHandler(c *gin.Context) {
 
 	utils.RespondWithSuccess(c, http.StatusOK, nil, "User deleted successfully")
 }
+
+// @Summary		Unlock a user
+// @Description	Clears the failed login attempts of the user so they can log in again right away
+// @Tags		users
+// @Produce		json
+// @Param		id path int true "User ID"
+// @Success		200	{object} model.ApiResponse "User unlocked successfully"
+// @Failure		400	{object} model.ApiResponse "Invalid user ID"
+// @Failure		403	{object} model.ApiResponse "Insufficient permissions"
+// @Failure		404	{object} model.ApiResponse "User not found"
+// @Failure		500	{object} model.ApiResponse "Error unlocking user"
+// @Router		/users/{id}/unlock [post]
+func UnlockUserHandler(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err, "Invalid user ID")
+		return
+	}
+
+	var count int64
+	if err = database.DB.Model(&model.User{}).Where("id = ? AND is_active = ?", id, true).Count(&count).Error; err != nil {
+		utils.RespondWithInternalError(c, "Error unlocking user")
+		return
+	}
+	if count == 0 {
+		utils.RespondWithCustomError(c, http.StatusNotFound, "User not found", "Something went wrong")
+		return
+	}
+
+	if err = clearLoginFailures(database.DB, uint(id)); err != nil {
+		utils.RespondWithInternalError(c, "Error unlocking user")
+		return
+	}
+
+	utils.RespondWithSuccess(c, http.StatusOK, nil, "User unlocked successfully")
+}
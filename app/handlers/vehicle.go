@@ -5,6 +5,7 @@ import (
 	"dapa/app/utils"
 	"dapa/database"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -154,13 +155,19 @@ func CreateVehicleHandler(c *gin.Context) {
 // @Produce		json
 // @Param		id path int true "Vehicle ID"
 // @Success		200	{object} model.ApiResponse "Successfully marked vehicle as inactive"
+// @Failure		400	{object} model.ApiResponse "Invalid vehicle ID"
 // @Failure		403	{object} model.ApiResponse "Insufficient permissions"
 // @Failure		500	{object} model.ApiResponse "Error updating vehicle status"
 // @Router		/vehicles/{id} [delete]
 func DeleteVehicleHandler(c *gin.Context) {
-	id := c.Param("id")
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err, "Invalid vehicle ID")
+		return
+	}
 
-	err := database.DB.Model(&model.Vehicle{}).
+	// Los documentos del vehículo se conservan por si se restaura
+	err = database.DB.Model(&model.Vehicle{}).
 		Where("id = ?", id).
 		Updates(map[string]any{
 			"deleted_at": time.Now(),
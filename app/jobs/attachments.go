@@ -0,0 +1,70 @@
+package jobs
+
+import (
+	"dapa/app/model"
+	"dapa/database"
+	"dapa/storage"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Tiempo que se conservan los adjuntos de una entidad desactivada, por si se restaura
+const attachmentRetention = 365 * 24 * time.Hour
+
+// Condición que cumple una entidad vigente, según si se elimina del todo o solo se desactiva
+// Las entidades desactivadas siguen vigentes hasta que vence el periodo de retención
+var attachmentOwnerConditions = map[model.AttachmentOwner]string{
+	model.AttachmentOwnerOrder:           "",
+	model.AttachmentOwnerExpense:         "",
+	model.AttachmentOwnerProofOfDelivery: "",
+	model.AttachmentOwnerVehicle:         " AND (owner.deleted_at IS NULL OR owner.deleted_at >= ?)",
+	model.AttachmentOwnerUser:            " AND (owner.deleted_at IS NULL OR owner.deleted_at >= ?)",
+}
+
+// Elimina los archivos adjuntos cuya entidad ya no existe, junto con su contenido en el almacenamiento
+// Cubre las entidades eliminadas fuera de la API, las constancias de entrega de órdenes eliminadas
+// y los usuarios y vehículos desactivados hace más que el periodo de retención
+func SweepOrphanAttachments(now time.Time) error {
+	err := database.DB.
+		Where("NOT EXISTS (SELECT 1 FROM orders WHERE orders.id = proof_of_deliveries.order_id)").
+		Delete(&model.ProofOfDelivery{}).Error
+	if err != nil {
+		return err
+	}
+
+	var swept int
+	for owner, condition := range attachmentOwnerConditions {
+		query := database.DB.Where("owner_type = ?", owner)
+		exists := fmt.Sprintf("NOT EXISTS (SELECT 1 FROM %s owner WHERE owner.id = attachments.owner_id%s)", owner, condition)
+		if condition == "" {
+			query = query.Where(exists)
+		} else {
+			query = query.Where(exists, now.Add(-attachmentRetention))
+		}
+
+		var orphans []model.Attachment
+		err = query.Find(&orphans).Error
+		if err != nil {
+			return err
+		}
+
+		for _, attachment := range orphans {
+			// El registro se conserva si el archivo no se pudo borrar, para reintentarlo en la siguiente ejecución
+			if err = storage.Files.Delete(attachment.FileKey); err != nil {
+				log.Printf("Error deleting file %s: %v", attachment.FileKey, err)
+				continue
+			}
+
+			if err = database.DB.Delete(&attachment).Error; err != nil {
+				return err
+			}
+			swept++
+		}
+	}
+
+	if swept > 0 {
+		log.Printf("%d orphan attachments deleted", swept)
+	}
+	return nil
+}
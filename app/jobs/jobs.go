@@ -0,0 +1,27 @@
+package jobs
+
+import (
+	"log"
+	"time"
+)
+
+// Inicia las tareas periódicas del sistema en segundo plano
+func Start() {
+	go runEvery("expire quotes", time.Hour, ExpireQuotes)
+	go runEvery("snapshot kpis", time.Hour, SnapshotKPIs)
+	go runEvery("prune login attempts", 24*time.Hour, PruneLoginAttempts)
+	go runEvery("sweep orphan attachments", 24*time.Hour, SweepOrphanAttachments)
+}
+
+// Ejecuta una tarea al iniciar y luego cada intervalo indicado
+func runEvery(name string, interval time.Duration, job func(now time.Time) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := job(time.Now()); err != nil {
+			log.Printf("Error running job '%s': %v", name, err)
+		}
+		<-ticker.C
+	}
+}
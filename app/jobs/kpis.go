@@ -0,0 +1,63 @@
+package jobs
+
+import (
+	"dapa/app/model"
+	"dapa/app/services"
+	"dapa/database"
+	"log"
+	"time"
+)
+
+// Guarda los indicadores de cada mes cerrado que todavía no tiene su cierre guardado
+// Inicia en el mes de la primera orden, así se recuperan los meses en los que la tarea no corrió
+// Si ya se guardaron no se recalculan, así las correcciones posteriores no cambian el histórico
+func SnapshotKPIs(now time.Time) error {
+	location, err := time.LoadLocation(model.DefaultChartTimezone)
+	if err != nil {
+		return err
+	}
+	now = now.In(location)
+
+	// Los indicadores se calculan sobre columnas de tipo fecha, que se leen en UTC
+	current, err := model.NewReportPeriod(model.PeriodMonth, time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		return err
+	}
+
+	var first model.Order
+	err = database.DB.Select("date").Where("date IS NOT NULL AND date < ?", current.Start).Order("date").Limit(1).Find(&first).Error
+	if err != nil {
+		return err
+	}
+
+	start := current.Previous().Start
+	if !first.Date.IsZero() {
+		start = time.Date(first.Date.Year(), first.Date.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	var snapshots []model.KPISnapshot
+	if err = database.DB.Select("period_start").Where("period_start >= ?", start).Find(&snapshots).Error; err != nil {
+		return err
+	}
+	saved := make(map[string]bool, len(snapshots))
+	for _, snapshot := range snapshots {
+		saved[snapshot.PeriodStart.Format("2006-01")] = true
+	}
+
+	for month := start; month.Before(current.Start); month = month.AddDate(0, 1, 0) {
+		if saved[month.Format("2006-01")] {
+			continue
+		}
+
+		kpis, err := services.ComputeKPIs(month, month.AddDate(0, 1, 0), nil)
+		if err != nil {
+			return err
+		}
+
+		if err = database.DB.Create(&model.KPISnapshot{PeriodStart: month, KPIs: kpis}).Error; err != nil {
+			return err
+		}
+		log.Printf("KPIs of %s saved", month.Format("2006-01"))
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package jobs
+
+import (
+	"dapa/app/model"
+	"dapa/database"
+	"log"
+	"time"
+)
+
+// Tiempo que se conservan los intentos de inicio de sesión en la bitácora
+const loginAttemptRetention = 90 * 24 * time.Hour
+
+// Elimina de la bitácora los intentos de inicio de sesión más antiguos que el periodo de retención
+func PruneLoginAttempts(now time.Time) error {
+	result := database.DB.Where("created_at < ?", now.Add(-loginAttemptRetention)).Delete(&model.LoginAttempt{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected > 0 {
+		log.Printf("%d login attempts pruned", result.RowsAffected)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package jobs
+
+import (
+	"dapa/app/model"
+	"dapa/database"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Marca como expiradas las cotizaciones sin responder cuyo enlace ya venció
+// Los envíos pendientes que se quedan sin cotizaciones abiertas pasan a expirados
+func ExpireQuotes(now time.Time) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		expiredTokens := tx.Model(&model.QuoteToken{}).Select("quote_id").Where("expiry < ?", now)
+
+		result := tx.Model(&model.Quote{}).
+			Where("status = ? AND id IN (?)", model.QuoteStatusDraft, expiredTokens).
+			Update("status", model.QuoteStatusExpired)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		log.Printf("%d quotes expired", result.RowsAffected)
+
+		expiredQuotes := tx.Model(&model.Quote{}).Select("submission_id").Where("status = ?", model.QuoteStatusExpired)
+		openQuotes := tx.Model(&model.Quote{}).Select("submission_id").
+			Where("status IN ?", []model.QuoteStatus{model.QuoteStatusDraft, model.QuoteStatusAccepted})
+
+		return tx.Model(&model.Submission{}).
+			Where("status = ? AND id IN (?) AND id NOT IN (?)", model.FormStatusPending, expiredQuotes, openQuotes).
+			Update("status", model.FormStatusExpired).Error
+	})
+}
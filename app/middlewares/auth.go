@@ -2,9 +2,11 @@ package middlewares
 
 import (
 	"net/http"
+	"time"
 
 	"dapa/app/model"
 	"dapa/app/utils"
+	"dapa/database"
 
 	"github.com/gin-gonic/gin"
 )
@@ -15,55 +17,37 @@ func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		tokenString := extractToken(c.Request)
 		if tokenString == "" {
-			utils.RespondWithUnathorizedError(c)
+			utils.RespondWithCustomError(c, http.StatusUnauthorized, "Missing access token", "Invalid session")
 			c.Abort()
 			return
 		}
 
 		claims, err := utils.ValidateToken(tokenString)
 		if err != nil {
-			utils.RespondWithUnathorizedError(c)
+			utils.RespondWithCustomError(c, http.StatusUnauthorized, "Access token is invalid or expired", "Invalid session")
 			c.Abort()
 			return
 		}
 
-		c.Set("claims", claims)
-		c.Next()
-	}
-}
-
-// Middleware para verificar si el usuario posee los roles requeridos
-// Recibe los roles requeridos como parámetro
-func RoleRequired(roles ...string) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		claimsInterface, exists := c.Get("claims")
-		if !exists {
-			utils.RespondWithUnathorizedError(c)
-			c.Abort()
-			return
-		}
-
-		claims, ok := claimsInterface.(*model.EmployeeClaims)
-		if !ok {
-			utils.RespondWithCustomError(c, http.StatusBadRequest, "Invalid claims format", "Invalid request format")
+		// El token solo es válido mientras su sesión siga abierta y el usuario activo
+		var sessions int64
+		err = database.DB.Model(&model.Session{}).
+			Joins("JOIN users ON users.id = sessions.user_id").
+			Where("sessions.id = ? AND sessions.user_id = ?", claims.SessionID, claims.UserID).
+			Where("sessions.revoked_at IS NULL AND sessions.expires_at > ? AND users.is_active = ?", time.Now(), true).
+			Count(&sessions).Error
+		if err != nil {
+			utils.RespondWithInternalError(c, "Error validating session")
 			c.Abort()
 			return
 		}
-
-		hasRole := false
-		for _, role := range roles {
-			if claims.Role == role {
-				hasRole = true
-				break
-			}
-		}
-
-		if !hasRole {
-			utils.RespondWithUnathorizedError(c)
+		if sessions == 0 {
+			utils.RespondWithCustomError(c, http.StatusUnauthorized, "Session has expired or was revoked", "Invalid session")
 			c.Abort()
 			return
 		}
 
+		c.Set("claims", claims)
 		c.Next()
 	}
 }
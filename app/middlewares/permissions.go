@@ -0,0 +1,56 @@
+package middlewares
+
+import (
+	"dapa/app/model"
+	"dapa/app/utils"
+	"dapa/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Middleware para verificar si el rol del usuario posee alguno de los permisos requeridos
+// Recibe los permisos como parámetro
+func RequirePermission(permissions ...model.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		granted, err := Permissions(c)
+		if err != nil {
+			utils.RespondWithInternalError(c, "Error fetching permissions")
+			c.Abort()
+			return
+		}
+
+		if !granted.HasAny(permissions...) {
+			utils.RespondWithUnathorizedError(c)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// Retorna los permisos del rol del usuario autenticado
+// Se consultan una sola vez por petición, así los cambios a un rol aplican de inmediato
+func Permissions(c *gin.Context) (model.PermissionSet, error) {
+	if cached, ok := c.Get("permissions"); ok {
+		return cached.(model.PermissionSet), nil
+	}
+
+	claimsInterface, exists := c.Get("claims")
+	if !exists {
+		return model.PermissionSet{}, nil
+	}
+	claims, ok := claimsInterface.(*model.EmployeeClaims)
+	if !ok {
+		return model.PermissionSet{}, nil
+	}
+
+	var role model.Role
+	if err := database.DB.Preload("Permissions").Where("name = ?", claims.Role).Limit(1).Find(&role).Error; err != nil {
+		return nil, err
+	}
+
+	permissions := role.PermissionSet()
+	c.Set("permissions", permissions)
+	return permissions, nil
+}
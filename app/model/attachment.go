@@ -0,0 +1,18 @@
+package model
+
+// Entidades a las que se les pueden adjuntar archivos
+type AttachmentOwner string
+
+const (
+	AttachmentOwnerOrder           AttachmentOwner = "orders"
+	AttachmentOwnerExpense         AttachmentOwner = "expenses"
+	AttachmentOwnerVehicle         AttachmentOwner = "vehicles"
+	AttachmentOwnerUser            AttachmentOwner = "users"
+	AttachmentOwnerProofOfDelivery AttachmentOwner = "proof_of_deliveries"
+)
+
+// Categorías de los archivos de una constancia de entrega
+const (
+	AttachmentCategorySignature = "signature"
+	AttachmentCategoryPhoto     = "photo"
+)
@@ -0,0 +1,139 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+type Granularity string
+
+const (
+	GranularityDay     Granularity = "day"
+	GranularityWeek    Granularity = "week"
+	GranularityMonth   Granularity = "month"
+	GranularityQuarter Granularity = "quarter"
+)
+
+// Zona horaria con la que se agrupan las gráficas si no se indica otra, también define el cierre de cada mes
+const DefaultChartTimezone = "America/Guatemala"
+
+// Cantidad máxima de intervalos de una gráfica
+const MaxChartBuckets = 1000
+
+// Rango de fechas de una gráfica dividido en intervalos de la misma granularidad
+// El inicio se incluye y el fin no; ambos están en la zona horaria solicitada
+type ChartRange struct {
+	From        time.Time
+	To          time.Time
+	Granularity Granularity
+	buckets     []time.Time
+}
+
+// Crea el rango entre los días indicados, incluyendo completo el último día
+// Las fechas deben estar en la zona horaria con la que se agrupan los datos
+func NewChartRange(from, to time.Time, granularity Granularity) (ChartRange, error) {
+	switch granularity {
+	case GranularityDay, GranularityWeek, GranularityMonth, GranularityQuarter:
+	default:
+		return ChartRange{}, fmt.Errorf("granularity must be day, week, month or quarter")
+	}
+
+	from = startOfDay(from)
+	to = startOfDay(to).AddDate(0, 0, 1)
+	if !from.Before(to) {
+		return ChartRange{}, fmt.Errorf("from can't be after to")
+	}
+
+	r := ChartRange{From: from, To: to, Granularity: granularity}
+	for start := r.bucketStart(from); start.Before(to); start = r.nextBucket(start) {
+		if len(r.buckets) == MaxChartBuckets {
+			return ChartRange{}, fmt.Errorf("the range can't have more than %d %ss", MaxChartBuckets, granularity)
+		}
+		r.buckets = append(r.buckets, start)
+	}
+	return r, nil
+}
+
+// Retorna el inicio de cada intervalo en orden cronológico
+func (r ChartRange) Buckets() []time.Time {
+	return r.buckets
+}
+
+// Retorna la etiqueta de cada intervalo en orden cronológico
+func (r ChartRange) Categories() []string {
+	categories := make([]string, len(r.buckets))
+	for i, start := range r.buckets {
+		categories[i] = r.Label(start)
+	}
+	return categories
+}
+
+// Retorna la etiqueta del intervalo que inicia en la fecha indicada
+// Los meses conservan el formato que usaban las gráficas antes de poder elegir la granularidad
+func (r ChartRange) Label(start time.Time) string {
+	switch r.Granularity {
+	case GranularityMonth:
+		return start.Format("January 2006")
+	case GranularityQuarter:
+		return fmt.Sprintf("%d-Q%d", start.Year(), (int(start.Month())-1)/3+1)
+	}
+	return start.Format("2006-01-02")
+}
+
+// Retorna la posición del intervalo al que pertenece un instante, o -1 si está fuera del rango
+func (r ChartRange) Index(t time.Time) int {
+	t = t.In(r.From.Location())
+	if t.Before(r.From) || !t.Before(r.To) {
+		return -1
+	}
+	return sort.Search(len(r.buckets), func(i int) bool { return r.buckets[i].After(t) }) - 1
+}
+
+// Retorna la posición del intervalo al que pertenece el día de una columna de tipo fecha, o -1 si está fuera del rango
+// Las columnas de tipo fecha no tienen zona horaria, por lo que se toma el día tal como está guardado
+func (r ChartRange) DateIndex(date time.Time) int {
+	return r.Index(time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, r.From.Location()))
+}
+
+// Retorna los límites del rango en UTC para filtrar columnas de fecha y hora
+func (r ChartRange) Bounds() (time.Time, time.Time) {
+	return r.From.UTC(), r.To.UTC()
+}
+
+// Retorna los límites del rango para filtrar columnas de tipo fecha, que se leen en UTC
+func (r ChartRange) DateBounds() (time.Time, time.Time) {
+	return time.Date(r.From.Year(), r.From.Month(), r.From.Day(), 0, 0, 0, 0, time.UTC),
+		time.Date(r.To.Year(), r.To.Month(), r.To.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Las semanas inician el lunes
+func (r ChartRange) bucketStart(t time.Time) time.Time {
+	year, month, day := t.Date()
+	switch r.Granularity {
+	case GranularityWeek:
+		return time.Date(year, month, day-(int(t.Weekday())+6)%7, 0, 0, 0, 0, t.Location())
+	case GranularityMonth:
+		return time.Date(year, month, 1, 0, 0, 0, 0, t.Location())
+	case GranularityQuarter:
+		return time.Date(year, month-(month-1)%3, 1, 0, 0, 0, 0, t.Location())
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+func (r ChartRange) nextBucket(start time.Time) time.Time {
+	switch r.Granularity {
+	case GranularityWeek:
+		return start.AddDate(0, 0, 7)
+	case GranularityMonth:
+		return start.AddDate(0, 1, 0)
+	case GranularityQuarter:
+		return start.AddDate(0, 3, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
@@ -7,7 +7,7 @@ type UserDTO struct {
 	LastName              string    `json:"lastName" binding:"required"`
 	Phone                 string    `json:"phone" binding:"required,phone"`
 	Email                 string    `json:"email" binding:"required,email"`
-	Role                  string    `json:"role" binding:"required,oneof=admin driver helper"`
+	Role                  string    `json:"role" binding:"required,max=50"`
 	LicenseExpirationDate time.Time `json:"licenseExpirationDate"`
 }
 
@@ -26,12 +26,37 @@ type OrderDTO struct {
 	HelperID	*uint   `json:"helperId"`
 	ClientName  string  `json:"clientName" binding:"required"`
 	ClientPhone string  `json:"clientPhone" binding:"required"`
-	Origin      string  `json:"origin" binding:"required"`
-	Destination string  `json:"destination" binding:"required"`
+	Origin      string  `json:"origin" binding:"required_without=Stops"`
+	Destination string  `json:"destination" binding:"required_without=Stops"`
 	TotalAmount float64 `json:"totalAmount" binding:"required"`
 	Details     *string `json:"details"`
 	Type        string  `json:"type" binding:"required"`
 	MeetingDate time.Time `json:"meetingDate" binding:"required"`
+	Items       []OrderItemDTO `json:"items" binding:"omitempty,dive"`
+	Stops       []OrderStopDTO `json:"stops" binding:"omitempty,min=2,dive"`
+}
+
+// El peso y volumen se indican por unidad
+type OrderItemDTO struct {
+	Description string  `json:"description" binding:"required,max=255"`
+	Quantity    int     `json:"quantity" binding:"required,gt=0"`
+	WeightKg    float64 `json:"weightKg" binding:"gte=0"`
+	VolumeM3    float64 `json:"volumeM3" binding:"gte=0"`
+	IsFragile   bool    `json:"isFragile"`
+}
+
+// Las paradas se recorren en el orden en que se envían
+type OrderStopDTO struct {
+	Type         StopType   `json:"type" binding:"required,oneof=pickup dropoff"`
+	Address      string     `json:"address" binding:"required,max=255"`
+	ContactName  *string    `json:"contactName" binding:"omitempty,max=100"`
+	ContactPhone *string    `json:"contactPhone" binding:"omitempty,phone"`
+	WindowStart  *time.Time `json:"windowStart"`
+	WindowEnd    *time.Time `json:"windowEnd"`
+}
+
+type StopStatusDTO struct {
+	Status StopStatus `json:"status" binding:"required,oneof=arrived completed skipped"`
 }
 
 type LoginDTO struct {
@@ -46,7 +71,14 @@ type RegisterDTO struct {
 	Email                 string    `json:"email" binding:"required,email"`
 	LicenseExpirationDate time.Time `json:"licenseExpirationDate" binding:"required_if=Role driver"`
 	Password              string    `json:"password" binding:"required,password"`
-	Role                  string    `json:"role" binding:"required,oneof=admin driver helper"`
+	Role                  string    `json:"role" binding:"required,max=50"`
+}
+
+// El nombre es el que se asigna a los usuarios; los permisos deben existir en el catálogo
+type RoleDTO struct {
+	Name        string       `json:"name" binding:"required,max=50"`
+	Description string       `json:"description" binding:"max=255"`
+	Permissions []Permission `json:"permissions" binding:"required"`
 }
 
 type ForgotPasswordDTO struct {
@@ -58,16 +90,95 @@ type ResetPasswordDTO struct {
 	NewPassword string `json:"newPassword" binding:"required,password"`
 }
 
+type ChangePasswordDTO struct {
+	CurrentPassword string `json:"currentPassword" binding:"required"`
+	NewPassword     string `json:"newPassword" binding:"required,password"`
+}
+
+// Datos que cada usuario puede actualizar de su propia cuenta, solo se modifican los que se envían
+// El correo y el rol solo los puede cambiar un administrador
+type UpdateAccountDTO struct {
+	Name                  *string    `json:"name" binding:"omitempty,min=1,max=50"`
+	LastName              *string    `json:"lastName" binding:"omitempty,min=1,max=50"`
+	Phone                 *string    `json:"phone" binding:"omitempty,phone"`
+	LicenseExpirationDate *time.Time `json:"licenseExpirationDate"`
+}
+
+// Cuenta del usuario autenticado junto con los permisos de su rol
+type AccountDTO struct {
+	User
+	Permissions []Permission `json:"permissions"`
+}
+
+// Si se indica una cotización aceptada, los datos de la orden se toman de ella
 type AcceptSubmissionDTO struct {
-	SubmissionID uint    `json:"submissionId" binding:"required"`
-	ClientName   string  `json:"clientName" binding:"required"`
-	ClientPhone  string  `json:"clientPhone" binding:"required"`
-	Origin       string  `json:"origin" binding:"required"`
-	Destination  string  `json:"destination" binding:"required"`
-	TotalAmount  float64 `json:"totalAmount" binding:"required"`
-	Details      string  `json:"details" binding:"required"`
-	Type         string  `json:"type" binding:"required"`
-	MeetingDate  time.Time `json:"meetingDate" binding:"required"`
+	QuoteID      *uint          `json:"quoteId"`
+	SubmissionID uint           `json:"submissionId" binding:"required_without=QuoteID"`
+	ClientName   string         `json:"clientName" binding:"required_without=QuoteID"`
+	ClientPhone  string         `json:"clientPhone" binding:"required_without=QuoteID"`
+	Origin       string         `json:"origin" binding:"required_without_all=Stops QuoteID"`
+	Destination  string         `json:"destination" binding:"required_without_all=Stops QuoteID"`
+	TotalAmount  float64        `json:"totalAmount" binding:"required_without=QuoteID"`
+	Details      string         `json:"details" binding:"required_without=QuoteID"`
+	Type         string         `json:"type" binding:"required_without=QuoteID"`
+	MeetingDate  time.Time      `json:"meetingDate" binding:"required_without=QuoteID"`
+	Items        []OrderItemDTO `json:"items" binding:"omitempty,dive"`
+	Stops        []OrderStopDTO `json:"stops" binding:"omitempty,min=2,dive"`
+}
+
+type CustomerAddressDTO struct {
+	Label   *string `json:"label" binding:"omitempty,max=50"`
+	Address string  `json:"address" binding:"required,max=255"`
+}
+
+// Las direcciones enviadas reemplazan a las registradas
+type CustomerDTO struct {
+	Name      string               `json:"name" binding:"required,max=100"`
+	Phone     string               `json:"phone" binding:"required,max=20"`
+	Email     *string              `json:"email" binding:"omitempty,email,max=50"`
+	Notes     *string              `json:"notes" binding:"omitempty,max=255"`
+	Addresses []CustomerAddressDTO `json:"addresses" binding:"omitempty,dive"`
+}
+
+type PricingRuleDTO struct {
+	OrderType              string  `json:"orderType" binding:"required,max=50"`
+	BaseFee                float64 `json:"baseFee" binding:"gte=0"`
+	PerKmRate              float64 `json:"perKmRate" binding:"gte=0"`
+	PerKgRate              float64 `json:"perKgRate" binding:"gte=0"`
+	HelperFee              float64 `json:"helperFee" binding:"gte=0"`
+	WeekendSurchargePct    float64 `json:"weekendSurchargePct" binding:"gte=0"`
+	AfterHoursSurchargePct float64 `json:"afterHoursSurchargePct" binding:"gte=0"`
+	WorkdayStartHour       int     `json:"workdayStartHour" binding:"gte=0,lte=23"`
+	WorkdayEndHour         int     `json:"workdayEndHour" binding:"required,gtfield=WorkdayStartHour,lte=24"`
+	MinimumCharge          float64 `json:"minimumCharge" binding:"gte=0"`
+	IsActive               *bool   `json:"isActive"`
+}
+
+// El peso se toma de los artículos si se envían
+type QuotePreviewDTO struct {
+	OrderType   string         `json:"orderType" binding:"required"`
+	DistanceKm  float64        `json:"distanceKm" binding:"gte=0"`
+	WeightKg    float64        `json:"weightKg" binding:"gte=0"`
+	Items       []OrderItemDTO `json:"items" binding:"omitempty,dive"`
+	Helpers     int            `json:"helpers" binding:"gte=0"`
+	MeetingDate time.Time      `json:"meetingDate" binding:"required"`
+}
+
+type CreateQuoteDTO struct {
+	QuotePreviewDTO
+	SubmissionID   uint     `json:"submissionId" binding:"required"`
+	ClientName     string   `json:"clientName" binding:"required,max=50"`
+	ClientPhone    string   `json:"clientPhone" binding:"required,max=20"`
+	Origin         string   `json:"origin" binding:"required,max=100"`
+	Destination    string   `json:"destination" binding:"required,max=100"`
+	Details        string   `json:"details" binding:"max=255"`
+	OverrideAmount *float64 `json:"overrideAmount" binding:"omitempty,gt=0"`
+	OverrideReason *string  `json:"overrideReason" binding:"required_with=OverrideAmount,omitempty,max=255"`
+}
+
+type OverrideQuoteDTO struct {
+	Amount float64 `json:"amount" binding:"required,gt=0"`
+	Reason string  `json:"reason" binding:"required,max=255"`
 }
 
 type AssignOrderDTO struct {
@@ -76,8 +187,68 @@ type AssignOrderDTO struct {
 	HelperID  uint `json:"helperId" binding:"required"`
 }
 
+type AssignmentConflictDTO struct {
+	Resource    string    `json:"resource"`
+	ResourceID  uint      `json:"resourceId"`
+	OrderID     uint      `json:"orderId"`
+	MeetingDate time.Time `json:"meetingDate"`
+}
+
+// Workload es la cantidad de órdenes que ya tiene en la semana del servicio
+type SuggestedResourceDTO struct {
+	ID       uint     `json:"id"`
+	Name     string   `json:"name"`
+	Score    float64  `json:"score"`
+	Workload int      `json:"workload"`
+	Notes    []string `json:"notes,omitempty"`
+}
+
+type AssignmentSuggestionDTO struct {
+	Score   float64              `json:"score"`
+	Driver  SuggestedResourceDTO `json:"driver"`
+	Helper  SuggestedResourceDTO `json:"helper"`
+	Vehicle SuggestedResourceDTO `json:"vehicle"`
+}
+
+type AutoAssignDTO struct {
+	Date time.Time `json:"date" binding:"required"`
+}
+
+type AutoAssignResultDTO struct {
+	OrderID    uint                     `json:"orderId"`
+	Assignment *AssignmentSuggestionDTO `json:"assignment,omitempty"`
+	Reason     string                   `json:"reason,omitempty"`
+}
+
 type OrderStatusDTO struct {
-	Status string `json:"status" binding:"oneof=pending assigned pickup collected delivered"`
+	Status OrderStatus `json:"status" binding:"required,oneof=pending assigned pickup collected delivered"`
+}
+
+// Si no se indica la fecha de pago se usa la fecha actual
+type PaymentDTO struct {
+	Amount    float64       `json:"amount" binding:"required,gt=0"`
+	Method    PaymentMethod `json:"method" binding:"required,oneof=cash transfer card"`
+	PaidAt    *time.Time    `json:"paidAt"`
+	Reference *string       `json:"reference" binding:"omitempty,max=100"`
+	IsDeposit bool          `json:"isDeposit"`
+}
+
+type VoidPaymentDTO struct {
+	Reason string `json:"reason" binding:"required,max=255"`
+}
+
+type CancelOrderDTO struct {
+	Reason string `json:"reason" binding:"required,max=255"`
+}
+
+type OrderFailureDTO struct {
+	Status OrderStatus `json:"status" binding:"required,oneof=failed no_show"`
+	Reason string      `json:"reason" binding:"required,max=255"`
+}
+
+type RescheduleOrderDTO struct {
+	MeetingDate time.Time `json:"meetingDate" binding:"required"`
+	Reason      *string   `json:"reason" binding:"omitempty,max=255"`
 }
 
 type QuestionTypeDTO struct {
@@ -115,35 +286,167 @@ type CreateSubmissionDTO struct {
 }
 
 type UpdateSubmissionStatusDTO struct {
-	Status FormStatus `json:"status" binding:"required,oneof=pending cancelled approved"`
+	Status FormStatus `json:"status" binding:"required,oneof=pending cancelled approved expired"`
 }
 
 type OrderTokenDTO struct {
 	Token string `json:"token" binding:"required"`
 }
 
+type QuoteTokenDTO struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type DeclineQuoteDTO struct {
+	Token  string  `json:"token" binding:"required"`
+	Reason *string `json:"reason" binding:"omitempty,max=255"`
+}
+
+// Cotización tal como la ve el cliente desde su enlace
+type QuoteReviewDTO struct {
+	Version     int            `json:"version"`
+	Status      QuoteStatus    `json:"status"`
+	ClientName  string         `json:"clientName"`
+	Origin      string         `json:"origin"`
+	Destination string         `json:"destination"`
+	Details     string         `json:"details"`
+	OrderType   string         `json:"orderType"`
+	MeetingDate time.Time      `json:"meetingDate"`
+	Breakdown   PriceBreakdown `json:"breakdown"`
+	Total       float64        `json:"total"`
+	ExpiresAt   time.Time      `json:"expiresAt"`
+}
+
 type OrderTrackingDTO struct {
-	Origin      string `json:"origin"`
-	Destination string `json:"destination"`
-	Status      string `json:"status"`
-	Type        string `json:"type"`
+	Origin      string            `json:"origin"`
+	Destination string            `json:"destination"`
+	Status      string            `json:"status"`
+	Type        string            `json:"type"`
+	Stops       []TrackingStopDTO `json:"stops"`
+	CurrentStop *int              `json:"currentStop"`
+}
+
+type TrackingStopDTO struct {
+	Position    int        `json:"position"`
+	Type        StopType   `json:"type"`
+	Address     string     `json:"address"`
+	Status      StopStatus `json:"status"`
+	WindowStart *time.Time `json:"windowStart,omitempty"`
+	WindowEnd   *time.Time `json:"windowEnd,omitempty"`
+}
+
+// Fila de la exportación de órdenes a hoja de cálculo
+type OrderExportDTO struct {
+	ID            uint          `export:"Orden"`
+	ClientName    string        `export:"Cliente"`
+	ClientPhone   string        `export:"Teléfono"`
+	Origin        string        `export:"Origen"`
+	Destination   string        `export:"Destino"`
+	TotalAmount   float64       `export:"Monto total"`
+	Details       string        `export:"Detalles"`
+	Status        OrderStatus   `export:"Estado"`
+	Type          string        `export:"Tipo de servicio"`
+	Date          time.Time     `export:"Fecha"`
+	MeetingDate   time.Time     `export:"Fecha de servicio"`
+	TotalWeightKg float64       `export:"Peso total (kg)"`
+	AmountPaid    float64       `export:"Monto pagado"`
+	BalanceDue    float64       `export:"Saldo pendiente"`
+	PaymentStatus PaymentStatus `export:"Estado de pago"`
+}
+
+// Fila de la exportación de gastos a hoja de cálculo
+type ExpenseExportDTO struct {
+	ID               uint      `export:"ID"`
+	Date             time.Time `export:"Fecha"`
+	Type             string    `export:"Tipo de gasto"`
+	TemporalEmployee bool      `export:"Empleado temporal"`
+	Description      string    `export:"Descripción"`
+	Amount           float64   `export:"Monto"`
+	OrderID          *uint     `export:"Orden"`
+	VehicleID        *uint     `export:"Vehículo"`
+	UserID           *uint     `export:"Empleado"`
+}
+
+type OrderProfitabilityDTO struct {
+	OrderID    uint      `json:"orderId" export:"Orden"`
+	Date       time.Time `json:"date" export:"Fecha de servicio"`
+	Type       string    `json:"type" export:"Tipo de servicio"`
+	ClientName string    `json:"clientName" export:"Cliente"`
+	VehicleID  *uint     `json:"vehicleId" export:"Vehículo"`
+	Profitability
+}
+
+type VehicleProfitabilityDTO struct {
+	VehicleID    uint   `json:"vehicleId" export:"ID"`
+	Vehicle      string `json:"vehicle" export:"Vehículo"`
+	LicensePlate string `json:"licensePlate" export:"Placa"`
+	Orders       int    `json:"orders" export:"Órdenes entregadas"`
+	Profitability
+}
+
+// Estado de resultados del periodo, con su desglose mensual y las comparaciones con el periodo anterior y el del año pasado
+type PnLReportDTO struct {
+	PnLStatement
+	Months         []PnLStatement `json:"months,omitempty"`
+	PreviousPeriod PnLComparison  `json:"previousPeriod"`
+	LastYear       PnLComparison  `json:"lastYear"`
+}
+
+// Metas de un mes ("2006-01") o un año ("2006"), generales o del piloto indicado
+// deliveryGoal es la meta de órdenes entregadas por empleado
+type PerformanceGoalDTO struct {
+	Period              string  `json:"period"`
+	UserID              *uint   `json:"userId"`
+	OrderGoal           int     `json:"orderGoal" binding:"gte=0"`
+	UtilityGoal         float64 `json:"utilityGoal"`
+	AveragePerOrderGoal float64 `json:"averagePerOrderGoal" binding:"gte=0"`
+	TravelGoal          int     `json:"travelGoal" binding:"gte=0"`
+	DeliveryGoal        float64 `json:"deliveryGoal" binding:"gte=0"`
+	AchievementRateGoal float64 `json:"achievementRateGoal" binding:"gte=0,lte=100"`
+}
+
+type GoalProgressDTO struct {
+	Period     string           `json:"period"`
+	UserID     *uint            `json:"userId"`
+	Start      time.Time        `json:"start"`
+	End        time.Time        `json:"end"`
+	ElapsedPct float64          `json:"elapsedPct"`
+	Goal       *PerformanceGoal `json:"goal"`
+	KPIs       KPIs             `json:"kpis"`
+	Metrics    []GoalMetric     `json:"metrics"`
+}
+
+// Indicadores de un mes comparados con el mes anterior
+// Months son las etiquetas de la serie de cada tendencia; SnapshotAt indica cuándo se guardaron al cierre, si vienen del histórico
+type KPIReportDTO struct {
+	Period         string     `json:"period"`
+	Start          time.Time  `json:"start"`
+	End            time.Time  `json:"end"`
+	Closed         bool       `json:"closed"`
+	SnapshotAt     *time.Time `json:"snapshotAt"`
+	KPIs           KPIs       `json:"kpis"`
+	PreviousPeriod KPIs       `json:"previousPeriod"`
+	Months         []string   `json:"months"`
+	Trends         []KPITrend `json:"trends"`
 }
 
 type FinancialReportDTO struct {
-	Date        time.Time `json:"date"`
-	Type        string    `json:"type"`
-	TotalAmount float64   `json:"totalAmount"`
-	User        string    `json:"user"`
+	OrderID       uint          `json:"orderId" export:"Orden"`
+	Date          time.Time     `json:"date" export:"Fecha de pago"`
+	Type          string        `json:"type" export:"Tipo de servicio"`
+	TotalAmount   float64       `json:"totalAmount" export:"Monto"`
+	PaymentMethod PaymentMethod `json:"paymentMethod" export:"Método de pago"`
+	User          string        `json:"user" export:"Piloto"`
 }
 
 type DriverReportDTO struct {
-	DriverName    string  `json:"driverName"`
-	TotalOrders   int     `json:"totalOrders"`
-	OrdersPerWeek float64 `json:"ordersPerWeek"`
+	DriverName    string  `json:"driverName" export:"Piloto"`
+	TotalOrders   int     `json:"totalOrders" export:"Órdenes entregadas"`
+	OrdersPerWeek float64 `json:"ordersPerWeek" export:"Órdenes por semana"`
 }
 
 type TotalIncomeReportDTO struct {
-	TotalIncome float64 `json:"totalIncome"`
+	TotalIncome float64 `json:"totalIncome" export:"Ingresos totales"`
 }
 
 type CompletedQuotationsDTO struct {
@@ -212,6 +515,9 @@ type ExpenseDTO struct {
 	TemporalEmployee bool      `json:"temporalEmployee"`
 	Description      string    `json:"description" binding:"required,max=255"`
 	Amount           float64   `json:"amount" binding:"required,gt=0"`
+	OrderID          *uint     `json:"orderId"`
+	VehicleID        *uint     `json:"vehicleId"`
+	UserID           *uint     `json:"userId"`
 }
 
 type ApiResponse struct {
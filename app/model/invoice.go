@@ -0,0 +1,37 @@
+package model
+
+import "fmt"
+
+// Línea de cobro de una factura
+type InvoiceLine struct {
+	Description string
+	Amount      float64
+}
+
+// Separa el impuesto incluido en el total según la tasa indicada
+// Los precios de las órdenes ya incluyen el impuesto
+func SplitTax(total, rate float64) (float64, float64) {
+	subtotal := roundMoney(total / (1 + rate))
+	return subtotal, roundMoney(total - subtotal)
+}
+
+// Convierte el desglose de la cotización en líneas de cobro, omitiendo los conceptos sin monto
+func (q *Quote) InvoiceLines() []InvoiceLine {
+	candidates := []InvoiceLine{
+		{"Tarifa base", q.BaseFee},
+		{fmt.Sprintf("Distancia (%.1f km)", q.DistanceKm), q.DistanceFee},
+		{fmt.Sprintf("Peso (%.1f kg)", q.WeightKg), q.WeightFee},
+		{fmt.Sprintf("Ayudantes (%d)", q.Helpers), q.HelperFee},
+		{"Recargo de fin de semana", q.WeekendSurcharge},
+		{"Recargo fuera de horario", q.AfterHoursSurcharge},
+		{"Ajuste a cobro mínimo", q.MinimumAdjustment},
+	}
+
+	var lines []InvoiceLine
+	for _, line := range candidates {
+		if line.Amount != 0 {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
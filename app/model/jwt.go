@@ -3,7 +3,8 @@ package model
 import "github.com/golang-jwt/jwt/v5"
 
 type EmployeeClaims struct {
-	UserID uint
-	Role   string `json:"role"`
+	UserID    uint
+	Role      string `json:"role"`
+	SessionID uint   `json:"sid"`
 	jwt.RegisteredClaims
 }
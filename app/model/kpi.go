@@ -0,0 +1,160 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// Indicadores de desempeño de un periodo
+type KPIs struct {
+	TotalIncome              float64 `json:"totalIncome"`
+	TotalExpenses            float64 `json:"totalExpenses"`
+	Utility                  float64 `json:"utility"`
+	Orders                   int64   `json:"orders"`
+	AveragePerOrder          float64 `json:"averagePerOrder"`
+	CompletedTrips           int64   `json:"completedTrips"`
+	DeliveredOrders          int64   `json:"deliveredOrders"`
+	AverageOrdersPerEmployee float64 `json:"averageOrdersPerEmployee"`
+	FulfillmentRate          float64 `json:"fulfillmentRate"`
+}
+
+// Retorna el mes ("2006-01") o el año ("2006") indicado como un periodo de fechas en UTC
+// Las fechas en UTC coinciden con las columnas de tipo fecha con las que se calculan los indicadores
+func ParseGoalPeriod(value string) (ReportPeriod, error) {
+	if date, err := time.Parse("2006-01", value); err == nil {
+		return NewReportPeriod(PeriodMonth, date)
+	}
+	if date, err := time.Parse("2006", value); err == nil {
+		return NewReportPeriod(PeriodYear, date)
+	}
+	return ReportPeriod{}, fmt.Errorf("period must be a month (YYYY-MM) or a year (YYYY)")
+}
+
+// Retorna el periodo con el mismo formato con el que se recibe
+func FormatGoalPeriod(period ReportPeriod) string {
+	if period.Kind == PeriodYear {
+		return period.Start.Format("2006")
+	}
+	return period.Start.Format("2006-01")
+}
+
+// Avance de un indicador respecto a su meta
+type GoalMetric struct {
+	Key       string  `json:"key"`
+	Goal      float64 `json:"goal"`
+	Actual    float64 `json:"actual"`
+	Projected float64 `json:"projected"`
+
+	// Porcentajes de la meta alcanzados y proyectados al final del periodo; son nulos si la meta es cero
+	AttainmentPct          *float64 `json:"attainmentPct"`
+	ProjectedAttainmentPct *float64 `json:"projectedAttainmentPct"`
+	OnTrack                bool     `json:"onTrack"`
+}
+
+// Compara cada meta con el valor real del indicador y proyecta el valor al final del periodo
+// Los indicadores acumulables se proyectan según el ritmo transcurrido; los promedios y tasas se mantienen
+// Recibe la fracción del periodo que ha transcurrido, entre 0 y 1
+func NewGoalMetrics(goal PerformanceGoal, kpis KPIs, elapsed float64) []GoalMetric {
+	metrics := []struct {
+		key        string
+		goal       float64
+		actual     float64
+		cumulative bool
+	}{
+		{"orderGoal", float64(goal.OrderGoal), float64(kpis.Orders), true},
+		{"utilityGoal", goal.UtilityGoal, kpis.Utility, true},
+		{"averagePerOrderGoal", goal.AveragePerOrderGoal, kpis.AveragePerOrder, false},
+		{"travelGoal", float64(goal.TravelGoal), float64(kpis.CompletedTrips), true},
+		{"deliveryGoal", goal.DeliveryGoal, kpis.AverageOrdersPerEmployee, true},
+		{"achievementRateGoal", goal.AchievementRateGoal, kpis.FulfillmentRate, false},
+	}
+
+	result := make([]GoalMetric, len(metrics))
+	for i, metric := range metrics {
+		projected := metric.actual
+		if metric.cumulative && elapsed > 0 && elapsed < 1 {
+			projected = metric.actual / elapsed
+		}
+
+		result[i] = GoalMetric{
+			Key:       metric.key,
+			Goal:      metric.goal,
+			Actual:    roundMoney(metric.actual),
+			Projected: roundMoney(projected),
+		}
+		if metric.goal != 0 {
+			attainment := roundMoney(metric.actual / metric.goal * 100)
+			projectedAttainment := roundMoney(projected / metric.goal * 100)
+			result[i].AttainmentPct = &attainment
+			result[i].ProjectedAttainmentPct = &projectedAttainment
+		}
+		result[i].OnTrack = projected >= metric.goal
+	}
+	return result
+}
+
+// Retorna la fracción del periodo transcurrida hasta el día indicado, incluyéndolo
+func ElapsedFraction(period ReportPeriod, today time.Time) float64 {
+	day := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, period.Start.Location()).AddDate(0, 0, 1)
+	if !day.After(period.Start) {
+		return 0
+	}
+	if !day.Before(period.End) {
+		return 1
+	}
+	return day.Sub(period.Start).Hours() / period.End.Sub(period.Start).Hours()
+}
+
+// Indicadores de un mes guardados al cierre
+// Las correcciones posteriores de órdenes, pagos o gastos no modifican el histórico
+type KPISnapshot struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	PeriodStart time.Time `json:"periodStart" gorm:"column:period_start;type:date;not null;uniqueIndex"`
+	KPIs        `gorm:"embedded"`
+	CreatedAt   time.Time `json:"createdAt" gorm:"column:created_at;autoCreateTime"`
+}
+
+// Tendencia de un indicador: el valor del periodo, el del periodo anterior y la serie de los últimos meses
+type KPITrend struct {
+	Key       string    `json:"key"`
+	Value     float64   `json:"value"`
+	Previous  float64   `json:"previous"`
+	Change    float64   `json:"change"`
+	ChangePct *float64  `json:"changePct"`
+	Series    []float64 `json:"series"`
+}
+
+// Retorna la tendencia de cada indicador a partir de los indicadores de cada mes en orden cronológico
+// El último mes es el periodo consultado y el penúltimo el periodo anterior
+func NewKPITrends(months []KPIs) []KPITrend {
+	values := func(k KPIs) []float64 {
+		return []float64{k.TotalIncome, k.TotalExpenses, k.Utility, float64(k.Orders), k.AveragePerOrder,
+			float64(k.CompletedTrips), float64(k.DeliveredOrders), k.AverageOrdersPerEmployee, k.FulfillmentRate}
+	}
+	keys := []string{"totalIncome", "totalExpenses", "utility", "orders", "averagePerOrder",
+		"completedTrips", "deliveredOrders", "averageOrdersPerEmployee", "fulfillmentRate"}
+
+	trends := make([]KPITrend, len(keys))
+	for i, key := range keys {
+		trends[i] = KPITrend{Key: key, Series: make([]float64, len(months))}
+	}
+	for m, month := range months {
+		for i, value := range values(month) {
+			trends[i].Series[m] = roundMoney(value)
+		}
+	}
+
+	for i := range trends {
+		series := trends[i].Series
+		if len(series) == 0 {
+			continue
+		}
+		trends[i].Value = series[len(series)-1]
+		if len(series) > 1 {
+			trends[i].Previous = series[len(series)-2]
+		}
+		trends[i].Change = roundMoney(trends[i].Value - trends[i].Previous)
+		trends[i].ChangePct = ChangePct(trends[i].Value, trends[i].Previous)
+	}
+	return trends
+}
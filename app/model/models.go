@@ -11,6 +11,7 @@ const (
 	FormStatusPending   FormStatus = "pending"
 	FormStatusCancelled FormStatus = "cancelled"
 	FormStatusApproved  FormStatus = "approved"
+	FormStatusExpired   FormStatus = "expired"
 )
 
 type VerificationResponse struct {
@@ -32,6 +33,10 @@ type User struct {
 	LastModifiedAt        time.Time  `json:"lastModifiedAt" gorm:"column:last_modified_at;autoUpdateTime"`
 	DeletedAt             *time.Time `json:"deletedAt" gorm:"column:deleted_at"`
 	IsActive              bool       `json:"isActive" gorm:"column:is_active;default:true"`
+
+	// Intentos fallidos de inicio de sesión seguidos; la cuenta no acepta intentos hasta LockedUntil
+	FailedLoginAttempts int        `json:"-" gorm:"column:failed_login_attempts;not null;default:0"`
+	LockedUntil         *time.Time `json:"-" gorm:"column:locked_until"`
 }
 
 type Vehicle struct {
@@ -48,6 +53,30 @@ type Vehicle struct {
 	IsActive       bool       `json:"isActive" gorm:"column:is_active;default:true"`
 }
 
+// Cliente identificado por su número de teléfono
+type Customer struct {
+	ID             uint              `json:"id" gorm:"primaryKey"`
+	Name           string            `json:"name" gorm:"size:100;not null"`
+	Phone          string            `json:"phone" gorm:"size:20;unique;not null"`
+	Email          *string           `json:"email" gorm:"size:50"`
+	Notes          *string           `json:"notes" gorm:"size:255"`
+	Addresses      []CustomerAddress `json:"addresses,omitempty" gorm:"foreignKey:CustomerID"`
+	CreatedAt      time.Time         `json:"createdAt" gorm:"column:created_at;autoCreateTime"`
+	LastModifiedAt time.Time         `json:"lastModifiedAt" gorm:"column:last_modified_at;autoUpdateTime"`
+	DeletedAt      *time.Time        `json:"deletedAt" gorm:"column:deleted_at"`
+	IsActive       bool              `json:"isActive" gorm:"column:is_active;default:true"`
+
+	// Valores calculados a partir de las órdenes del cliente
+	OrdersCount int64 `json:"ordersCount" gorm:"-"`
+}
+
+type CustomerAddress struct {
+	ID         uint    `json:"id" gorm:"primaryKey"`
+	CustomerID uint    `json:"customerId" gorm:"not null;index"`
+	Label      *string `json:"label" gorm:"size:50"`
+	Address    string  `json:"address" gorm:"size:255;not null"`
+}
+
 type ResetToken struct {
 	ID     uint      `gorm:"primaryKey"`
 	Token  string    `gorm:"size:255;not null"`
@@ -56,22 +85,200 @@ type ResetToken struct {
 	UserID uint      `gorm:"not null"`
 }
 
+// Sesión de un usuario, se renueva con un refresh token que cambia en cada uso
+// Solo se guarda el hash del token; el anterior se conserva para detectar si se reutiliza
+type Session struct {
+	ID                uint       `json:"id" gorm:"primaryKey"`
+	UserID            uint       `json:"userId" gorm:"column:user_id;not null;index"`
+	TokenHash         string     `json:"-" gorm:"column:token_hash;size:255;not null;uniqueIndex"`
+	PreviousTokenHash string     `json:"-" gorm:"column:previous_token_hash;size:255;index"`
+	UserAgent         string     `json:"userAgent" gorm:"column:user_agent;size:255"`
+	IP                string     `json:"ip" gorm:"column:ip;size:45"`
+	ExpiresAt         time.Time  `json:"expiresAt" gorm:"column:expires_at;not null"`
+	LastUsedAt        time.Time  `json:"lastUsedAt" gorm:"column:last_used_at"`
+	RevokedAt         *time.Time `json:"revokedAt" gorm:"column:revoked_at"`
+	CreatedAt         time.Time  `json:"createdAt" gorm:"column:created_at;autoCreateTime"`
+}
+
+type LoginAttemptKind string
+
+const (
+	LoginAttemptLogin  LoginAttemptKind = "login"
+	LoginAttemptForgot LoginAttemptKind = "forgot"
+)
+
+// Intento de inicio de sesión o de recuperación de contraseña
+// Sirve como bitácora y para limitar los intentos por IP y por cuenta
+type LoginAttempt struct {
+	ID        uint             `json:"id" gorm:"primaryKey"`
+	Kind      LoginAttemptKind `json:"kind" gorm:"size:10;not null"`
+	Email     string           `json:"email" gorm:"size:100;index"`
+	UserID    *uint            `json:"userId" gorm:"column:user_id;index"`
+	IP        string           `json:"ip" gorm:"column:ip;size:45;index"`
+	UserAgent string           `json:"userAgent" gorm:"column:user_agent;size:255"`
+	Success   bool             `json:"success" gorm:"not null;default:false"`
+	Reason    string           `json:"reason" gorm:"size:30"`
+	CreatedAt time.Time        `json:"createdAt" gorm:"column:created_at;autoCreateTime;index"`
+}
+
 type Order struct {
-	ID           uint      `gorm:"primaryKey" json:"id"`
-	SubmissionID uint      `json:"submissionId"`
-	UserID       *uint     `json:"userId,omitempty" gorm:"default:null"`
-	VehicleID    *uint     `json:"vehicleId,omitempty" gorm:"default:null"`
-	HelperID     *uint     `json:"helperId,omitempty" gorm:"default:null"`
-	ClientName   string    `json:"clientName"`
-	ClientPhone  string    `json:"clientPhone"`
-	Origin       string    `json:"origin" gorm:"size:100;not null"`
-	Destination  string    `json:"destination" gorm:"size:100;not null"`
-	TotalAmount  float64   `json:"totalAmount" gorm:"not null"`
-	Details      string    `json:"details"`
-	Status       string    `json:"status" gorm:"default:pending"`
-	Type         string    `json:"type" gorm:"not null"`
-	Date         time.Time `json:"date" gorm:"type:date"`
-	MeetingDate  time.Time `json:"meetingDate" gorm:"type:date;not null"`
+	ID           uint        `gorm:"primaryKey" json:"id"`
+	SubmissionID uint        `json:"submissionId"`
+	UserID       *uint       `json:"userId,omitempty" gorm:"default:null"`
+	VehicleID    *uint       `json:"vehicleId,omitempty" gorm:"default:null"`
+	HelperID     *uint       `json:"helperId,omitempty" gorm:"default:null"`
+	ClientName   string      `json:"clientName"`
+	ClientPhone  string      `json:"clientPhone"`
+	Origin       string      `json:"origin" gorm:"size:100;not null"`
+	Destination  string      `json:"destination" gorm:"size:100;not null"`
+	TotalAmount  float64     `json:"totalAmount" gorm:"not null"`
+	Details      string      `json:"details"`
+	Status       OrderStatus `json:"status" gorm:"default:pending"`
+	Type         string      `json:"type" gorm:"not null"`
+	Date         time.Time   `json:"date" gorm:"type:date"`
+	MeetingDate  time.Time   `json:"meetingDate" gorm:"type:date;not null"`
+	Items        []OrderItem `json:"items,omitempty" gorm:"foreignKey:OrderID"`
+	Stops        []OrderStop `json:"stops,omitempty" gorm:"foreignKey:OrderID"`
+	QuoteID      *uint       `json:"quoteId" gorm:"column:quote_id"`
+	CustomerID   *uint       `json:"customerId" gorm:"column:customer_id;index"`
+
+	// Valores calculados a partir de las relaciones precargadas
+	TotalWeightKg float64       `json:"totalWeightKg" gorm:"-"`
+	AmountPaid    float64       `json:"amountPaid" gorm:"-"`
+	BalanceDue    float64       `json:"balanceDue" gorm:"-"`
+	PaymentStatus PaymentStatus `json:"paymentStatus" gorm:"-"`
+}
+
+// Calcula los valores derivados de la orden a partir de sus relaciones precargadas
+func (o *Order) ComputeTotals() {
+	o.TotalWeightKg = 0
+	for _, item := range o.Items {
+		o.TotalWeightKg += item.TotalWeightKg()
+	}
+}
+
+// Calcula el saldo pendiente y el estado de pago a partir del monto pagado
+func (o *Order) ApplyPayments(paid float64) {
+	o.AmountPaid = roundMoney(paid)
+	o.BalanceDue = roundMoney(o.TotalAmount - paid)
+	if o.BalanceDue < 0 {
+		o.BalanceDue = 0
+	}
+	o.PaymentStatus = PaymentStatusFor(o.TotalAmount, paid)
+}
+
+// Pago registrado para una orden, ya sea anticipo, abono o liquidación
+// Los pagos anulados no cuentan para el saldo ni para los ingresos
+type Payment struct {
+	ID         uint          `json:"id" gorm:"primaryKey"`
+	OrderID    uint          `json:"orderId" gorm:"not null;index"`
+	Amount     float64       `json:"amount" gorm:"not null"`
+	Method     PaymentMethod `json:"method" gorm:"size:15;not null"`
+	PaidAt     time.Time     `json:"paidAt" gorm:"column:paid_at;type:date;not null"`
+	Reference  *string       `json:"reference" gorm:"size:100"`
+	IsDeposit  bool          `json:"isDeposit" gorm:"column:is_deposit;not null;default:false"`
+	ReceivedBy *uint         `json:"receivedBy" gorm:"column:received_by"`
+	CreatedAt  time.Time     `json:"createdAt" gorm:"column:created_at;autoCreateTime"`
+
+	// Un pago registrado por error se anula en lugar de eliminarse para conservar el registro
+	VoidedAt   *time.Time `json:"voidedAt" gorm:"column:voided_at"`
+	VoidedBy   *uint      `json:"voidedBy" gorm:"column:voided_by"`
+	VoidReason *string    `json:"voidReason" gorm:"column:void_reason;size:255"`
+}
+
+// Factura emitida para una orden entregada
+// Conserva los montos y el documento generado para poder descargarlo de nuevo sin cambios
+type Invoice struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Number      uint      `json:"number" gorm:"unique;not null"`
+	OrderID     uint      `json:"orderId" gorm:"unique;not null"`
+	ClientName  string    `json:"clientName" gorm:"column:client_name;size:50;not null"`
+	ClientPhone string    `json:"clientPhone" gorm:"column:client_phone;size:20"`
+	Subtotal    float64   `json:"subtotal" gorm:"not null"`
+	TaxRate     float64   `json:"taxRate" gorm:"column:tax_rate;not null"`
+	TaxAmount   float64   `json:"taxAmount" gorm:"column:tax_amount;not null"`
+	Total       float64   `json:"total" gorm:"not null"`
+	Document    []byte    `json:"-" gorm:"not null"`
+	IssuedBy    *uint     `json:"issuedBy" gorm:"column:issued_by"`
+	IssuedAt    time.Time `json:"issuedAt" gorm:"column:issued_at;autoCreateTime"`
+}
+
+// Contador de la numeración de facturas
+// Se actualiza en la misma transacción que crea la factura para que la secuencia no tenga saltos
+type InvoiceSequence struct {
+	ID         uint `gorm:"primaryKey"`
+	LastNumber uint `gorm:"column:last_number;not null;default:0"`
+}
+
+// Artículo de la carga de una orden
+type OrderItem struct {
+	ID          uint    `json:"id" gorm:"primaryKey"`
+	OrderID     uint    `json:"orderId" gorm:"not null;index"`
+	Description string  `json:"description" gorm:"size:255;not null"`
+	Quantity    int     `json:"quantity" gorm:"not null;default:1"`
+	WeightKg    float64 `json:"weightKg" gorm:"column:weight_kg;not null;default:0"`
+	VolumeM3    float64 `json:"volumeM3" gorm:"column:volume_m3;not null;default:0"`
+	IsFragile   bool    `json:"isFragile" gorm:"column:is_fragile;not null;default:false"`
+}
+
+// Parada de la ruta de una orden
+type OrderStop struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	OrderID      uint       `json:"orderId" gorm:"not null;index"`
+	Position     int        `json:"position" gorm:"not null"`
+	Type         StopType   `json:"type" gorm:"size:10;not null"`
+	Address      string     `json:"address" gorm:"size:255;not null"`
+	ContactName  *string    `json:"contactName" gorm:"column:contact_name;size:100"`
+	ContactPhone *string    `json:"contactPhone" gorm:"column:contact_phone;size:20"`
+	WindowStart  *time.Time `json:"windowStart" gorm:"column:window_start"`
+	WindowEnd    *time.Time `json:"windowEnd" gorm:"column:window_end"`
+	Status       StopStatus `json:"status" gorm:"size:15;not null;default:pending"`
+	CompletedAt  *time.Time `json:"completedAt" gorm:"column:completed_at"`
+}
+
+// Peso estimado de todas las unidades del artículo
+func (i OrderItem) TotalWeightKg() float64 {
+	return float64(i.Quantity) * i.WeightKg
+}
+
+// Registro de cada cambio de estado de una orden
+type OrderStatusHistory struct {
+	ID         uint        `json:"id" gorm:"primaryKey"`
+	OrderID    uint        `json:"orderId" gorm:"not null;index"`
+	FromStatus OrderStatus `json:"fromStatus" gorm:"column:from_status"`
+	ToStatus   OrderStatus `json:"toStatus" gorm:"column:to_status;not null"`
+	ChangedBy  *uint       `json:"changedBy" gorm:"column:changed_by"`
+	Reason     *string     `json:"reason,omitempty" gorm:"size:255"`
+	ChangedAt  time.Time   `json:"changedAt" gorm:"column:changed_at;autoCreateTime"`
+}
+
+// Constancia de entrega registrada por el conductor al terminar el traslado
+type ProofOfDelivery struct {
+	ID            uint         `json:"id" gorm:"primaryKey"`
+	OrderID       uint         `json:"orderId" gorm:"unique;not null"`
+	RecipientName string       `json:"recipientName" gorm:"column:recipient_name;size:100;not null"`
+	Notes         *string      `json:"notes" gorm:"size:255"`
+	DeliveredAt   time.Time    `json:"deliveredAt" gorm:"column:delivered_at;not null"`
+	SubmittedBy   uint         `json:"submittedBy" gorm:"column:submitted_by;not null"`
+	Files         []Attachment `json:"files" gorm:"polymorphic:Owner;polymorphicValue:proof_of_deliveries"`
+}
+
+// Archivo adjunto a una orden, gasto, vehículo, usuario o constancia de entrega
+// El contenido se guarda en el almacenamiento de archivos y se descarga mediante un enlace firmado
+type Attachment struct {
+	ID          uint            `json:"id" gorm:"primaryKey"`
+	OwnerType   AttachmentOwner `json:"ownerType" gorm:"column:owner_type;size:30;not null;index:idx_attachments_owner"`
+	OwnerID     uint            `json:"ownerId" gorm:"column:owner_id;not null;index:idx_attachments_owner"`
+	Category    string          `json:"category" gorm:"size:50"`
+	FileName    string          `json:"fileName" gorm:"column:file_name;size:255;not null"`
+	FileKey     string          `json:"-" gorm:"column:file_key;size:255;not null"`
+	ContentType string          `json:"contentType" gorm:"column:content_type;size:100;not null"`
+	Size        int64           `json:"size" gorm:"not null"`
+	UploadedBy  *uint           `json:"uploadedBy" gorm:"column:uploaded_by"`
+	CreatedAt   time.Time       `json:"createdAt" gorm:"column:created_at;autoCreateTime"`
+
+	// Enlace de descarga firmado, generado al consultar el archivo
+	URL string `json:"url,omitempty" gorm:"-"`
 }
 
 type OrderToken struct {
@@ -88,6 +295,12 @@ type QuestionType struct {
 	Type string `json:"type" gorm:"size:50;not null" validate:"required,question_type"`
 }
 
+// Preguntas fijas del formulario con las que se identifica al cliente
+const (
+	QuestionClientName  = "¿Cuál es tu nombre?"
+	QuestionClientPhone = "¿Cuál es tu número de teléfono?"
+)
+
 // Pregunta del formulario
 type Question struct {
 	ID          uint             `json:"id" gorm:"primaryKey"`
@@ -116,6 +329,7 @@ type Submission struct {
 	ID          uint       `json:"id" gorm:"primaryKey"`
 	SubmittedAt time.Time  `json:"submittedAt" gorm:"default:CURRENT_TIMESTAMP"`
 	Status      FormStatus `json:"status" gorm:"type:form_status;not null;default:'pending'" validate:"required,submission_status"`
+	CustomerID  *uint      `json:"customerId" gorm:"column:customer_id;index"`
 	Answers     []Answer   `json:"answers,omitempty" gorm:"foreignKey:SubmissionID"`
 }
 
@@ -129,6 +343,59 @@ type Answer struct {
 	Options      []QuestionOption `json:"options,omitempty" gorm:"many2many:answer_options;"`
 }
 
+// Tarifas con las que se cotiza un tipo de orden
+type PricingRule struct {
+	ID                     uint      `json:"id" gorm:"primaryKey"`
+	OrderType              string    `json:"orderType" gorm:"column:order_type;size:50;unique;not null"`
+	BaseFee                float64   `json:"baseFee" gorm:"column:base_fee;not null"`
+	PerKmRate              float64   `json:"perKmRate" gorm:"column:per_km_rate;not null"`
+	PerKgRate              float64   `json:"perKgRate" gorm:"column:per_kg_rate;not null"`
+	HelperFee              float64   `json:"helperFee" gorm:"column:helper_fee;not null"`
+	WeekendSurchargePct    float64   `json:"weekendSurchargePct" gorm:"column:weekend_surcharge_pct;not null"`
+	AfterHoursSurchargePct float64   `json:"afterHoursSurchargePct" gorm:"column:after_hours_surcharge_pct;not null"`
+	WorkdayStartHour       int       `json:"workdayStartHour" gorm:"column:workday_start_hour;not null;default:7"`
+	WorkdayEndHour         int       `json:"workdayEndHour" gorm:"column:workday_end_hour;not null;default:18"`
+	MinimumCharge          float64   `json:"minimumCharge" gorm:"column:minimum_charge;not null"`
+	IsActive               bool      `json:"isActive" gorm:"column:is_active;default:true"`
+	CreatedAt              time.Time `json:"createdAt" gorm:"column:created_at;autoCreateTime"`
+	LastModifiedAt         time.Time `json:"lastModifiedAt" gorm:"column:last_modified_at;autoUpdateTime"`
+}
+
+// Versión de una cotización enviada para un envío de formulario
+// Cada cambio de precio genera una nueva versión y la anterior queda reemplazada
+type Quote struct {
+	ID             uint        `json:"id" gorm:"primaryKey"`
+	SubmissionID   uint        `json:"submissionId" gorm:"not null;index;uniqueIndex:idx_quotes_submission_version"`
+	Version        int         `json:"version" gorm:"not null;uniqueIndex:idx_quotes_submission_version"`
+	Status         QuoteStatus `json:"status" gorm:"size:15;not null;default:draft"`
+	ClientName     string      `json:"clientName" gorm:"column:client_name;size:50;not null"`
+	ClientPhone    string      `json:"clientPhone" gorm:"column:client_phone;size:20;not null"`
+	Origin         string      `json:"origin" gorm:"size:100;not null"`
+	Destination    string      `json:"destination" gorm:"size:100;not null"`
+	Details        string      `json:"details" gorm:"size:255"`
+	OrderType      string      `json:"orderType" gorm:"column:order_type;size:50;not null"`
+	MeetingDate    time.Time   `json:"meetingDate" gorm:"column:meeting_date;not null"`
+	DistanceKm     float64     `json:"distanceKm" gorm:"column:distance_km"`
+	WeightKg       float64     `json:"weightKg" gorm:"column:weight_kg"`
+	Helpers        int         `json:"helpers"`
+	PriceBreakdown `gorm:"embedded"`
+	OverrideAmount *float64   `json:"overrideAmount" gorm:"column:override_amount"`
+	OverrideReason *string    `json:"overrideReason" gorm:"column:override_reason;size:255"`
+	Total          float64    `json:"total" gorm:"not null"`
+	DeclineReason  *string    `json:"declineReason" gorm:"column:decline_reason;size:255"`
+	RespondedAt    *time.Time `json:"respondedAt" gorm:"column:responded_at"`
+	CreatedBy      *uint      `json:"createdBy" gorm:"column:created_by"`
+	CreatedAt      time.Time  `json:"createdAt" gorm:"column:created_at;autoCreateTime"`
+}
+
+// Enlace con el que el cliente revisa y responde una cotización
+type QuoteToken struct {
+	ID      uint      `json:"id" gorm:"primaryKey"`
+	QuoteID uint      `json:"quoteId" gorm:"unique;not null;column:quote_id"`
+	Token   string    `json:"token" gorm:"not null;unique;size:255"`
+	Expiry  time.Time `json:"expiry" gorm:"not null"`
+}
+
 type ExpenseType struct {
 	ID   uint   `json:"id" gorm:"primaryKey"`
 	Type string `json:"type" gorm:"not null;size:25"`
@@ -142,14 +409,29 @@ type Expense struct {
 	TemporalEmployee bool        `json:"temporalEmployee" gorm:"not null;column:temporal_employee"`
 	Description      string      `json:"description" gorm:"size:255"`
 	Amount           float64     `json:"amount" gorm:"not null" validate:"gt=0"`
+	OrderID          *uint       `json:"orderId" gorm:"column:order_id;index"`
+	VehicleID        *uint       `json:"vehicleId" gorm:"column:vehicle_id;index"`
+	UserID           *uint       `json:"userId" gorm:"column:user_id;index"`
+
+	// Comprobantes del gasto guardados como adjuntos
+	Receipts []Attachment `json:"receipts,omitempty" gorm:"polymorphic:Owner;polymorphicValue:expenses"`
 }
 
+// Metas de desempeño de un mes o un año, generales o de un piloto
+// Cada periodo conserva sus metas para poder evaluarlo después
+// Las metas generales no tienen piloto, por lo que se distinguen con un índice único propio
 type PerformanceGoal struct {
-	ID                  uint    `json:"id" gorm:"primaryKey"`
-	OrderGoal           int     `json:"orderGoal" gorm:"not null"`
-	UtilityGoal         float64 `json:"utilityGoal" gorm:"not null"`
-	AveragePerOrderGoal float64 `json:"averagePerOrderGoal" gorm:"not null"`
-	TravelGoal          int     `json:"travelGoal" gorm:"not null"`
-	DeliveryGoal        float64 `json:"deliveryGoal" gorm:"not null"`
-	AchievementRateGoal float64 `json:"achievementRateGoal" gorm:"not null"`
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	Period      PeriodKind `json:"period" gorm:"size:10;not null;uniqueIndex:idx_performance_goals_general,where:user_id IS NULL;uniqueIndex:idx_performance_goals_driver,where:user_id IS NOT NULL"`
+	PeriodStart time.Time  `json:"periodStart" gorm:"column:period_start;type:date;not null;uniqueIndex:idx_performance_goals_general;uniqueIndex:idx_performance_goals_driver"`
+	UserID      *uint      `json:"userId" gorm:"column:user_id;uniqueIndex:idx_performance_goals_driver"`
+
+	OrderGoal           int       `json:"orderGoal" gorm:"not null"`
+	UtilityGoal         float64   `json:"utilityGoal" gorm:"not null"`
+	AveragePerOrderGoal float64   `json:"averagePerOrderGoal" gorm:"not null"`
+	TravelGoal          int       `json:"travelGoal" gorm:"not null"`
+	DeliveryGoal        float64   `json:"deliveryGoal" gorm:"not null"`
+	AchievementRateGoal float64   `json:"achievementRateGoal" gorm:"not null"`
+	UpdatedBy           *uint     `json:"updatedBy" gorm:"column:updated_by"`
+	UpdatedAt           time.Time `json:"updatedAt" gorm:"column:updated_at;autoUpdateTime"`
 }
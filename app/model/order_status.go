@@ -0,0 +1,65 @@
+package model
+
+type OrderStatus string
+
+const (
+	OrderStatusPending   OrderStatus = "pending"
+	OrderStatusAssigned  OrderStatus = "assigned"
+	OrderStatusPickup    OrderStatus = "pickup"
+	OrderStatusCollected OrderStatus = "collected"
+	OrderStatusDelivered OrderStatus = "delivered"
+	OrderStatusCancelled OrderStatus = "cancelled"
+	OrderStatusFailed    OrderStatus = "failed"
+	OrderStatusNoShow    OrderStatus = "no_show"
+)
+
+// Transiciones permitidas entre estados de una orden
+// Para cada estado de origen se indican los estados destino y los permisos con los que se pueden realizar
+// El piloto puede cancelar una orden asignada a él; antes de asignarla solo la cancela quien administra las órdenes
+var orderTransitions = map[OrderStatus]map[OrderStatus][]Permission{
+	OrderStatusPending: {
+		OrderStatusAssigned:  {PermissionOrdersAssign},
+		OrderStatusCancelled: {PermissionOrdersManage},
+	},
+	OrderStatusAssigned: {
+		OrderStatusPending:   {PermissionOrdersManage},
+		OrderStatusPickup:    {PermissionOrdersProgress},
+		OrderStatusCancelled: {PermissionOrdersManage, PermissionOrdersProgress},
+		OrderStatusFailed:    {PermissionOrdersProgress},
+	},
+	OrderStatusPickup: {
+		OrderStatusCollected: {PermissionOrdersProgress},
+		OrderStatusCancelled: {PermissionOrdersManage, PermissionOrdersProgress},
+		OrderStatusFailed:    {PermissionOrdersProgress},
+		OrderStatusNoShow:    {PermissionOrdersProgress},
+	},
+	OrderStatusCollected: {
+		OrderStatusDelivered: {PermissionOrdersProgress},
+	},
+	// Una orden fallida puede reprogramarse, lo que la devuelve a pendiente
+	OrderStatusFailed: {
+		OrderStatusPending:   {PermissionOrdersManage},
+		OrderStatusCancelled: {PermissionOrdersManage},
+	},
+	OrderStatusNoShow: {
+		OrderStatusPending:   {PermissionOrdersManage},
+		OrderStatusCancelled: {PermissionOrdersManage},
+	},
+}
+
+// Determina si con los permisos indicados se puede mover una orden del estado actual al siguiente
+// Retorna un boolean
+func (s OrderStatus) CanTransitionTo(next OrderStatus, permissions PermissionSet) bool {
+	required, ok := orderTransitions[s][next]
+	return ok && permissions.HasAny(required...)
+}
+
+// Indica si el estado ya no admite más transiciones
+func (s OrderStatus) IsTerminal() bool {
+	return len(orderTransitions[s]) == 0
+}
+
+// Indica si el estado corresponde a una orden que no llegó a completarse
+func (s OrderStatus) IsUnfulfilled() bool {
+	return s == OrderStatusCancelled || s == OrderStatusFailed || s == OrderStatusNoShow
+}
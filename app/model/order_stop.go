@@ -0,0 +1,77 @@
+package model
+
+type StopType string
+
+const (
+	StopTypePickup  StopType = "pickup"
+	StopTypeDropoff StopType = "dropoff"
+)
+
+type StopStatus string
+
+const (
+	StopStatusPending   StopStatus = "pending"
+	StopStatusArrived   StopStatus = "arrived"
+	StopStatusCompleted StopStatus = "completed"
+	StopStatusSkipped   StopStatus = "skipped"
+)
+
+// Indica si la parada ya fue atendida, ya sea completándola u omitiéndola
+func (s StopStatus) IsDone() bool {
+	return s == StopStatusCompleted || s == StopStatusSkipped
+}
+
+// Determina si una parada puede pasar del estado actual al siguiente
+func (s StopStatus) CanTransitionTo(next StopStatus) bool {
+	switch s {
+	case StopStatusPending:
+		return next == StopStatusArrived || next == StopStatusCompleted || next == StopStatusSkipped
+	case StopStatusArrived:
+		return next == StopStatusCompleted || next == StopStatusSkipped
+	}
+	return false
+}
+
+// Calcula el estado de una orden en curso según el avance de sus paradas
+// Las recolecciones omitidas cuentan como atendidas para que la ruta pueda continuar
+// Retorna assigned si ninguna parada ha comenzado
+func RouteStatus(stops []OrderStop) OrderStatus {
+	started := false
+	pickupsDone, dropoffsDone := true, true
+	delivered := false
+
+	for _, stop := range stops {
+		if stop.Status != StopStatusPending {
+			started = true
+		}
+
+		switch stop.Type {
+		case StopTypePickup:
+			pickupsDone = pickupsDone && stop.Status.IsDone()
+		case StopTypeDropoff:
+			dropoffsDone = dropoffsDone && stop.Status.IsDone()
+			delivered = delivered || stop.Status == StopStatusCompleted
+		}
+	}
+
+	switch {
+	case pickupsDone && dropoffsDone && delivered:
+		return OrderStatusDelivered
+	case pickupsDone && started:
+		return OrderStatusCollected
+	case started:
+		return OrderStatusPickup
+	}
+	return OrderStatusAssigned
+}
+
+// Índice de la primera parada pendiente de atender
+// Retorna -1 si todas fueron atendidas
+func CurrentStopIndex(stops []OrderStop) int {
+	for i, stop := range stops {
+		if !stop.Status.IsDone() {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,38 @@
+package model
+
+import "gorm.io/gorm"
+
+type PaymentMethod string
+
+const (
+	PaymentMethodCash     PaymentMethod = "cash"
+	PaymentMethodTransfer PaymentMethod = "transfer"
+	PaymentMethodCard     PaymentMethod = "card"
+
+	// Cobros de órdenes entregadas antes de que existiera el registro de pagos
+	PaymentMethodUnknown PaymentMethod = "unknown"
+)
+
+type PaymentStatus string
+
+const (
+	PaymentStatusUnpaid  PaymentStatus = "unpaid"
+	PaymentStatusPartial PaymentStatus = "partial"
+	PaymentStatusPaid    PaymentStatus = "paid"
+)
+
+// Determina el estado de pago de una orden según su total y lo pagado
+func PaymentStatusFor(total, paid float64) PaymentStatus {
+	switch {
+	case roundMoney(paid) <= 0:
+		return PaymentStatusUnpaid
+	case roundMoney(paid) < roundMoney(total):
+		return PaymentStatusPartial
+	}
+	return PaymentStatusPaid
+}
+
+// Excluye los pagos anulados
+func ActivePayments(db *gorm.DB) *gorm.DB {
+	return db.Where("payments.voided_at IS NULL")
+}
@@ -0,0 +1,149 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Permiso para realizar una acción, con el formato recurso:acción[:alcance]
+type Permission string
+
+const (
+	PermissionUsersRead         Permission = "users:read"
+	PermissionUsersManage       Permission = "users:manage"
+	PermissionRolesManage       Permission = "roles:manage"
+	PermissionVehiclesManage    Permission = "vehicles:manage"
+	PermissionCustomersManage   Permission = "customers:manage"
+	PermissionOrdersReadAll     Permission = "orders:read:all"
+	PermissionOrdersReadOwn     Permission = "orders:read:own"
+	PermissionOrdersManage      Permission = "orders:manage"
+	PermissionOrdersAssign      Permission = "orders:assign"
+	PermissionOrdersProgress    Permission = "orders:progress"
+	PermissionPaymentsVoid      Permission = "payments:void"
+	PermissionInvoicesRead      Permission = "invoices:read"
+	PermissionAttachmentsDelete Permission = "attachments:delete"
+	PermissionQuotesManage      Permission = "quotes:manage"
+	PermissionFormManage        Permission = "form:manage"
+	PermissionSubmissionsManage Permission = "submissions:manage"
+	PermissionExpensesManage    Permission = "expenses:manage"
+	PermissionReportsFinancial  Permission = "reports:financial"
+	PermissionReportsOperations Permission = "reports:operations"
+	PermissionKPIRead           Permission = "kpi:read"
+	PermissionKPIGoals          Permission = "kpi:goals"
+)
+
+type PermissionInfo struct {
+	Permission  Permission `json:"permission"`
+	Description string     `json:"description"`
+}
+
+// Catálogo de todos los permisos que existen en el sistema
+var Permissions = []PermissionInfo{
+	{PermissionUsersRead, "Ver los usuarios"},
+	{PermissionUsersManage, "Registrar, editar y desactivar usuarios"},
+	{PermissionRolesManage, "Administrar los roles y sus permisos"},
+	{PermissionVehiclesManage, "Administrar los vehículos"},
+	{PermissionCustomersManage, "Administrar los clientes"},
+	{PermissionOrdersReadAll, "Ver todas las órdenes"},
+	{PermissionOrdersReadOwn, "Ver las órdenes asignadas"},
+	{PermissionOrdersManage, "Crear, editar, cancelar y reprogramar órdenes"},
+	{PermissionOrdersAssign, "Asignar personal y vehículos a las órdenes"},
+	{PermissionOrdersProgress, "Avanzar el estado de las órdenes asignadas y cancelarlas"},
+	{PermissionPaymentsVoid, "Anular pagos"},
+	{PermissionInvoicesRead, "Descargar facturas y comprobantes de entrega"},
+	{PermissionAttachmentsDelete, "Eliminar archivos adjuntos"},
+	{PermissionQuotesManage, "Administrar tarifas y cotizaciones"},
+	{PermissionFormManage, "Administrar las preguntas del formulario"},
+	{PermissionSubmissionsManage, "Ver y actualizar los envíos del formulario"},
+	{PermissionExpensesManage, "Administrar los gastos y sus tipos"},
+	{PermissionReportsFinancial, "Ver los reportes financieros"},
+	{PermissionReportsOperations, "Ver los reportes de desempeño"},
+	{PermissionKPIRead, "Ver los indicadores"},
+	{PermissionKPIGoals, "Definir las metas de desempeño"},
+}
+
+// Indica si el permiso existe en el catálogo
+func (p Permission) IsValid() bool {
+	for _, info := range Permissions {
+		if info.Permission == p {
+			return true
+		}
+	}
+	return false
+}
+
+// Retorna los nombres de todos los permisos del catálogo
+func AllPermissions() []Permission {
+	permissions := make([]Permission, len(Permissions))
+	for i, info := range Permissions {
+		permissions[i] = info.Permission
+	}
+	return permissions
+}
+
+// Conjunto de permisos de un rol
+type PermissionSet map[Permission]bool
+
+// Indica si el conjunto tiene al menos uno de los permisos indicados
+func (s PermissionSet) HasAny(permissions ...Permission) bool {
+	for _, permission := range permissions {
+		if s[permission] {
+			return true
+		}
+	}
+	return false
+}
+
+// Indica si el rol es de personal de campo, que solo ve las órdenes que tiene asignadas
+// Solo el personal de campo se asigna como piloto o ayudante de una orden
+func (s PermissionSet) IsFieldStaff() bool {
+	return s[PermissionOrdersReadOwn] && !s[PermissionOrdersReadAll]
+}
+
+// Indica si el rol puede asignarse como piloto, quien avanza el estado de la orden
+func (s PermissionSet) CanDrive() bool {
+	return s.IsFieldStaff() && s[PermissionOrdersProgress]
+}
+
+// Rol de un usuario, definido como un conjunto de permisos
+// Los roles del sistema no se pueden eliminar porque la asignación de personal depende de ellos
+type Role struct {
+	ID          uint             `json:"id" gorm:"primaryKey"`
+	Name        string           `json:"name" gorm:"size:50;not null;uniqueIndex"`
+	Description string           `json:"description" gorm:"size:255"`
+	System      bool             `json:"system" gorm:"not null;default:false"`
+	Permissions []RolePermission `json:"permissions" gorm:"foreignKey:RoleID;constraint:OnDelete:CASCADE"`
+	CreatedAt   time.Time        `json:"createdAt" gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt   time.Time        `json:"updatedAt" gorm:"column:updated_at;autoUpdateTime"`
+}
+
+type RolePermission struct {
+	RoleID     uint       `gorm:"primaryKey"`
+	Permission Permission `gorm:"primaryKey;size:50"`
+}
+
+// Los permisos de un rol se muestran como una lista de nombres
+func (p RolePermission) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.Permission)
+}
+
+// Retorna los permisos del rol como un conjunto
+func (r Role) PermissionSet() PermissionSet {
+	set := make(PermissionSet, len(r.Permissions))
+	for _, permission := range r.Permissions {
+		set[permission.Permission] = true
+	}
+	return set
+}
+
+// Roles que se crean al iniciar el sistema si no existen
+// El administrador recibe siempre todos los permisos del catálogo
+var DefaultRoles = []struct {
+	Name        string
+	Description string
+	Permissions []Permission
+}{
+	{"admin", "Administrador", nil},
+	{"driver", "Piloto", []Permission{PermissionOrdersReadOwn, PermissionOrdersProgress}},
+	{"helper", "Ayudante", []Permission{PermissionOrdersReadOwn}},
+}
@@ -0,0 +1,173 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+type PeriodKind string
+
+const (
+	PeriodMonth   PeriodKind = "month"
+	PeriodQuarter PeriodKind = "quarter"
+	PeriodYear    PeriodKind = "year"
+	PeriodCustom  PeriodKind = "custom"
+)
+
+// Rango de fechas de un reporte; el inicio se incluye y el fin no
+type ReportPeriod struct {
+	Kind  PeriodKind `json:"kind"`
+	Start time.Time  `json:"start"`
+	End   time.Time  `json:"end"`
+}
+
+// Retorna el mes, trimestre o año calendario que contiene la fecha indicada
+func NewReportPeriod(kind PeriodKind, date time.Time) (ReportPeriod, error) {
+	year, month, _ := date.Date()
+	start := time.Date(year, month, 1, 0, 0, 0, 0, date.Location())
+
+	switch kind {
+	case PeriodMonth:
+		return ReportPeriod{Kind: kind, Start: start, End: start.AddDate(0, 1, 0)}, nil
+	case PeriodQuarter:
+		start = start.AddDate(0, -(int(month-1) % 3), 0)
+		return ReportPeriod{Kind: kind, Start: start, End: start.AddDate(0, 3, 0)}, nil
+	case PeriodYear:
+		start = start.AddDate(0, -int(month-1), 0)
+		return ReportPeriod{Kind: kind, Start: start, End: start.AddDate(1, 0, 0)}, nil
+	}
+	return ReportPeriod{}, fmt.Errorf("unknown period %q", kind)
+}
+
+// Retorna un periodo personalizado que incluye completos los días de inicio y fin
+func NewCustomPeriod(start, end time.Time) (ReportPeriod, error) {
+	if end.Before(start) {
+		return ReportPeriod{}, fmt.Errorf("end date can't be before start date")
+	}
+	return ReportPeriod{Kind: PeriodCustom, Start: start, End: end.AddDate(0, 0, 1)}, nil
+}
+
+// Retorna el periodo inmediatamente anterior con la misma duración
+func (p ReportPeriod) Previous() ReportPeriod {
+	switch p.Kind {
+	case PeriodMonth:
+		return ReportPeriod{Kind: p.Kind, Start: p.Start.AddDate(0, -1, 0), End: p.Start}
+	case PeriodQuarter:
+		return ReportPeriod{Kind: p.Kind, Start: p.Start.AddDate(0, -3, 0), End: p.Start}
+	case PeriodYear:
+		return ReportPeriod{Kind: p.Kind, Start: p.Start.AddDate(-1, 0, 0), End: p.Start}
+	}
+
+	days := int(p.End.Sub(p.Start).Hours()/24 + 0.5)
+	return ReportPeriod{Kind: p.Kind, Start: p.Start.AddDate(0, 0, -days), End: p.Start}
+}
+
+// Retorna el mismo periodo del año anterior
+func (p ReportPeriod) LastYear() ReportPeriod {
+	return ReportPeriod{Kind: p.Kind, Start: p.Start.AddDate(-1, 0, 0), End: p.End.AddDate(-1, 0, 0)}
+}
+
+// Divide el periodo en meses calendario, recortando el primero y el último al rango del periodo
+func (p ReportPeriod) Months() []ReportPeriod {
+	var months []ReportPeriod
+	start := p.Start
+	for start.Before(p.End) {
+		year, month, _ := start.Date()
+		end := time.Date(year, month+1, 1, 0, 0, 0, 0, start.Location())
+		if end.After(p.End) {
+			end = p.End
+		}
+		months = append(months, ReportPeriod{Kind: PeriodMonth, Start: start, End: end})
+		start = end
+	}
+	return months
+}
+
+// Monto de un concepto del estado de resultados
+type PnLLine struct {
+	Label  string  `json:"label"`
+	Amount float64 `json:"amount"`
+}
+
+// Estado de resultados de un periodo
+// Los costos de servicio son los gastos vinculados a una orden o vehículo; el resto son gastos de operación
+type PnLStatement struct {
+	Period                 ReportPeriod `json:"period"`
+	Revenue                []PnLLine    `json:"revenue"`
+	TotalRevenue           float64      `json:"totalRevenue"`
+	CostOfServices         []PnLLine    `json:"costOfServices"`
+	TotalCostOfServices    float64      `json:"totalCostOfServices"`
+	GrossProfit            float64      `json:"grossProfit"`
+	OperatingExpenses      []PnLLine    `json:"operatingExpenses"`
+	TotalOperatingExpenses float64      `json:"totalOperatingExpenses"`
+	NetProfit              float64      `json:"netProfit"`
+	NetMarginPct           float64      `json:"netMarginPct"`
+}
+
+// Calcula los totales y las utilidades del estado de resultados
+func NewPnLStatement(period ReportPeriod, revenue, costOfServices, operatingExpenses []PnLLine) PnLStatement {
+	s := PnLStatement{
+		Period:                 period,
+		Revenue:                nonNilLines(revenue),
+		TotalRevenue:           sumLines(revenue),
+		CostOfServices:         nonNilLines(costOfServices),
+		TotalCostOfServices:    sumLines(costOfServices),
+		OperatingExpenses:      nonNilLines(operatingExpenses),
+		TotalOperatingExpenses: sumLines(operatingExpenses),
+	}
+
+	s.GrossProfit = roundMoney(s.TotalRevenue - s.TotalCostOfServices)
+	s.NetProfit = roundMoney(s.GrossProfit - s.TotalOperatingExpenses)
+	if s.TotalRevenue != 0 {
+		s.NetMarginPct = roundMoney(s.NetProfit / s.TotalRevenue * 100)
+	}
+	return s
+}
+
+// Estado de resultados de un periodo de referencia y el cambio porcentual hacia el periodo reportado
+// Los cambios son nulos cuando el valor de referencia es cero
+type PnLComparison struct {
+	PnLStatement
+	RevenueChangePct     *float64 `json:"revenueChangePct"`
+	GrossProfitChangePct *float64 `json:"grossProfitChangePct"`
+	NetProfitChangePct   *float64 `json:"netProfitChangePct"`
+}
+
+func NewPnLComparison(current, base PnLStatement) PnLComparison {
+	return PnLComparison{
+		PnLStatement:         base,
+		RevenueChangePct:     ChangePct(current.TotalRevenue, base.TotalRevenue),
+		GrossProfitChangePct: ChangePct(current.GrossProfit, base.GrossProfit),
+		NetProfitChangePct:   ChangePct(current.NetProfit, base.NetProfit),
+	}
+}
+
+// Retorna el cambio porcentual de un valor respecto a su referencia, o nil si la referencia es cero
+func ChangePct(value, base float64) *float64 {
+	if base == 0 {
+		return nil
+	}
+
+	// Se divide entre el valor absoluto para que una mejora sobre una pérdida sea un cambio positivo
+	change := base
+	if change < 0 {
+		change = -change
+	}
+	change = roundMoney((value - base) / change * 100)
+	return &change
+}
+
+func sumLines(lines []PnLLine) float64 {
+	var total float64
+	for _, line := range lines {
+		total += line.Amount
+	}
+	return roundMoney(total)
+}
+
+func nonNilLines(lines []PnLLine) []PnLLine {
+	if lines == nil {
+		return []PnLLine{}
+	}
+	return lines
+}
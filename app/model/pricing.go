@@ -0,0 +1,88 @@
+package model
+
+import (
+	"math"
+	"time"
+	_ "time/tzdata"
+)
+
+type QuoteStatus string
+
+const (
+	QuoteStatusDraft      QuoteStatus = "draft"
+	QuoteStatusAccepted   QuoteStatus = "accepted"
+	QuoteStatusDeclined   QuoteStatus = "declined"
+	QuoteStatusSuperseded QuoteStatus = "superseded"
+	QuoteStatusExpired    QuoteStatus = "expired"
+)
+
+// Zona horaria del negocio, en la que se evalúan los recargos de fin de semana y fuera de horario
+var businessLocation, _ = time.LoadLocation(DefaultChartTimezone)
+
+// Datos de un traslado necesarios para calcular su precio
+type PricingInput struct {
+	DistanceKm  float64
+	WeightKg    float64
+	Helpers     int
+	MeetingDate time.Time
+}
+
+// Desglose del precio calculado para un traslado
+type PriceBreakdown struct {
+	BaseFee             float64 `json:"baseFee" gorm:"column:base_fee"`
+	DistanceFee         float64 `json:"distanceFee" gorm:"column:distance_fee"`
+	WeightFee           float64 `json:"weightFee" gorm:"column:weight_fee"`
+	HelperFee           float64 `json:"helperFee" gorm:"column:helper_fee"`
+	WeekendSurcharge    float64 `json:"weekendSurcharge" gorm:"column:weekend_surcharge"`
+	AfterHoursSurcharge float64 `json:"afterHoursSurcharge" gorm:"column:after_hours_surcharge"`
+	MinimumAdjustment   float64 `json:"minimumAdjustment" gorm:"column:minimum_adjustment"`
+	CalculatedTotal     float64 `json:"calculatedTotal" gorm:"column:calculated_total"`
+}
+
+// Calcula el precio de un traslado según la regla de su tipo de orden
+// Los recargos de fin de semana y fuera de horario son porcentajes sobre el subtotal, según la hora local del negocio
+// Si el total no alcanza el cobro mínimo se agrega la diferencia como ajuste
+func (r PricingRule) Calculate(input PricingInput) PriceBreakdown {
+	breakdown := PriceBreakdown{
+		BaseFee:     roundMoney(r.BaseFee),
+		DistanceFee: roundMoney(input.DistanceKm * r.PerKmRate),
+		WeightFee:   roundMoney(input.WeightKg * r.PerKgRate),
+		HelperFee:   roundMoney(float64(input.Helpers) * r.HelperFee),
+	}
+
+	subtotal := breakdown.BaseFee + breakdown.DistanceFee + breakdown.WeightFee + breakdown.HelperFee
+
+	// La fecha puede llegar con cualquier desplazamiento, por ejemplo en UTC desde el navegador
+	meetingDate := input.MeetingDate.In(businessLocation)
+
+	weekday := meetingDate.Weekday()
+	if weekday == time.Saturday || weekday == time.Sunday {
+		breakdown.WeekendSurcharge = roundMoney(subtotal * r.WeekendSurchargePct / 100)
+	}
+
+	hour := meetingDate.Hour()
+	if hour < r.WorkdayStartHour || hour >= r.WorkdayEndHour {
+		breakdown.AfterHoursSurcharge = roundMoney(subtotal * r.AfterHoursSurchargePct / 100)
+	}
+
+	total := subtotal + breakdown.WeekendSurcharge + breakdown.AfterHoursSurcharge
+	if total < r.MinimumCharge {
+		breakdown.MinimumAdjustment = roundMoney(r.MinimumCharge - total)
+		total = r.MinimumCharge
+	}
+
+	breakdown.CalculatedTotal = roundMoney(total)
+	return breakdown
+}
+
+// Precio final de la cotización, considerando el ajuste manual si existe
+func (q *Quote) FinalAmount() float64 {
+	if q.OverrideAmount != nil {
+		return *q.OverrideAmount
+	}
+	return q.CalculatedTotal
+}
+
+func roundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,24 @@
+package model
+
+// Ingresos, costos directos y margen de una orden o vehículo
+type Profitability struct {
+	Revenue   float64 `json:"revenue" export:"Ingresos"`
+	Costs     float64 `json:"costs" export:"Costos directos"`
+	Margin    float64 `json:"margin" export:"Margen"`
+	MarginPct float64 `json:"marginPct" export:"Margen (%)"`
+}
+
+// Calcula el margen y su porcentaje sobre los ingresos
+// El porcentaje es cero cuando no hay ingresos
+func NewProfitability(revenue, costs float64) Profitability {
+	p := Profitability{
+		Revenue: roundMoney(revenue),
+		Costs:   roundMoney(costs),
+		Margin:  roundMoney(revenue - costs),
+	}
+
+	if revenue != 0 {
+		p.MarginPct = roundMoney((revenue - costs) / revenue * 100)
+	}
+	return p
+}
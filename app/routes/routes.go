@@ -3,6 +3,7 @@ package routes
 import (
 	"dapa/app/handlers"
 	"dapa/app/middlewares"
+	"dapa/app/model"
 
 	"github.com/gin-gonic/gin"
 )
@@ -16,6 +17,8 @@ func SetupRoutes(router *gin.Engine) {
 	api.POST("/login", handlers.LoginHandler)
 	api.POST("/auth/forgot", handlers.ForgotPasswordHandler)
 	api.POST("/auth/reset", handlers.ResetPasswordHandler)
+	api.POST("/auth/refresh", handlers.RefreshTokenHandler)
+	api.POST("/auth/logout", handlers.LogoutHandler)
 
 	// Formulario para clientes
 	api.GET("form/questions", handlers.GetQuestionsHandler)
@@ -24,101 +27,173 @@ func SetupRoutes(router *gin.Engine) {
 	// Tracking de órdenes
 	api.GET("/orders/track", handlers.OrderTrackingHandler)
 
+	// Descarga de archivos con enlaces firmados
+	api.GET("/files/*key", handlers.DownloadFileHandler)
+
+	// Respuesta del cliente a su cotización
+	api.GET("/quotes/review", handlers.ReviewQuoteHandler)
+	api.POST("/quotes/review/accept", handlers.AcceptQuoteByTokenHandler)
+	api.POST("/quotes/review/decline", handlers.DeclineQuoteByTokenHandler)
+
 	// Rutas que requieren que el usuario se encuentra autenticado
 	protected := api.Group("")
 	protected.Use(middlewares.AuthMiddleware())
 	{
+		// Cuenta del usuario autenticado
+		protected.GET("/me", handlers.GetAccountHandler)
+		protected.PATCH("/me", handlers.UpdateAccountHandler)
+		protected.POST("/me/password", handlers.ChangePasswordHandler)
+
 		// ENTIDADES: Usuarios
 		protected.PUT("/users/:id", handlers.UpdateUserHandler)
 		protected.GET("/users/:id", handlers.GetUserHandler)
 
 		// ENTIDADES: órdenes
 		protected.GET("/orders/:id/token", handlers.GetOrderTokenHandler)  // MÁS ESPECÍFICO PRIMERO
+		protected.GET("/orders/:id/history", handlers.GetOrderHistoryHandler)
+		protected.GET("/orders/:id/items", handlers.GetOrderItemsHandler)
+		protected.GET("/orders/:id/stops", handlers.GetOrderStopsHandler)
+		protected.GET("/orders/:id/payments", handlers.GetOrderPaymentsHandler)
+		protected.GET("/orders/:id/proof-of-delivery", handlers.GetProofOfDeliveryHandler)
 		protected.GET("/orders/:id", handlers.GetOrderHandler)             // MENOS ESPECÍFICO DESPUÉS
 		protected.GET("/orders", handlers.GetOrdersHandler)
 		protected.PATCH("/orders/:id/status", handlers.ChangeOrderStatusHandler)
+		protected.PATCH("/orders/:id/cancel", handlers.CancelOrderHandler)
+		protected.PATCH("/orders/:id/failure", handlers.FailOrderHandler)
+		protected.PATCH("/orders/:id/stops/:position/status", handlers.UpdateStopStatusHandler)
+		protected.POST("/orders/:id/payments", handlers.CreatePaymentHandler)
+		protected.POST("/orders/:id/proof-of-delivery", handlers.SubmitProofOfDeliveryHandler)
+
+		// ARCHIVOS: adjuntos de órdenes, gastos, vehículos y usuarios
+		protected.GET("/attachments/:owner/:ownerId", handlers.GetAttachmentsHandler)
+		protected.POST("/attachments/:owner/:ownerId", handlers.CreateAttachmentHandler)
 
 		// FORMULARIO: respuestas
 		protected.GET("form/submissions/:id", handlers.GetSubmissionHandler)
 	}
 
-	// Rutas que requieren que el usuario posea el rol de admin
-	admin := protected.Group("")
-	admin.Use(middlewares.RoleRequired("admin"))
+	// Rutas que requieren que el rol del usuario posea el permiso indicado
+	can := middlewares.RequirePermission
 	{
+		// SEGURIDAD: Roles y permisos
+		protected.GET("/permissions", can(model.PermissionRolesManage), handlers.GetPermissionsHandler)
+		protected.GET("/roles", can(model.PermissionRolesManage), handlers.GetRolesHandler)
+		protected.POST("/roles", can(model.PermissionRolesManage), handlers.CreateRoleHandler)
+		protected.PUT("/roles/:id", can(model.PermissionRolesManage), handlers.UpdateRoleHandler)
+		protected.DELETE("/roles/:id", can(model.PermissionRolesManage), handlers.DeleteRoleHandler)
+
 		// ENTIDADES: Usuarios
-		admin.POST("/users", handlers.RegisterHandler)
-		admin.GET("/users", handlers.GetUsersHandler)
-		admin.DELETE("/users/:id", handlers.DeleteUserHandler)
+		protected.POST("/users", can(model.PermissionUsersManage), handlers.RegisterHandler)
+		protected.GET("/users", can(model.PermissionUsersRead), handlers.GetUsersHandler)
+		protected.DELETE("/users/:id", can(model.PermissionUsersManage), handlers.DeleteUserHandler)
+		protected.POST("/users/:id/unlock", can(model.PermissionUsersManage), handlers.UnlockUserHandler)
+		protected.GET("/login-attempts", can(model.PermissionUsersManage), handlers.GetLoginAttemptsHandler)
 
 		// ENTIDADES: Vehículos
-		admin.GET("/vehicles", handlers.GetVehiclesHandler)
-		admin.POST("/vehicles", handlers.CreateVehicleHandler)
-		admin.GET("/vehicles/:id", handlers.GetVehicleHandler)
-		admin.PUT("/vehicles/:id", handlers.UpdateVehicleHandler)
-		admin.DELETE("/vehicles/:id", handlers.DeleteVehicleHandler)
+		protected.GET("/vehicles", can(model.PermissionVehiclesManage), handlers.GetVehiclesHandler)
+		protected.POST("/vehicles", can(model.PermissionVehiclesManage), handlers.CreateVehicleHandler)
+		protected.GET("/vehicles/:id", can(model.PermissionVehiclesManage), handlers.GetVehicleHandler)
+		protected.PUT("/vehicles/:id", can(model.PermissionVehiclesManage), handlers.UpdateVehicleHandler)
+		protected.DELETE("/vehicles/:id", can(model.PermissionVehiclesManage), handlers.DeleteVehicleHandler)
 
 		// ENTIDADES: Órdenes
-		admin.POST("/orders", handlers.CreateOrderHandler)
-		admin.PUT("/orders/:id", handlers.UpdateOrderHandler)
-		admin.PATCH("/orders/:id/assign", handlers.AssignOrderHandler)
+		protected.POST("/orders", can(model.PermissionOrdersManage), handlers.CreateOrderHandler)
+		protected.PUT("/orders/:id", can(model.PermissionOrdersManage), handlers.UpdateOrderHandler)
+		protected.POST("/orders/auto-assign", can(model.PermissionOrdersAssign), handlers.AutoAssignOrdersHandler)
+		protected.GET("/orders/:id/assignment-suggestions", can(model.PermissionOrdersAssign), handlers.GetAssignmentSuggestionsHandler)
+		protected.PATCH("/orders/:id/assign", can(model.PermissionOrdersAssign), handlers.AssignOrderHandler)
+		protected.POST("/orders/:id/items", can(model.PermissionOrdersManage), handlers.CreateOrderItemHandler)
+		protected.PUT("/orders/:id/items/:itemId", can(model.PermissionOrdersManage), handlers.UpdateOrderItemHandler)
+		protected.DELETE("/orders/:id/items/:itemId", can(model.PermissionOrdersManage), handlers.DeleteOrderItemHandler)
+		protected.PATCH("/orders/:id/reschedule", can(model.PermissionOrdersManage), handlers.RescheduleOrderHandler)
+		protected.POST("/orders/:id/payments/:paymentId/void", can(model.PermissionPaymentsVoid), handlers.VoidPaymentHandler)
+		protected.GET("/orders/:id/invoice.pdf", can(model.PermissionInvoicesRead), handlers.GetOrderInvoiceHandler)
+
+		// ARCHIVOS: adjuntos
+		protected.DELETE("/attachments/:id", can(model.PermissionAttachmentsDelete), handlers.DeleteAttachmentHandler)
+
+		// ENTIDADES: Clientes
+		protected.GET("/customers", can(model.PermissionCustomersManage), handlers.GetCustomersHandler)
+		protected.POST("/customers", can(model.PermissionCustomersManage), handlers.CreateCustomerHandler)
+		protected.GET("/customers/:id", can(model.PermissionCustomersManage), handlers.GetCustomerHandler)
+		protected.PUT("/customers/:id", can(model.PermissionCustomersManage), handlers.UpdateCustomerHandler)
+		protected.DELETE("/customers/:id", can(model.PermissionCustomersManage), handlers.DeleteCustomerHandler)
+		protected.GET("/customers/:id/orders", can(model.PermissionCustomersManage), handlers.GetCustomerOrdersHandler)
+
+		// COTIZACIONES: Tarifas y cotizaciones de envíos
+		protected.GET("/pricing-rules", can(model.PermissionQuotesManage), handlers.GetPricingRulesHandler)
+		protected.POST("/pricing-rules", can(model.PermissionQuotesManage), handlers.CreatePricingRuleHandler)
+		protected.PUT("/pricing-rules/:id", can(model.PermissionQuotesManage), handlers.UpdatePricingRuleHandler)
+		protected.POST("/quotes/preview", can(model.PermissionQuotesManage), handlers.PreviewQuoteHandler)
+		protected.POST("/quotes", can(model.PermissionQuotesManage), handlers.CreateQuoteHandler)
+		protected.GET("/quotes/:id", can(model.PermissionQuotesManage), handlers.GetQuoteHandler)
+		protected.GET("/quotes/:id/token", can(model.PermissionQuotesManage), handlers.GetQuoteTokenHandler)
+		protected.PATCH("/quotes/:id/override", can(model.PermissionQuotesManage), handlers.OverrideQuoteHandler)
+		protected.PATCH("/quotes/:id/accept", can(model.PermissionQuotesManage), handlers.AcceptQuoteHandler)
+		protected.GET("form/submissions/:id/quotes", can(model.PermissionQuotesManage), handlers.GetSubmissionQuotesHandler)
 
 		// FORMULARIO: Tipos de pregunta
-		admin.GET("form/question-types", handlers.GetQuestionTypesHandler)
+		protected.GET("form/question-types", can(model.PermissionFormManage), handlers.GetQuestionTypesHandler)
 
 		// FORMULARIO: Preguntas
-		admin.POST("form/questions", handlers.CreateQuestionHandler)
-		admin.PUT("form/questions/:id", handlers.UpdateQuestionHandler)
-		admin.DELETE("form/questions/:id", handlers.DeleteQuestionHandler)
-		admin.PATCH("form/questions/reorder", handlers.ReorderQuestionsHandler)
-		admin.PATCH("form/questions/:id/active", handlers.ToggleQuestionActiveHandler)
-		admin.PATCH("form/questions/:id/required", handlers.ToggleQuestionRequiredHandler)
+		protected.POST("form/questions", can(model.PermissionFormManage), handlers.CreateQuestionHandler)
+		protected.PUT("form/questions/:id", can(model.PermissionFormManage), handlers.UpdateQuestionHandler)
+		protected.DELETE("form/questions/:id", can(model.PermissionFormManage), handlers.DeleteQuestionHandler)
+		protected.PATCH("form/questions/reorder", can(model.PermissionFormManage), handlers.ReorderQuestionsHandler)
+		protected.PATCH("form/questions/:id/active", can(model.PermissionFormManage), handlers.ToggleQuestionActiveHandler)
+		protected.PATCH("form/questions/:id/required", can(model.PermissionFormManage), handlers.ToggleQuestionRequiredHandler)
 
 		// FORMULARIO: Opciones de pregunta
-		admin.POST("form/questions/:questionId/options", handlers.CreateQuestionOptionHandler)
+		protected.POST("form/questions/:questionId/options", can(model.PermissionFormManage), handlers.CreateQuestionOptionHandler)
 
-		// FORMULARIO: Envíos (admin puede ver y actualizar estado)
-		admin.GET("form/submissions", handlers.GetSubmissionsHandler)
-		admin.PATCH("form/submissions/:id/status", handlers.UpdateSubmissionStatusHandler)
+		// FORMULARIO: Envíos (ver y actualizar estado)
+		protected.GET("form/submissions", can(model.PermissionSubmissionsManage), handlers.GetSubmissionsHandler)
+		protected.PATCH("form/submissions/:id/status", can(model.PermissionSubmissionsManage), handlers.UpdateSubmissionStatusHandler)
 
 		// REPORTE: Financiero 
-		admin.GET("/reports/financial", handlers.FinancialReport)
-		admin.GET("/reports/financial/date", handlers.FinancialReportByDate)
-		admin.GET("/reports/drivers", handlers.DriversReport)
-		admin.GET("/reports/income", handlers.TotalIncomeReport)
-		admin.GET("/reports/financial-control-income", handlers.FinancialControlIncome)
-		admin.GET("/reports/financial-control-spending", handlers.FinancialControlSpending)
+		protected.GET("/reports/financial", can(model.PermissionReportsFinancial), handlers.FinancialReport)
+		protected.GET("/reports/financial/date", can(model.PermissionReportsFinancial), handlers.FinancialReportByDate)
+		protected.GET("/reports/drivers", can(model.PermissionReportsOperations), handlers.DriversReport)
+		protected.GET("/reports/income", can(model.PermissionReportsFinancial), handlers.TotalIncomeReport)
+		protected.GET("/reports/financial-control-income", can(model.PermissionReportsFinancial), handlers.FinancialControlIncome)
+		protected.GET("/reports/financial-control-spending", can(model.PermissionReportsFinancial), handlers.FinancialControlSpending)
 		
 		// KPIs
-		admin.GET("/kpi/current", handlers.GetCurrentKPIs)
-		admin.GET("/kpi/goals", handlers.GetPerformanceGoal)
-		admin.PUT("/kpi/goals", handlers.UpsertPerformanceGoal)
+		protected.GET("/kpi", can(model.PermissionKPIRead), handlers.GetKPIs)
+		protected.GET("/kpi/current", can(model.PermissionKPIRead), handlers.GetCurrentKPIs)
+		protected.GET("/kpi/goals", can(model.PermissionKPIRead), handlers.GetPerformanceGoal)
+		protected.PUT("/kpi/goals", can(model.PermissionKPIGoals), handlers.UpsertPerformanceGoal)
+		protected.GET("/kpi/goals/history", can(model.PermissionKPIRead), handlers.GetPerformanceGoalHistory)
+		protected.GET("/kpi/goals/progress", can(model.PermissionKPIRead), handlers.GetGoalProgress)
 
 		// REPORTE: Gráficas desempeño
-		admin.GET("/reports/completed-quotations", handlers.CompletedQuotationsChart)
-		admin.GET("/reports/quotations-status", handlers.QuotationsStatusChart)
-		admin.GET("/reports/drivers-performance", handlers.DriversPerformanceChart)
-		admin.GET("/reports/drivers-participation", handlers.DriversTripParticipationChart)
+		protected.GET("/reports/completed-quotations", can(model.PermissionReportsOperations), handlers.CompletedQuotationsChart)
+		protected.GET("/reports/quotations-status", can(model.PermissionReportsOperations), handlers.QuotationsStatusChart)
+		protected.GET("/reports/drivers-performance", can(model.PermissionReportsOperations), handlers.DriversPerformanceChart)
+		protected.GET("/reports/drivers-participation", can(model.PermissionReportsOperations), handlers.DriversTripParticipationChart)
 
 		// REPORTE: Gráficas financieras
-		admin.GET("/reports/financial/monthly", handlers.IncomePerMonth)
-		admin.GET("/reports/expenses/grouped", handlers.ExpensesPerType)
-		admin.GET("/reports/expenses/monthly", handlers.ExpensesPerMonth)
-		admin.GET("/reports/financial/order-type", handlers.OrderTypeDistribution)
+		protected.GET("/reports/financial/monthly", can(model.PermissionReportsFinancial), handlers.IncomePerMonth)
+		protected.GET("/reports/profitability/orders", can(model.PermissionReportsFinancial), handlers.OrderProfitabilityReport)
+		protected.GET("/reports/profitability/vehicles", can(model.PermissionReportsFinancial), handlers.VehicleProfitabilityReport)
+		protected.GET("/reports/pnl", can(model.PermissionReportsFinancial), handlers.ProfitAndLossReport)
+		protected.GET("/reports/expenses/grouped", can(model.PermissionReportsFinancial), handlers.ExpensesPerType)
+		protected.GET("/reports/expenses/monthly", can(model.PermissionReportsFinancial), handlers.ExpensesPerMonth)
+		protected.GET("/reports/financial/order-type", can(model.PermissionReportsFinancial), handlers.OrderTypeDistribution)
 
 		// ENTIDADES: Tipos de Gasto
-		admin.GET("/expense-types", handlers.GetExpenseTypes)
-		admin.POST("/expense-types", handlers.CreateExpenseType)
-		admin.GET("/expense-types/:id", handlers.GetExpenseType)
-		admin.PUT("/expense-types/:id", handlers.UpdateExpenseType)
-		admin.DELETE("/expense-types/:id", handlers.DeleteExpenseType)
+		protected.GET("/expense-types", can(model.PermissionExpensesManage), handlers.GetExpenseTypes)
+		protected.POST("/expense-types", can(model.PermissionExpensesManage), handlers.CreateExpenseType)
+		protected.GET("/expense-types/:id", can(model.PermissionExpensesManage), handlers.GetExpenseType)
+		protected.PUT("/expense-types/:id", can(model.PermissionExpensesManage), handlers.UpdateExpenseType)
+		protected.DELETE("/expense-types/:id", can(model.PermissionExpensesManage), handlers.DeleteExpenseType)
 
 		// ENTIDADES: Gastos
-		admin.GET("/expenses", handlers.GetExpenses)
-		admin.POST("/expenses", handlers.CreateExpense)
-		admin.GET("/expenses/:id", handlers.GetExpense)
-		admin.PUT("/expenses/:id", handlers.UpdateExpense)
-		admin.DELETE("/expenses/:id", handlers.DeleteExpense)
+		protected.GET("/expenses", can(model.PermissionExpensesManage), handlers.GetExpenses)
+		protected.POST("/expenses", can(model.PermissionExpensesManage), handlers.CreateExpense)
+		protected.GET("/expenses/:id", can(model.PermissionExpensesManage), handlers.GetExpense)
+		protected.PUT("/expenses/:id", can(model.PermissionExpensesManage), handlers.UpdateExpense)
+		protected.DELETE("/expenses/:id", can(model.PermissionExpensesManage), handlers.DeleteExpense)
 
 	}
 }
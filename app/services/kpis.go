@@ -0,0 +1,75 @@
+package services
+
+import (
+	"dapa/app/model"
+	"dapa/database"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Calcula los indicadores entre las fechas indicadas, el fin no se incluye
+// Si se indica un piloto solo se consideran sus órdenes, los pagos de estas y los gastos que tiene asignados
+func ComputeKPIs(from, to time.Time, userID *uint) (model.KPIs, error) {
+	var kpis model.KPIs
+
+	orders := func() *gorm.DB {
+		db := database.DB.Model(&model.Order{}).Where("orders.date >= ? AND orders.date < ?", from, to)
+		if userID != nil {
+			db = db.Where("orders.user_id = ?", *userID)
+		}
+		return db
+	}
+
+	// Se considera el dinero cobrado durante el periodo, no el total de las órdenes entregadas
+	income := database.DB.Model(&model.Payment{}).Scopes(model.ActivePayments).Where("payments.paid_at >= ? AND payments.paid_at < ?", from, to)
+	if userID != nil {
+		income = income.Joins("JOIN orders ON orders.id = payments.order_id").Where("orders.user_id = ?", *userID)
+	}
+	if err := income.Select("COALESCE(SUM(payments.amount), 0)").Row().Scan(&kpis.TotalIncome); err != nil {
+		return kpis, err
+	}
+
+	expenses := database.DB.Model(&model.Expense{}).Where("date >= ? AND date < ?", from, to)
+	if userID != nil {
+		expenses = expenses.Where("user_id = ?", *userID)
+	}
+	if err := expenses.Select("COALESCE(SUM(amount), 0)").Row().Scan(&kpis.TotalExpenses); err != nil {
+		return kpis, err
+	}
+	kpis.Utility = kpis.TotalIncome - kpis.TotalExpenses
+
+	if err := orders().Where("status <> ?", model.OrderStatusCancelled).Count(&kpis.Orders).Error; err != nil {
+		return kpis, err
+	}
+
+	delivered := func() *gorm.DB { return orders().Where("status = ?", model.OrderStatusDelivered) }
+	if err := delivered().Select("COALESCE(AVG(total_amount), 0)").Row().Scan(&kpis.AveragePerOrder); err != nil {
+		return kpis, err
+	}
+
+	if err := delivered().Count(&kpis.CompletedTrips).Error; err != nil {
+		return kpis, err
+	}
+	kpis.DeliveredOrders = kpis.CompletedTrips
+
+	var employeeCount int64
+	if err := delivered().Where("user_id IS NOT NULL").Distinct("user_id").Count(&employeeCount).Error; err != nil {
+		return kpis, err
+	}
+	if employeeCount > 0 {
+		kpis.AverageOrdersPerEmployee = float64(int(float64(kpis.CompletedTrips)/float64(employeeCount) + 0.5))
+	}
+
+	// Las órdenes canceladas no cuentan, las fallidas y ausencias del cliente sí
+	var unfulfilledOrders int64
+	unfulfilled := []model.OrderStatus{model.OrderStatusPending, model.OrderStatusFailed, model.OrderStatusNoShow}
+	if err := orders().Where("status IN ?", unfulfilled).Count(&unfulfilledOrders).Error; err != nil {
+		return kpis, err
+	}
+	if kpis.CompletedTrips+unfulfilledOrders > 0 {
+		kpis.FulfillmentRate = (float64(kpis.CompletedTrips) / float64(kpis.CompletedTrips+unfulfilledOrders)) * 100
+	}
+
+	return kpis, nil
+}
@@ -0,0 +1,121 @@
+package test
+
+import (
+	"bytes"
+	"dapa/app/handlers"
+	"dapa/app/model"
+	"dapa/app/utils"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+)
+
+func sendAccount(claims *model.EmployeeClaims, method, path string, body any, handler gin.HandlerFunc) *httptest.ResponseRecorder {
+	payload, _ := json.Marshal(body)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("claims", claims)
+	c.Request, _ = http.NewRequest(method, path, bytes.NewReader(payload))
+	c.Request.Header.Set("Content-Type", "application/json")
+	handler(c)
+	return w
+}
+
+func TestAccount_GetAndUpdateOwnProfile(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupSessionTestContext(t)
+	db.AutoMigrate(&model.Role{}, &model.RolePermission{})
+	seedRoles(db)
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterValidation("phone", utils.PhoneValidator)
+	}
+
+	claims := &model.EmployeeClaims{UserID: 1, Role: "driver"}
+	w := sendAccount(claims, "GET", "/me", nil, handlers.GetAccountHandler)
+
+	var resp struct {
+		Data map[string]any `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "piloto@dapa.com", resp.Data["email"])
+	assert.Equal(t, "", resp.Data["password"])
+	assert.Equal(t, []any{"orders:read:own", "orders:progress"}, resp.Data["permissions"])
+
+	phone := "55551234"
+	body := map[string]any{"phone": phone, "email": "otro@dapa.com", "role": "admin"}
+	w = sendAccount(claims, "PATCH", "/me", body, handlers.UpdateAccountHandler)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var user model.User
+	db.First(&user, 1)
+	assert.Equal(t, phone, user.Phone)
+	assert.Equal(t, "piloto@dapa.com", user.Email)
+	assert.Equal(t, "driver", user.Role)
+	assert.NotEmpty(t, user.PasswordHash)
+
+	w = sendAccount(claims, "PATCH", "/me", map[string]any{"name": ""}, handlers.UpdateAccountHandler)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestChangePassword_RequiresCurrentAndRevokesOtherSessions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupSessionTestContext(t)
+
+	current, _ := login(t)
+	other, otherRefresh := login(t)
+
+	claims, err := utils.ValidateToken(current)
+	assert.NoError(t, err)
+
+	wrong := model.ChangePasswordDTO{CurrentPassword: "Incorrecta123!", NewPassword: "Nueva12345!"}
+	assert.Equal(t, http.StatusForbidden, sendAccount(claims, "POST", "/me/password", wrong, handlers.ChangePasswordHandler).Code)
+
+	change := model.ChangePasswordDTO{CurrentPassword: "Secreta123!", NewPassword: "Nueva12345!"}
+	assert.Equal(t, http.StatusOK, sendAccount(claims, "POST", "/me/password", change, handlers.ChangePasswordHandler).Code)
+
+	assert.Equal(t, http.StatusOK, authenticatedStatus(current))
+	assert.Equal(t, http.StatusUnauthorized, authenticatedStatus(other))
+	assert.Equal(t, http.StatusUnauthorized, refresh(otherRefresh).Code)
+
+	var user model.User
+	db.First(&user, 1)
+	assert.True(t, utils.CheckPassword("Nueva12345!", user.PasswordHash))
+}
+
+func TestChangePassword_CountsFailuresAndRespectsLockout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupSessionTestContext(t)
+	claims := &model.EmployeeClaims{UserID: 1, Role: "driver"}
+
+	wrong := model.ChangePasswordDTO{CurrentPassword: "Incorrecta123!", NewPassword: "Nueva12345!"}
+	assert.Equal(t, http.StatusForbidden, sendAccount(claims, "POST", "/me/password", wrong, handlers.ChangePasswordHandler).Code)
+
+	var user model.User
+	db.First(&user, 1)
+	assert.Equal(t, 1, user.FailedLoginAttempts)
+
+	change := model.ChangePasswordDTO{CurrentPassword: "Secreta123!", NewPassword: "Nueva12345!"}
+	assert.Equal(t, http.StatusOK, sendAccount(claims, "POST", "/me/password", change, handlers.ChangePasswordHandler).Code)
+
+	db.First(&user, 1)
+	assert.Equal(t, 0, user.FailedLoginAttempts)
+
+	// La cuenta bloqueada rechaza también la contraseña correcta
+	db.Model(&model.User{}).Where("id = ?", 1).Update("failed_login_attempts", 9)
+	assert.Equal(t, http.StatusForbidden, sendAccount(claims, "POST", "/me/password", wrong, handlers.ChangePasswordHandler).Code)
+
+	back := model.ChangePasswordDTO{CurrentPassword: "Nueva12345!", NewPassword: "Secreta123!"}
+	w := sendAccount(claims, "POST", "/me/password", back, handlers.ChangePasswordHandler)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	db.First(&user, 1)
+	assert.True(t, utils.CheckPassword("Nueva12345!", user.PasswordHash))
+}
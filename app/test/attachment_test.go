@@ -0,0 +1,171 @@
+package test
+
+import (
+	"bytes"
+	"dapa/app/handlers"
+	"dapa/app/jobs"
+	"dapa/app/model"
+	"dapa/storage"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func createAttachment(owner, ownerID string, content []byte, claims *model.EmployeeClaims) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("category", "registration")
+	part, _ := form.CreateFormFile("file", "tarjeta.png")
+	part.Write(content)
+	form.Close()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("claims", claims)
+	c.Request, _ = http.NewRequest("POST", "/attachments/"+owner+"/"+ownerID, &body)
+	c.Request.Header.Set("Content-Type", form.FormDataContentType())
+	c.Params = gin.Params{{Key: "owner", Value: owner}, {Key: "ownerId", Value: ownerID}}
+
+	handlers.CreateAttachmentHandler(c)
+	return w
+}
+
+func downloadFile(rawURL string) *httptest.ResponseRecorder {
+	parsed, _ := url.Parse(rawURL)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", parsed.RequestURI(), nil)
+	c.Params = gin.Params{{Key: "key", Value: strings.TrimPrefix(parsed.Path, "/api/files")}}
+
+	handlers.DownloadFileHandler(c)
+	return w
+}
+
+func TestCreateAttachment_DownloadsWithSignedURL(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupOrderTestContext()
+	storage.Files = storage.NewLocalStorage(t.TempDir(), "http://localhost:8080/api/files", "secret")
+
+	db.Create(&model.Vehicle{Brand: "Isuzu", Model: "NPR", LicensePlate: "C123ABC"})
+
+	w := createAttachment("vehicles", "1", pngImage, &model.EmployeeClaims{UserID: 7, Role: "driver"})
+	assert.NotEqual(t, http.StatusCreated, w.Code)
+
+	w = createAttachment("vehicles", "1", []byte("#!/bin/sh"), &model.EmployeeClaims{UserID: 1, Role: "admin"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = createAttachment("vehicles", "1", pngImage, &model.EmployeeClaims{UserID: 1, Role: "admin"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var resp struct {
+		Data model.Attachment `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.Equal(t, "image/png", resp.Data.ContentType)
+
+	w = downloadFile(resp.Data.URL)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, pngImage, w.Body.Bytes())
+
+	w = downloadFile(strings.Replace(resp.Data.URL, "signature=", "signature=0", 1))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestCreateAttachment_RejectsLargeFiles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupOrderTestContext()
+	storage.Files = storage.NewLocalStorage(t.TempDir(), "http://localhost:8080/api/files", "secret")
+	t.Setenv("UPLOAD_MAX_SIZE_MB", "1")
+
+	db.Create(&model.Vehicle{Brand: "Isuzu", Model: "NPR", LicensePlate: "C123ABC"})
+
+	large := append(append([]byte{}, pngImage...), make([]byte, 3<<20)...)
+	w := createAttachment("vehicles", "1", large, &model.EmployeeClaims{UserID: 1, Role: "admin"})
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+}
+
+func TestDeleteVehicle_KeepsAttachments(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupOrderTestContext()
+	storage.Files = storage.NewLocalStorage(t.TempDir(), "http://localhost:8080/api/files", "secret")
+
+	db.Create(&model.Vehicle{Brand: "Isuzu", Model: "NPR", LicensePlate: "C123ABC"})
+
+	w := createAttachment("vehicles", "1", pngImage, &model.EmployeeClaims{UserID: 1, Role: "admin"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var attachment model.Attachment
+	db.First(&attachment)
+
+	w = httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("DELETE", "/vehicles/1", nil)
+	c.Params = gin.Params{{Key: "id", Value: "1"}}
+	handlers.DeleteVehicleHandler(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	assert.NoError(t, jobs.SweepOrphanAttachments(time.Now()))
+
+	var count int64
+	db.Model(&model.Attachment{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	content, err := storage.Files.Open(attachment.FileKey)
+	assert.NoError(t, err)
+	content.Close()
+}
+
+func TestSweepOrphanAttachments_DeletesMissingOwners(t *testing.T) {
+	db := setupOrderTestContext()
+	storage.Files = storage.NewLocalStorage(t.TempDir(), "http://localhost:8080/api/files", "secret")
+
+	db.Create(&model.Order{Status: model.OrderStatusPending, MeetingDate: time.Now()})
+	for _, attachment := range []model.Attachment{
+		{OwnerType: model.AttachmentOwnerOrder, OwnerID: 1, FileName: "a.png", FileKey: "orders/1/a.png", ContentType: "image/png"},
+		{OwnerType: model.AttachmentOwnerOrder, OwnerID: 2, FileName: "b.png", FileKey: "orders/2/b.png", ContentType: "image/png"},
+	} {
+		storage.Files.Save(attachment.FileKey, bytes.NewReader(pngImage), int64(len(pngImage)), attachment.ContentType)
+		db.Create(&attachment)
+	}
+
+	assert.NoError(t, jobs.SweepOrphanAttachments(time.Now()))
+
+	var keys []string
+	db.Model(&model.Attachment{}).Pluck("file_key", &keys)
+	assert.Equal(t, []string{"orders/1/a.png"}, keys)
+
+	_, err := storage.Files.Open("orders/2/b.png")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+func TestSweepOrphanAttachments_DeletesExpiredInactiveOwners(t *testing.T) {
+	db := setupOrderTestContext()
+	storage.Files = storage.NewLocalStorage(t.TempDir(), "http://localhost:8080/api/files", "secret")
+
+	now := time.Now()
+	recently := now.AddDate(0, -1, 0)
+	longAgo := now.AddDate(-2, 0, 0)
+	db.Create(&model.Vehicle{Brand: "Isuzu", Model: "NPR", LicensePlate: "C123ABC", DeletedAt: &recently})
+	db.Create(&model.Vehicle{Brand: "Hino", Model: "300", LicensePlate: "C456DEF", DeletedAt: &longAgo})
+	for _, attachment := range []model.Attachment{
+		{OwnerType: model.AttachmentOwnerVehicle, OwnerID: 1, FileName: "a.png", FileKey: "vehicles/1/a.png", ContentType: "image/png"},
+		{OwnerType: model.AttachmentOwnerVehicle, OwnerID: 2, FileName: "b.png", FileKey: "vehicles/2/b.png", ContentType: "image/png"},
+	} {
+		storage.Files.Save(attachment.FileKey, bytes.NewReader(pngImage), int64(len(pngImage)), attachment.ContentType)
+		db.Create(&attachment)
+	}
+
+	assert.NoError(t, jobs.SweepOrphanAttachments(now))
+
+	var keys []string
+	db.Model(&model.Attachment{}).Pluck("file_key", &keys)
+	assert.Equal(t, []string{"vehicles/1/a.png"}, keys)
+}
@@ -0,0 +1,93 @@
+package test
+
+import (
+	"dapa/app/handlers"
+	"dapa/app/model"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type chartResponse struct {
+	Data struct {
+		Series []struct {
+			Data []float64 `json:"data"`
+		} `json:"series"`
+		Categories []string `json:"categories"`
+	} `json:"data"`
+}
+
+func getChart(handler gin.HandlerFunc, target string) (*httptest.ResponseRecorder, chartResponse) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", target, nil)
+	handler(c)
+
+	var resp chartResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp
+}
+
+func TestNewChartRange_WeeksStartOnMonday(t *testing.T) {
+	location, _ := time.LoadLocation("America/Guatemala")
+	from := time.Date(2025, 3, 5, 0, 0, 0, 0, location)
+	to := time.Date(2025, 3, 17, 0, 0, 0, 0, location)
+
+	chartRange, err := model.NewChartRange(from, to, model.GranularityWeek)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2025-03-03", "2025-03-10", "2025-03-17"}, chartRange.Categories())
+
+	// Las 3:00 UTC del 10 de marzo todavía son el 9 de marzo en Guatemala
+	assert.Equal(t, 0, chartRange.Index(time.Date(2025, 3, 10, 3, 0, 0, 0, time.UTC)))
+	assert.Equal(t, 1, chartRange.DateIndex(time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, -1, chartRange.Index(time.Date(2025, 3, 18, 6, 0, 0, 0, time.UTC)))
+
+	_, err = model.NewChartRange(from, to, "year")
+	assert.Error(t, err)
+}
+
+func TestIncomePerMonth_ZeroFillsChronologicalBuckets(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupOrderTestContext()
+
+	db.Create(&model.Order{Type: "flete", Status: model.OrderStatusDelivered, TotalAmount: 900})
+	db.Create(&model.Payment{OrderID: 1, Amount: 300, Method: model.PaymentMethodCash, PaidAt: time.Date(2025, 4, 2, 0, 0, 0, 0, time.UTC)})
+	db.Create(&model.Payment{OrderID: 1, Amount: 200, Method: model.PaymentMethodCash, PaidAt: time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)})
+	db.Create(&model.Payment{OrderID: 1, Amount: 400, Method: model.PaymentMethodCash, PaidAt: time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC)})
+
+	w, resp := getChart(handlers.IncomePerMonth, "/reports/financial/monthly?from=2025-01-01&to=2025-04-30")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"January 2025", "February 2025", "March 2025", "April 2025"}, resp.Data.Categories)
+	assert.Equal(t, []float64{200, 0, 0, 300}, resp.Data.Series[0].Data)
+
+	w, resp = getChart(handlers.IncomePerMonth, "/reports/financial/monthly?from=2024-10-01&to=2025-06-30&granularity=quarter")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"2024-Q4", "2025-Q1", "2025-Q2"}, resp.Data.Categories)
+	assert.Equal(t, []float64{400, 200, 300}, resp.Data.Series[0].Data)
+
+	for _, query := range []string{"tz=Mars/Olympus", "granularity=hour", "from=2025-05-01&to=2025-04-01"} {
+		w, _ = getChart(handlers.IncomePerMonth, "/reports/financial/monthly?"+query)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestCompletedQuotationsChart_GroupsInTimezone(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupOrderTestContext()
+	db.AutoMigrate(&model.Submission{})
+
+	db.Create(&model.Submission{Status: model.FormStatusApproved, SubmittedAt: time.Date(2025, 3, 1, 3, 0, 0, 0, time.UTC)})
+	db.Create(&model.Submission{Status: model.FormStatusApproved, SubmittedAt: time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)})
+	db.Create(&model.Submission{Status: model.FormStatusPending, SubmittedAt: time.Date(2025, 3, 2, 9, 0, 0, 0, time.UTC)})
+
+	_, resp := getChart(handlers.CompletedQuotationsChart, "/reports/completed-quotations?from=2025-02-01&to=2025-03-31")
+	assert.Equal(t, []float64{1, 1}, resp.Data.Series[0].Data)
+
+	_, resp = getChart(handlers.CompletedQuotationsChart, "/reports/completed-quotations?from=2025-02-01&to=2025-03-31&tz=UTC")
+	assert.Equal(t, []float64{0, 2}, resp.Data.Series[0].Data)
+}
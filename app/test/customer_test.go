@@ -0,0 +1,80 @@
+package test
+
+import (
+	"bytes"
+	"dapa/app/handlers"
+	"dapa/app/model"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func createCustomer(dto model.CustomerDTO) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(dto)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("claims", &model.EmployeeClaims{UserID: 1, Role: "admin"})
+	c.Request, _ = http.NewRequest("POST", "/customers", bytes.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	handlers.CreateCustomerHandler(c)
+	return w
+}
+
+func TestCreateCustomer_RejectsDuplicatePhone(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupOrderTestContext()
+
+	w := createCustomer(model.CustomerDTO{Name: "Ana López", Phone: "5555-1234"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = createCustomer(model.CustomerDTO{Name: "Ana", Phone: "5555 1234"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestAcceptQuoteByToken_LinksExistingCustomer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupQuoteTestContext()
+
+	createCustomer(model.CustomerDTO{Name: "Ana López", Phone: "5555-1234"})
+	customerID := uint(1)
+	db.Create(&model.Order{CustomerID: &customerID, Status: model.OrderStatusDelivered, MeetingDate: time.Now()})
+
+	db.Create(&model.Submission{Status: model.FormStatusPending})
+	db.Create(&model.Quote{SubmissionID: 1, Version: 1, Status: model.QuoteStatusDraft, ClientName: "Ana", ClientPhone: "55551234", OrderType: "mudanza", MeetingDate: time.Now(), Total: 400})
+	db.Create(&model.QuoteToken{QuoteID: 1, Token: "abc", Expiry: time.Now().Add(time.Hour)})
+
+	body, _ := json.Marshal(model.QuoteTokenDTO{Token: "abc"})
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/quotes/review/accept", bytes.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	handlers.AcceptQuoteByTokenHandler(c)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var customers int64
+	db.Model(&model.Customer{}).Count(&customers)
+	assert.Equal(t, int64(1), customers)
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/customers/1/orders", nil)
+	c.Params = gin.Params{{Key: "id", Value: "1"}}
+	handlers.GetCustomerOrdersHandler(c)
+
+	var resp struct {
+		Data []model.Order `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.Len(t, resp.Data, 2)
+
+	var submission model.Submission
+	db.First(&submission, 1)
+	assert.Equal(t, uint(1), *submission.CustomerID)
+}
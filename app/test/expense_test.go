@@ -0,0 +1,58 @@
+package test
+
+import (
+	"bytes"
+	"dapa/app/handlers"
+	"dapa/app/model"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func createExpense(dto model.ExpenseDTO) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(dto)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/expenses", bytes.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	handlers.CreateExpense(c)
+	return w
+}
+
+func TestCreateExpense_ValidatesAndFiltersByOrder(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupOrderTestContext()
+
+	db.Create(&model.ExpenseType{Type: "Combustible"})
+	db.Create(&model.Order{Status: model.OrderStatusAssigned, MeetingDate: time.Now()})
+
+	missing := uint(5)
+	w := createExpense(model.ExpenseDTO{Date: time.Now(), TypeID: 1, Description: "Diésel", Amount: 250, OrderID: &missing})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Order 5 doesn't exist")
+
+	orderID := uint(1)
+	w = createExpense(model.ExpenseDTO{Date: time.Now(), TypeID: 1, Description: "Diésel", Amount: 250, OrderID: &orderID})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = createExpense(model.ExpenseDTO{Date: time.Now(), TypeID: 1, Description: "Peaje", Amount: 20})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/expenses?orderId=1", nil)
+	handlers.GetExpenses(c)
+
+	var resp struct {
+		Data []model.Expense `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.Len(t, resp.Data, 1)
+	assert.Equal(t, 250.0, resp.Data[0].Amount)
+}
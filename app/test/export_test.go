@@ -0,0 +1,67 @@
+package test
+
+import (
+	"archive/zip"
+	"bytes"
+	"dapa/app/handlers"
+	"dapa/app/model"
+	"encoding/csv"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func exportExpenses(target string, accept string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", target, nil)
+	if accept != "" {
+		c.Request.Header.Set("Accept", accept)
+	}
+	handlers.GetExpenses(c)
+	return w
+}
+
+func TestGetExpenses_ExportsSpreadsheets(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupOrderTestContext()
+
+	order := uint(3)
+	db.Create(&model.ExpenseType{Type: "Combustible"})
+	db.Create(&model.Expense{Date: time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC), TypeID: 1, Description: "=HYPERLINK(\"x\")", Amount: 1250.5, OrderID: &order})
+
+	w := exportExpenses("/expenses?format=csv", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "egresos-")
+
+	records, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(w.Body.String(), "\xEF\xBB\xBF"))).ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ID", "Fecha", "Tipo de gasto", "Empleado temporal", "Descripción", "Monto", "Orden", "Vehículo", "Empleado"}, records[0])
+	assert.Equal(t, []string{"1", "2025-03-10", "Combustible", "No", "'=HYPERLINK(\"x\")", "1250.50", "3", "", ""}, records[1])
+
+	w = exportExpenses("/expenses", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	assert.NoError(t, err)
+
+	var sheet string
+	for _, file := range archive.File {
+		if file.Name == "xl/worksheets/sheet1.xml" {
+			content, _ := file.Open()
+			data, _ := io.ReadAll(content)
+			sheet = string(data)
+		}
+	}
+	assert.Contains(t, sheet, `<c r="B2" s="1"><v>45726</v></c>`)
+	assert.Contains(t, sheet, `<c r="F2" s="3"><v>1250.5</v></c>`)
+
+	w = exportExpenses("/expenses?format=pdf", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
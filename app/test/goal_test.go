@@ -0,0 +1,125 @@
+package test
+
+import (
+	"bytes"
+	"dapa/app/handlers"
+	"dapa/app/model"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func upsertPerformanceGoal(input model.PerformanceGoalDTO) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(input)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("claims", &model.EmployeeClaims{UserID: 1, Role: "admin"})
+
+	req, _ := http.NewRequest("PUT", "/kpi/goals", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	c.Request = req
+
+	handlers.UpsertPerformanceGoal(c)
+	return w
+}
+
+func TestUpsertPerformanceGoal_KeepsHistoryPerPeriodAndDriver(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupOrderTestContext()
+	db.AutoMigrate(&model.PerformanceGoal{})
+	db.Create(&model.User{Name: "Admin", Email: "admin@dapa.com", Role: "admin"})
+	db.Create(&model.User{Name: "Piloto", Email: "piloto@dapa.com", Role: "driver"})
+
+	driverID := uint(2)
+	assert.Equal(t, http.StatusCreated, upsertPerformanceGoal(model.PerformanceGoalDTO{Period: "2025-01", OrderGoal: 10}).Code)
+	assert.Equal(t, http.StatusCreated, upsertPerformanceGoal(model.PerformanceGoalDTO{Period: "2025-02", OrderGoal: 20}).Code)
+	assert.Equal(t, http.StatusCreated, upsertPerformanceGoal(model.PerformanceGoalDTO{Period: "2025-02", UserID: &driverID, OrderGoal: 5}).Code)
+	assert.Equal(t, http.StatusOK, upsertPerformanceGoal(model.PerformanceGoalDTO{Period: "2025-02", OrderGoal: 25}).Code)
+
+	adminID := uint(1)
+	assert.Equal(t, http.StatusBadRequest, upsertPerformanceGoal(model.PerformanceGoalDTO{Period: "2025-02", UserID: &adminID}).Code)
+	assert.Equal(t, http.StatusBadRequest, upsertPerformanceGoal(model.PerformanceGoalDTO{Period: "02-2025"}).Code)
+
+	var goals []model.PerformanceGoal
+	db.Order("id").Find(&goals)
+	assert.Len(t, goals, 3)
+	assert.Equal(t, 10, goals[0].OrderGoal)
+	assert.Equal(t, 25, goals[1].OrderGoal)
+	assert.Equal(t, &adminID, goals[1].UpdatedBy)
+	assert.Equal(t, &driverID, goals[2].UserID)
+}
+
+func TestGetGoalProgress_ComparesDriverGoalWithActuals(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupOrderTestContext()
+	db.AutoMigrate(&model.PerformanceGoal{})
+
+	day := func(d int) time.Time { return time.Date(2025, 1, d, 0, 0, 0, 0, time.UTC) }
+	driverID, otherID := uint(2), uint(3)
+	db.Create(&model.Order{UserID: &driverID, Date: day(5), Status: model.OrderStatusDelivered, TotalAmount: 300})
+	db.Create(&model.Order{UserID: &driverID, Date: day(12), Status: model.OrderStatusDelivered, TotalAmount: 500})
+	db.Create(&model.Order{UserID: &driverID, Date: day(20), Status: model.OrderStatusCancelled, TotalAmount: 900})
+	db.Create(&model.Order{UserID: &otherID, Date: day(20), Status: model.OrderStatusDelivered, TotalAmount: 700})
+	db.Create(&model.Payment{OrderID: 1, Amount: 300, Method: model.PaymentMethodCash, PaidAt: day(5)})
+	db.Create(&model.Payment{OrderID: 4, Amount: 700, Method: model.PaymentMethodCash, PaidAt: day(20)})
+	db.Create(&model.PerformanceGoal{Period: model.PeriodMonth, PeriodStart: day(1), UserID: &driverID, OrderGoal: 4, UtilityGoal: 600})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/kpi/goals/progress?period=2025-01&userId=2", nil)
+	handlers.GetGoalProgress(c)
+
+	var resp struct {
+		Data model.GoalProgressDTO `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	progress := resp.Data
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 100.0, progress.ElapsedPct)
+	assert.NotNil(t, progress.Goal)
+	assert.Equal(t, int64(2), progress.KPIs.Orders)
+	assert.Equal(t, 300.0, progress.KPIs.TotalIncome)
+
+	assert.Equal(t, "orderGoal", progress.Metrics[0].Key)
+	assert.Equal(t, 50.0, *progress.Metrics[0].AttainmentPct)
+	assert.False(t, progress.Metrics[0].OnTrack)
+	assert.Equal(t, 50.0, *progress.Metrics[1].AttainmentPct)
+	assert.Nil(t, progress.Metrics[2].AttainmentPct)
+}
+
+func TestUpsertPerformanceGoal_DefaultsToCurrentMonth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupOrderTestContext()
+	db.AutoMigrate(&model.PerformanceGoal{})
+
+	assert.Equal(t, http.StatusCreated, upsertPerformanceGoal(model.PerformanceGoalDTO{OrderGoal: 10}).Code)
+	assert.Equal(t, http.StatusOK, upsertPerformanceGoal(model.PerformanceGoalDTO{OrderGoal: 15}).Code)
+
+	var goals []model.PerformanceGoal
+	db.Find(&goals)
+	assert.Len(t, goals, 1)
+	assert.Equal(t, 15, goals[0].OrderGoal)
+	assert.Nil(t, goals[0].UserID)
+
+	// Las metas generales repetidas se rechazan aunque no tengan piloto
+	duplicate := model.PerformanceGoal{Period: goals[0].Period, PeriodStart: goals[0].PeriodStart}
+	assert.Error(t, db.Create(&duplicate).Error)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/kpi/goals", nil)
+	handlers.GetPerformanceGoal(c)
+
+	var resp struct {
+		Data model.PerformanceGoal `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.Equal(t, goals[0].ID, resp.Data.ID)
+	assert.Equal(t, 15, resp.Data.OrderGoal)
+}
@@ -0,0 +1,56 @@
+package test
+
+import (
+	"bytes"
+	"dapa/app/handlers"
+	"dapa/app/model"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func getInvoice(orderID string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("claims", &model.EmployeeClaims{UserID: 1, Role: "admin"})
+	c.Request, _ = http.NewRequest("GET", "/orders/"+orderID+"/invoice.pdf", nil)
+	c.Params = gin.Params{{Key: "id", Value: orderID}}
+
+	handlers.GetOrderInvoiceHandler(c)
+	return w
+}
+
+func TestGetOrderInvoice_NumbersAreSequential(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupOrderTestContext()
+
+	db.Create(&model.Order{ClientName: "Ana López", Status: model.OrderStatusDelivered, TotalAmount: 560, Type: "mudanza", MeetingDate: time.Now()})
+	db.Create(&model.Order{ClientName: "Luis Pérez", Status: model.OrderStatusDelivered, TotalAmount: 300, Type: "flete", MeetingDate: time.Now()})
+	db.Create(&model.Order{ClientName: "Eva Ruiz", Status: model.OrderStatusAssigned, TotalAmount: 100, Type: "flete", MeetingDate: time.Now()})
+	db.Create(&model.Payment{OrderID: 1, Amount: 200, Method: model.PaymentMethodCash, PaidAt: time.Now()})
+
+	w := getInvoice("1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+	assert.True(t, bytes.HasPrefix(w.Body.Bytes(), []byte("%PDF-")))
+	first := w.Body.Bytes()
+
+	w = getInvoice("3")
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = getInvoice("2")
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "factura-000002.pdf")
+
+	w = getInvoice("1")
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "factura-000001.pdf")
+	assert.Equal(t, first, w.Body.Bytes())
+
+	var invoice model.Invoice
+	db.Where("order_id = ?", 1).First(&invoice)
+	assert.Equal(t, 500.0, invoice.Subtotal)
+	assert.Equal(t, 60.0, invoice.TaxAmount)
+}
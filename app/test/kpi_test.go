@@ -0,0 +1,96 @@
+package test
+
+import (
+	"dapa/app/handlers"
+	"dapa/app/jobs"
+	"dapa/app/model"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetKPIs_UsesMonthCloseSnapshots(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupOrderTestContext()
+	db.AutoMigrate(&model.KPISnapshot{})
+
+	day := func(month time.Month, d int) time.Time { return time.Date(2025, month, d, 0, 0, 0, 0, time.UTC) }
+	db.Create(&model.Order{Date: day(1, 10), Status: model.OrderStatusDelivered, TotalAmount: 1000})
+	db.Create(&model.Order{Date: day(2, 10), Status: model.OrderStatusDelivered, TotalAmount: 1500})
+	db.Create(&model.Payment{OrderID: 1, Amount: 1000, Method: model.PaymentMethodCash, PaidAt: day(1, 10)})
+	db.Create(&model.Payment{OrderID: 2, Amount: 1500, Method: model.PaymentMethodCash, PaidAt: day(2, 10)})
+
+	now := time.Date(2025, 2, 15, 12, 0, 0, 0, time.UTC)
+	assert.NoError(t, jobs.SnapshotKPIs(now))
+	assert.NoError(t, jobs.SnapshotKPIs(now))
+
+	var count int64
+	db.Model(&model.KPISnapshot{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	// Una corrección posterior no cambia el mes cerrado
+	db.Create(&model.Payment{OrderID: 1, Amount: 500, Method: model.PaymentMethodCash, PaidAt: day(1, 20)})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/kpi?period=2025-02", nil)
+	handlers.GetKPIs(c)
+
+	var resp struct {
+		Data model.KPIReportDTO `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	report := resp.Data
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, report.Months, 12)
+	assert.Equal(t, "2024-03", report.Months[0])
+	assert.Equal(t, 1500.0, report.KPIs.TotalIncome)
+	assert.Equal(t, 1000.0, report.PreviousPeriod.TotalIncome)
+	assert.Nil(t, report.SnapshotAt)
+
+	income := report.Trends[0]
+	assert.Equal(t, "totalIncome", income.Key)
+	assert.Equal(t, 500.0, income.Change)
+	assert.Equal(t, 50.0, *income.ChangePct)
+	assert.Equal(t, []float64{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1000, 1500}, income.Series)
+}
+
+func TestGetKPIs_RejectsInvalidPeriods(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupOrderTestContext()
+
+	for _, period := range []string{"2025", "2025-13", "2999-01"} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("GET", "/kpi?period="+period, nil)
+		handlers.GetKPIs(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, period)
+	}
+}
+
+func TestSnapshotKPIs_CatchesUpMissedMonths(t *testing.T) {
+	db := setupOrderTestContext()
+	db.AutoMigrate(&model.KPISnapshot{})
+
+	day := func(month time.Month, d int) time.Time { return time.Date(2025, month, d, 0, 0, 0, 0, time.UTC) }
+	db.Create(&model.Order{Date: day(1, 10), Status: model.OrderStatusDelivered, TotalAmount: 1000})
+	db.Create(&model.Order{Date: day(3, 10), Status: model.OrderStatusDelivered, TotalAmount: 1500})
+	db.Create(&model.Payment{OrderID: 2, Amount: 1500, Method: model.PaymentMethodCash, PaidAt: day(3, 10)})
+	db.Create(&model.KPISnapshot{PeriodStart: day(2, 1), KPIs: model.KPIs{Orders: 7}})
+
+	assert.NoError(t, jobs.SnapshotKPIs(time.Date(2025, 4, 15, 12, 0, 0, 0, time.UTC)))
+
+	var snapshots []model.KPISnapshot
+	db.Order("period_start").Find(&snapshots)
+	assert.Len(t, snapshots, 3)
+	assert.Equal(t, int64(1), snapshots[0].Orders)
+	assert.Equal(t, int64(7), snapshots[1].Orders)
+	assert.Equal(t, 1500.0, snapshots[2].TotalIncome)
+}
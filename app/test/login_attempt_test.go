@@ -0,0 +1,88 @@
+package test
+
+import (
+	"bytes"
+	"dapa/app/handlers"
+	"dapa/app/model"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func attemptLogin(password string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(model.LoginDTO{Email: "piloto@dapa.com", Password: password})
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/login", bytes.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	handlers.LoginHandler(c)
+	return w
+}
+
+func TestLogin_LocksAccountAfterRepeatedFailures(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupSessionTestContext(t)
+	db.Model(&model.User{}).Where("id = ?", 1).Update("failed_login_attempts", 9)
+
+	assert.Equal(t, http.StatusUnauthorized, attemptLogin("Incorrecta123!").Code)
+
+	// La cuenta bloqueada rechaza también la contraseña correcta
+	w := attemptLogin("Secreta123!")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	var user model.User
+	db.First(&user, 1)
+	assert.Equal(t, 10, user.FailedLoginAttempts)
+	assert.NotNil(t, user.LockedUntil)
+
+	w = httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/users/1/unlock", nil)
+	c.Params = gin.Params{{Key: "id", Value: "1"}}
+	handlers.UnlockUserHandler(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	assert.Equal(t, http.StatusOK, attemptLogin("Secreta123!").Code)
+
+	var attempts []model.LoginAttempt
+	db.Order("id").Find(&attempts)
+	assert.Len(t, attempts, 3)
+	assert.Equal(t, "invalid_password", attempts[0].Reason)
+	assert.Equal(t, "account_locked", attempts[1].Reason)
+	assert.True(t, attempts[2].Success)
+	assert.Equal(t, uint(1), *attempts[2].UserID)
+}
+
+func TestLoginAndForgot_LimitAttemptsPerIPAndAccount(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupSessionTestContext(t)
+
+	userID := uint(1)
+	for range 20 {
+		db.Create(&model.LoginAttempt{Kind: model.LoginAttemptLogin, Email: "otro@dapa.com"})
+	}
+	for range 3 {
+		db.Create(&model.LoginAttempt{Kind: model.LoginAttemptForgot, UserID: &userID, Success: true})
+	}
+
+	assert.Equal(t, http.StatusTooManyRequests, attemptLogin("Secreta123!").Code)
+
+	body, _ := json.Marshal(model.ForgotPasswordDTO{Email: "piloto@dapa.com"})
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/auth/forgot", bytes.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	handlers.ForgotPasswordHandler(c)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+
+	var rejected []model.LoginAttempt
+	db.Where("reason <> ''").Order("id").Find(&rejected)
+	assert.Len(t, rejected, 2)
+	assert.Equal(t, "ip_limited", rejected[0].Reason)
+	assert.Equal(t, "account_limited", rejected[1].Reason)
+}
@@ -0,0 +1,543 @@
+package test
+
+import (
+	"bytes"
+	"dapa/app/handlers"
+	"dapa/app/model"
+	"dapa/database"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupOrderTestContext() *gorm.DB {
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.AutoMigrate(&model.Order{}, &model.OrderToken{}, &model.OrderStatusHistory{}, &model.OrderItem{}, &model.OrderStop{}, &model.User{}, &model.Vehicle{}, &model.Customer{}, &model.CustomerAddress{}, &model.Payment{}, &model.Invoice{}, &model.InvoiceSequence{}, &model.ProofOfDelivery{}, &model.Attachment{}, &model.Expense{}, &model.ExpenseType{}, &model.Role{}, &model.RolePermission{})
+	database.DB = db
+	seedRoles(db)
+	return db
+}
+
+// Crea los roles del sistema con sus permisos predeterminados
+func seedRoles(db *gorm.DB) {
+	for _, defaultRole := range model.DefaultRoles {
+		permissions := defaultRole.Permissions
+		if defaultRole.Name == "admin" {
+			permissions = model.AllPermissions()
+		}
+
+		role := model.Role{Name: defaultRole.Name, Description: defaultRole.Description, System: true}
+		for _, permission := range permissions {
+			role.Permissions = append(role.Permissions, model.RolePermission{Permission: permission})
+		}
+		db.Create(&role)
+	}
+}
+
+func changeOrderStatus(orderID string, status model.OrderStatus, claims *model.EmployeeClaims) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(model.OrderStatusDTO{Status: status})
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("claims", claims)
+
+	req, _ := http.NewRequest("PATCH", "/orders/"+orderID+"/status", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	c.Request = req
+	c.Params = gin.Params{{Key: "id", Value: orderID}}
+
+	handlers.ChangeOrderStatusHandler(c)
+	return w
+}
+
+func TestChangeOrderStatus_RejectsSkippedTransition(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupOrderTestContext()
+
+	driverID := uint(7)
+	db.Create(&model.Order{UserID: &driverID, Status: model.OrderStatusPending, MeetingDate: time.Now()})
+
+	w := changeOrderStatus("1", model.OrderStatusDelivered, &model.EmployeeClaims{UserID: driverID, Role: "driver"})
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	var order model.Order
+	db.First(&order, 1)
+	assert.Equal(t, model.OrderStatusPending, order.Status)
+}
+
+func TestChangeOrderStatus_RecordsHistory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupOrderTestContext()
+
+	driverID := uint(7)
+	db.Create(&model.Order{UserID: &driverID, Status: model.OrderStatusAssigned, MeetingDate: time.Now()})
+
+	w := changeOrderStatus("1", model.OrderStatusPickup, &model.EmployeeClaims{UserID: driverID, Role: "driver"})
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var history []model.OrderStatusHistory
+	db.Where("order_id = ?", 1).Find(&history)
+	assert.Len(t, history, 1)
+	assert.Equal(t, model.OrderStatusAssigned, history[0].FromStatus)
+	assert.Equal(t, model.OrderStatusPickup, history[0].ToStatus)
+	assert.Equal(t, driverID, *history[0].ChangedBy)
+}
+
+func TestChangeOrderStatus_HelperCannotChangeStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupOrderTestContext()
+
+	helperID := uint(9)
+	db.Create(&model.Order{HelperID: &helperID, Status: model.OrderStatusAssigned, MeetingDate: time.Now()})
+
+	w := changeOrderStatus("1", model.OrderStatusPickup, &model.EmployeeClaims{UserID: helperID, Role: "helper"})
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func assignOrder(orderID string, dto model.AssignOrderDTO) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(dto)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("claims", &model.EmployeeClaims{UserID: 1, Role: "admin"})
+
+	req, _ := http.NewRequest("PATCH", "/orders/"+orderID+"/assign", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	c.Request = req
+	c.Params = gin.Params{{Key: "id", Value: orderID}}
+
+	handlers.AssignOrderHandler(c)
+	return w
+}
+
+func TestAssignOrder_RejectsDoubleBooking(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupOrderTestContext()
+
+	driver := model.User{Name: "Driver", Email: "driver@example.com", Role: "driver", IsActive: true}
+	helper := model.User{Name: "Helper", Email: "helper@example.com", Role: "helper", IsActive: true}
+	db.Create(&driver)
+	db.Create(&helper)
+	db.Create(&model.Vehicle{Brand: "Isuzu", IsAvailable: true, IsActive: true})
+
+	meetingDate := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+	db.Create(&model.Order{Status: model.OrderStatusPending, MeetingDate: meetingDate})
+	db.Create(&model.Order{Status: model.OrderStatusPending, MeetingDate: meetingDate})
+
+	dto := model.AssignOrderDTO{UserID: driver.ID, HelperID: helper.ID, VehicleID: 1}
+
+	w := assignOrder("1", dto)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = assignOrder("2", dto)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	var resp struct {
+		Data []model.AssignmentConflictDTO `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.Len(t, resp.Data, 3)
+	assert.Equal(t, uint(1), resp.Data[0].OrderID)
+}
+
+func TestAssignOrder_RejectsWrongRole(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupOrderTestContext()
+
+	helper := model.User{Name: "Helper", Email: "helper@example.com", Role: "helper", IsActive: true}
+	db.Create(&helper)
+	db.Create(&model.Vehicle{Brand: "Isuzu", IsAvailable: true, IsActive: true})
+	db.Create(&model.Order{Status: model.OrderStatusPending, MeetingDate: time.Now()})
+
+	w := assignOrder("1", model.AssignOrderDTO{UserID: helper.ID, HelperID: helper.ID, VehicleID: 1})
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "does not have the driver role")
+}
+
+func TestAssignmentSuggestions_SkipsExpiredAndBookedResources(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupOrderTestContext()
+
+	meetingDate := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+	valid := meetingDate.AddDate(1, 0, 0)
+
+	busyDriver := model.User{Name: "Busy", Email: "busy@example.com", Role: "driver", IsActive: true, LicenseExpirationDate: valid}
+	expiredDriver := model.User{Name: "Expired", Email: "expired@example.com", Role: "driver", IsActive: true, LicenseExpirationDate: meetingDate.AddDate(0, -1, 0)}
+	freeDriver := model.User{Name: "Free", Email: "free@example.com", Role: "driver", IsActive: true, LicenseExpirationDate: valid}
+	helper := model.User{Name: "Helper", Email: "helper@example.com", Role: "helper", IsActive: true}
+	db.Create(&busyDriver)
+	db.Create(&expiredDriver)
+	db.Create(&freeDriver)
+	db.Create(&helper)
+	db.Create(&model.Vehicle{Brand: "Isuzu", IsAvailable: true, IsActive: true, CapacityKg: 1000, InsuranceDate: valid})
+
+	db.Create(&model.Order{Status: model.OrderStatusAssigned, UserID: &busyDriver.ID, MeetingDate: meetingDate})
+	db.Create(&model.Order{Status: model.OrderStatusPending, MeetingDate: meetingDate})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("claims", &model.EmployeeClaims{UserID: 1, Role: "admin"})
+	c.Request, _ = http.NewRequest("GET", "/orders/2/assignment-suggestions", nil)
+	c.Params = gin.Params{{Key: "id", Value: "2"}}
+
+	handlers.GetAssignmentSuggestionsHandler(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		Data []model.AssignmentSuggestionDTO `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.Len(t, resp.Data, 1)
+	assert.Equal(t, freeDriver.ID, resp.Data[0].Driver.ID)
+}
+
+func TestAssignmentSuggestions_PrefersLowerWeeklyWorkload(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupOrderTestContext()
+
+	// El servicio es un miércoles y el piloto ocupado ya tuvo una orden el lunes de esa semana
+	meetingDate := time.Date(2025, 3, 12, 0, 0, 0, 0, time.UTC)
+	monday := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+	valid := meetingDate.AddDate(1, 0, 0)
+
+	busyDriver := model.User{Name: "Busy", Email: "busy@example.com", Role: "driver", IsActive: true, LicenseExpirationDate: valid}
+	freeDriver := model.User{Name: "Free", Email: "free@example.com", Role: "driver", IsActive: true, LicenseExpirationDate: valid}
+	firstHelper := model.User{Name: "First", Email: "first@example.com", Role: "helper", IsActive: true}
+	secondHelper := model.User{Name: "Second", Email: "second@example.com", Role: "helper", IsActive: true}
+	db.Create(&busyDriver)
+	db.Create(&freeDriver)
+	db.Create(&firstHelper)
+	db.Create(&secondHelper)
+	db.Create(&model.Vehicle{Brand: "Isuzu", IsAvailable: true, IsActive: true, CapacityKg: 1000, InsuranceDate: valid})
+
+	db.Create(&model.Order{Status: model.OrderStatusDelivered, UserID: &busyDriver.ID, MeetingDate: monday})
+	db.Create(&model.Order{Status: model.OrderStatusPending, MeetingDate: meetingDate})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/orders/2/assignment-suggestions", nil)
+	c.Params = gin.Params{{Key: "id", Value: "2"}}
+	handlers.GetAssignmentSuggestionsHandler(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		Data []model.AssignmentSuggestionDTO `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.Len(t, resp.Data, 4)
+	assert.Equal(t, freeDriver.ID, resp.Data[0].Driver.ID)
+	assert.Equal(t, firstHelper.ID, resp.Data[0].Helper.ID)
+	assert.Equal(t, 1, resp.Data[2].Driver.Workload)
+}
+
+func TestAssignOrder_RejectsVehicleOverCapacity(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupOrderTestContext()
+
+	driver := model.User{Name: "Driver", Email: "driver@example.com", Role: "driver", IsActive: true}
+	helper := model.User{Name: "Helper", Email: "helper@example.com", Role: "helper", IsActive: true}
+	db.Create(&driver)
+	db.Create(&helper)
+	db.Create(&model.Vehicle{Brand: "Isuzu", IsAvailable: true, IsActive: true, CapacityKg: 500})
+	db.Create(&model.Order{Status: model.OrderStatusPending, MeetingDate: time.Now()})
+	db.Create(&model.OrderItem{OrderID: 1, Description: "Refrigerador", Quantity: 2, WeightKg: 300})
+
+	w := assignOrder("1", model.AssignOrderDTO{UserID: driver.ID, HelperID: helper.ID, VehicleID: 1})
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "exceeds the 500.00 kg capacity")
+}
+
+func TestUpdateOrder_RejectsStaffChanges(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupOrderTestContext()
+
+	driverID, otherID := uint(1), uint(2)
+	meetingDate := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+	db.Create(&model.Order{UserID: &driverID, Status: model.OrderStatusAssigned, ClientName: "Ana", ClientPhone: "55551234", Origin: "Zona 1", Destination: "Zona 10", TotalAmount: 300, Type: "Mudanza", MeetingDate: meetingDate})
+
+	update := func(userID *uint) int {
+		dto := model.OrderDTO{UserID: userID, ClientName: "Ana", ClientPhone: "55551234", Origin: "Zona 1", Destination: "Zona 10", TotalAmount: 350, Type: "Mudanza", MeetingDate: meetingDate}
+		body, _ := json.Marshal(dto)
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("PUT", "/orders/1", bytes.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Params = gin.Params{{Key: "id", Value: "1"}}
+		handlers.UpdateOrderHandler(c)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusBadRequest, update(&otherID))
+	assert.Equal(t, http.StatusOK, update(&driverID))
+
+	var order model.Order
+	db.First(&order, 1)
+	assert.Equal(t, driverID, *order.UserID)
+	assert.Equal(t, model.OrderStatusAssigned, order.Status)
+	assert.Equal(t, 350.0, order.TotalAmount)
+}
+
+func updateStopStatus(orderID, position string, status model.StopStatus, claims *model.EmployeeClaims) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(model.StopStatusDTO{Status: status})
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("claims", claims)
+
+	req, _ := http.NewRequest("PATCH", "/orders/"+orderID+"/stops/"+position+"/status", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	c.Request = req
+	c.Params = gin.Params{{Key: "id", Value: orderID}, {Key: "position", Value: position}}
+
+	handlers.UpdateStopStatusHandler(c)
+	return w
+}
+
+func TestUpdateStopStatus_DerivesOrderStatusFromRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupOrderTestContext()
+
+	driverID := uint(7)
+	claims := &model.EmployeeClaims{UserID: driverID, Role: "driver"}
+	db.Create(&model.Order{UserID: &driverID, Status: model.OrderStatusAssigned, MeetingDate: time.Now()})
+	db.Create(&[]model.OrderStop{
+		{OrderID: 1, Position: 1, Type: model.StopTypePickup, Address: "Zona 1", Status: model.StopStatusPending},
+		{OrderID: 1, Position: 2, Type: model.StopTypePickup, Address: "Zona 10", Status: model.StopStatusPending},
+		{OrderID: 1, Position: 3, Type: model.StopTypeDropoff, Address: "Mixco", Status: model.StopStatusPending},
+	})
+
+	var order model.Order
+
+	assert.Equal(t, http.StatusOK, updateStopStatus("1", "1", model.StopStatusCompleted, claims).Code)
+	db.First(&order, 1)
+	assert.Equal(t, model.OrderStatusPickup, order.Status)
+
+	assert.Equal(t, http.StatusOK, updateStopStatus("1", "2", model.StopStatusCompleted, claims).Code)
+	db.First(&order, 1)
+	assert.Equal(t, model.OrderStatusCollected, order.Status)
+
+	assert.Equal(t, http.StatusConflict, updateStopStatus("1", "2", model.StopStatusArrived, claims).Code)
+
+	assert.Equal(t, http.StatusConflict, updateStopStatus("1", "3", model.StopStatusCompleted, claims).Code)
+	db.Create(&model.ProofOfDelivery{OrderID: 1, RecipientName: "Ana", DeliveredAt: time.Now(), SubmittedBy: driverID})
+
+	assert.Equal(t, http.StatusOK, updateStopStatus("1", "3", model.StopStatusCompleted, claims).Code)
+	db.First(&order, 1)
+	assert.Equal(t, model.OrderStatusDelivered, order.Status)
+
+	var history []model.OrderStatusHistory
+	db.Where("order_id = ?", 1).Order("id").Find(&history)
+	assert.Len(t, history, 3)
+	assert.Equal(t, model.OrderStatusDelivered, history[2].ToStatus)
+}
+
+func TestUpdateStopStatus_LegacyOrderUsesTwoStopRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupOrderTestContext()
+
+	driverID := uint(7)
+	db.Create(&model.Order{UserID: &driverID, Origin: "Zona 1", Destination: "Mixco", Status: model.OrderStatusAssigned, MeetingDate: time.Now()})
+
+	w := updateStopStatus("1", "1", model.StopStatusCompleted, &model.EmployeeClaims{UserID: driverID, Role: "driver"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var stops []model.OrderStop
+	db.Where("order_id = ?", 1).Order("position").Find(&stops)
+	assert.Len(t, stops, 2)
+	assert.Equal(t, "Mixco", stops[1].Address)
+	assert.Equal(t, model.StopStatusPending, stops[1].Status)
+
+	var order model.Order
+	db.First(&order, 1)
+	assert.Equal(t, model.OrderStatusCollected, order.Status)
+}
+
+func closeOrderRequest(handler gin.HandlerFunc, path string, dto any, claims *model.EmployeeClaims) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(dto)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("claims", claims)
+
+	req, _ := http.NewRequest("PATCH", "/orders/1/"+path, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	c.Request = req
+	c.Params = gin.Params{{Key: "id", Value: "1"}}
+
+	handler(c)
+	return w
+}
+
+func TestCancelOrder_DriverCancelsAssignedOrder(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupOrderTestContext()
+	db.AutoMigrate(&model.Submission{})
+
+	driverID := uint(7)
+	db.Create(&model.Submission{Status: model.FormStatusApproved})
+	db.Create(&model.Order{SubmissionID: 1, UserID: &driverID, Status: model.OrderStatusAssigned, MeetingDate: time.Now()})
+	db.Create(&model.OrderToken{OrderID: 1, Token: "token"})
+
+	w := closeOrderRequest(handlers.CancelOrderHandler, "cancel", model.CancelOrderDTO{Reason: "Cliente canceló"}, &model.EmployeeClaims{UserID: driverID, Role: "driver"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var order model.Order
+	db.First(&order, 1)
+	assert.Equal(t, model.OrderStatusCancelled, order.Status)
+
+	var submission model.Submission
+	db.First(&submission, 1)
+	assert.Equal(t, model.FormStatusCancelled, submission.Status)
+
+	var token model.OrderToken
+	db.First(&token, 1)
+	assert.NotNil(t, token.Expiry)
+
+	var history model.OrderStatusHistory
+	db.Where("order_id = ?", 1).First(&history)
+	assert.Equal(t, "Cliente canceló", *history.Reason)
+}
+
+func TestCancelOrder_RejectsUnassignedDriverAndDeliveredOrder(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupOrderTestContext()
+	db.AutoMigrate(&model.Submission{})
+
+	driverID := uint(7)
+	db.Create(&model.Order{Status: model.OrderStatusPending, MeetingDate: time.Now()})
+	db.Create(&model.Order{UserID: &driverID, Status: model.OrderStatusDelivered, MeetingDate: time.Now()})
+
+	dto := model.CancelOrderDTO{Reason: "Sin motivo"}
+	driver := &model.EmployeeClaims{UserID: driverID, Role: "driver"}
+
+	w := closeOrderRequest(handlers.CancelOrderHandler, "cancel", dto, driver)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	db.Model(&model.Order{}).Where("id = ?", 1).Update("user_id", driverID)
+	w = closeOrderRequest(handlers.CancelOrderHandler, "cancel", dto, driver)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	db.Model(&model.Order{}).Where("id = ?", 1).Update("status", model.OrderStatusDelivered)
+	w = closeOrderRequest(handlers.CancelOrderHandler, "cancel", dto, &model.EmployeeClaims{UserID: 1, Role: "admin"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestFailOrder_NoShowOnlyFromPickup(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupOrderTestContext()
+	db.AutoMigrate(&model.Submission{})
+
+	driverID := uint(7)
+	claims := &model.EmployeeClaims{UserID: driverID, Role: "driver"}
+	db.Create(&model.Submission{Status: model.FormStatusApproved})
+	db.Create(&model.Order{SubmissionID: 1, UserID: &driverID, Status: model.OrderStatusAssigned, MeetingDate: time.Now()})
+
+	noShow := model.OrderFailureDTO{Status: model.OrderStatusNoShow, Reason: "Nadie en casa"}
+	w := closeOrderRequest(handlers.FailOrderHandler, "failure", noShow, claims)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	db.Model(&model.Order{}).Where("id = ?", 1).Update("status", model.OrderStatusPickup)
+	w = closeOrderRequest(handlers.FailOrderHandler, "failure", noShow, claims)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var order model.Order
+	db.First(&order, 1)
+	assert.Equal(t, model.OrderStatusNoShow, order.Status)
+}
+
+func TestFailOrder_HelperCannotMarkFailure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupOrderTestContext()
+
+	helperID := uint(9)
+	db.Create(&model.Order{HelperID: &helperID, Status: model.OrderStatusPickup, MeetingDate: time.Now()})
+
+	failed := model.OrderFailureDTO{Status: model.OrderStatusFailed, Reason: "Camión averiado"}
+	w := closeOrderRequest(handlers.FailOrderHandler, "failure", failed, &model.EmployeeClaims{UserID: helperID, Role: "helper"})
+	assert.NotEqual(t, http.StatusOK, w.Code)
+
+	var order model.Order
+	db.First(&order, 1)
+	assert.Equal(t, model.OrderStatusPickup, order.Status)
+}
+
+func TestRescheduleOrder_ReleasesAssignmentAndReopensRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupOrderTestContext()
+	db.AutoMigrate(&model.Submission{})
+
+	driverID := uint(7)
+	db.Create(&model.Submission{Status: model.FormStatusCancelled})
+	db.Create(&model.Order{SubmissionID: 1, UserID: &driverID, Status: model.OrderStatusFailed, MeetingDate: time.Now()})
+	db.Create(&model.OrderStop{OrderID: 1, Position: 1, Type: model.StopTypePickup, Address: "Zona 1", Status: model.StopStatusSkipped})
+
+	newDate := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	w := closeOrderRequest(handlers.RescheduleOrderHandler, "reschedule", model.RescheduleOrderDTO{MeetingDate: newDate}, &model.EmployeeClaims{UserID: 1, Role: "admin"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var order model.Order
+	db.First(&order, 1)
+	assert.Equal(t, model.OrderStatusPending, order.Status)
+	assert.Nil(t, order.UserID)
+	assert.True(t, newDate.Equal(order.MeetingDate))
+
+	var stop model.OrderStop
+	db.First(&stop, 1)
+	assert.Equal(t, model.StopStatusPending, stop.Status)
+
+	var submission model.Submission
+	db.First(&submission, 1)
+	assert.Equal(t, model.FormStatusApproved, submission.Status)
+}
+
+func TestRescheduleOrder_RejectsDeliveredOrderAndDriver(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupOrderTestContext()
+
+	driverID := uint(7)
+	db.Create(&model.Order{UserID: &driverID, Status: model.OrderStatusDelivered, MeetingDate: time.Now()})
+
+	dto := model.RescheduleOrderDTO{MeetingDate: time.Now().AddDate(0, 0, 7)}
+	w := closeOrderRequest(handlers.RescheduleOrderHandler, "reschedule", dto, &model.EmployeeClaims{UserID: 1, Role: "admin"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	db.Model(&model.Order{}).Where("id = ?", 1).Update("status", model.OrderStatusFailed)
+	w = closeOrderRequest(handlers.RescheduleOrderHandler, "reschedule", dto, &model.EmployeeClaims{UserID: driverID, Role: "driver"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestUpdateStopStatus_SkippedPickupsResolveCollection(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupOrderTestContext()
+
+	driverID := uint(7)
+	claims := &model.EmployeeClaims{UserID: driverID, Role: "driver"}
+	db.Create(&model.Order{UserID: &driverID, Status: model.OrderStatusAssigned, MeetingDate: time.Now()})
+	db.Create(&[]model.OrderStop{
+		{OrderID: 1, Position: 1, Type: model.StopTypePickup, Address: "Zona 1", Status: model.StopStatusPending},
+		{OrderID: 1, Position: 2, Type: model.StopTypePickup, Address: "Zona 10", Status: model.StopStatusPending},
+		{OrderID: 1, Position: 3, Type: model.StopTypeDropoff, Address: "Mixco", Status: model.StopStatusPending},
+	})
+
+	assert.Equal(t, http.StatusOK, updateStopStatus("1", "1", model.StopStatusSkipped, claims).Code)
+	assert.Equal(t, http.StatusOK, updateStopStatus("1", "2", model.StopStatusSkipped, claims).Code)
+
+	var order model.Order
+	db.First(&order, 1)
+	assert.Equal(t, model.OrderStatusCollected, order.Status)
+
+	var history []model.OrderStatusHistory
+	db.Where("order_id = ?", 1).Order("id").Find(&history)
+	assert.Len(t, history, 2)
+	assert.Equal(t, model.OrderStatusPickup, history[0].ToStatus)
+}
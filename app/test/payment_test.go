@@ -0,0 +1,108 @@
+package test
+
+import (
+	"bytes"
+	"dapa/app/handlers"
+	"dapa/app/model"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func createPayment(orderID string, dto model.PaymentDTO, claims *model.EmployeeClaims) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(dto)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("claims", claims)
+	c.Request, _ = http.NewRequest("POST", "/orders/"+orderID+"/payments", bytes.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "id", Value: orderID}}
+
+	handlers.CreatePaymentHandler(c)
+	return w
+}
+
+func TestCreatePayment_TracksBalanceDue(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupOrderTestContext()
+	admin := &model.EmployeeClaims{UserID: 1, Role: "admin"}
+
+	db.Create(&model.Order{Status: model.OrderStatusAssigned, TotalAmount: 500, MeetingDate: time.Now()})
+
+	w := createPayment("1", model.PaymentDTO{Amount: 200, Method: model.PaymentMethodCash, IsDeposit: true}, admin)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = createPayment("1", model.PaymentDTO{Amount: 350, Method: model.PaymentMethodTransfer}, admin)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("claims", admin)
+	c.Request, _ = http.NewRequest("GET", "/orders/1/payments", nil)
+	c.Params = gin.Params{{Key: "id", Value: "1"}}
+	handlers.GetOrderPaymentsHandler(c)
+
+	var resp struct {
+		Data struct {
+			AmountPaid    float64             `json:"amountPaid"`
+			BalanceDue    float64             `json:"balanceDue"`
+			PaymentStatus model.PaymentStatus `json:"paymentStatus"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.Equal(t, 200.0, resp.Data.AmountPaid)
+	assert.Equal(t, 300.0, resp.Data.BalanceDue)
+	assert.Equal(t, model.PaymentStatusPartial, resp.Data.PaymentStatus)
+}
+
+func TestCreatePayment_DriverCannotPayOtherOrders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupOrderTestContext()
+
+	driverID := uint(2)
+	db.Create(&model.Order{Status: model.OrderStatusAssigned, TotalAmount: 500, UserID: &driverID, MeetingDate: time.Now()})
+
+	w := createPayment("1", model.PaymentDTO{Amount: 100, Method: model.PaymentMethodCash}, &model.EmployeeClaims{UserID: 3, Role: "driver"})
+	assert.NotEqual(t, http.StatusCreated, w.Code)
+
+	w = createPayment("1", model.PaymentDTO{Amount: 100, Method: model.PaymentMethodCash}, &model.EmployeeClaims{UserID: 2, Role: "driver"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+}
+
+func TestVoidPayment_RestoresBalanceDue(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupOrderTestContext()
+	admin := &model.EmployeeClaims{UserID: 1, Role: "admin"}
+
+	db.Create(&model.Order{Status: model.OrderStatusAssigned, TotalAmount: 500, MeetingDate: time.Now()})
+
+	w := createPayment("1", model.PaymentDTO{Amount: 500, Method: model.PaymentMethodCash}, admin)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	voidPayment := func() *httptest.ResponseRecorder {
+		body, _ := json.Marshal(model.VoidPaymentDTO{Reason: "Registered twice"})
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Set("claims", admin)
+		c.Request, _ = http.NewRequest("POST", "/orders/1/payments/1/void", bytes.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Params = gin.Params{{Key: "id", Value: "1"}, {Key: "paymentId", Value: "1"}}
+		handlers.VoidPaymentHandler(c)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, voidPayment().Code)
+	assert.Equal(t, http.StatusConflict, voidPayment().Code)
+
+	var payment model.Payment
+	assert.NoError(t, db.First(&payment, 1).Error)
+	assert.NotNil(t, payment.VoidedAt)
+
+	w = createPayment("1", model.PaymentDTO{Amount: 500, Method: model.PaymentMethodTransfer}, admin)
+	assert.Equal(t, http.StatusCreated, w.Code)
+}
@@ -0,0 +1,74 @@
+package test
+
+import (
+	"dapa/app/handlers"
+	"dapa/app/model"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfitAndLossReport_ComparesWithPreviousPeriods(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupOrderTestContext()
+
+	day := func(year int, month time.Month, d int) time.Time {
+		return time.Date(year, month, d, 0, 0, 0, 0, time.Local)
+	}
+	db.Create(&model.ExpenseType{Type: "Combustible"})
+	db.Create(&model.ExpenseType{Type: "Alquiler"})
+	db.Create(&model.Order{Type: "mudanza", Status: model.OrderStatusDelivered, TotalAmount: 1500})
+	db.Create(&model.Order{Type: "flete", Status: model.OrderStatusDelivered, TotalAmount: 500})
+
+	order := uint(1)
+	db.Create(&model.Payment{OrderID: 1, Amount: 1000, Method: model.PaymentMethodCash, PaidAt: day(2025, 1, 15)})
+	db.Create(&model.Payment{OrderID: 1, Amount: 500, Method: model.PaymentMethodCash, PaidAt: day(2025, 3, 2)})
+	db.Create(&model.Payment{OrderID: 2, Amount: 500, Method: model.PaymentMethodCard, PaidAt: day(2025, 2, 20)})
+	db.Create(&model.Payment{OrderID: 2, Amount: 800, Method: model.PaymentMethodCard, PaidAt: day(2024, 11, 5)})
+	db.Create(&model.Expense{Date: day(2025, 1, 15), TypeID: 1, Amount: 400, OrderID: &order})
+	db.Create(&model.Expense{Date: day(2025, 2, 1), TypeID: 2, Amount: 600})
+	db.Create(&model.Expense{Date: day(2024, 2, 1), TypeID: 2, Amount: 600})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/reports/pnl?period=quarter&date=2025-02-10&groupBy=month", nil)
+	handlers.ProfitAndLossReport(c)
+
+	var resp struct {
+		Data model.PnLReportDTO `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	report := resp.Data
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []model.PnLLine{{Label: "mudanza", Amount: 1500}, {Label: "flete", Amount: 500}}, report.Revenue)
+	assert.Equal(t, 1600.0, report.GrossProfit)
+	assert.Equal(t, 1000.0, report.NetProfit)
+	assert.Equal(t, 50.0, report.NetMarginPct)
+	assert.Len(t, report.Months, 3)
+	assert.Equal(t, 1000.0-400, report.Months[0].NetProfit)
+
+	assert.Equal(t, 800.0, report.PreviousPeriod.TotalRevenue)
+	assert.Equal(t, 25.0, *report.PreviousPeriod.NetProfitChangePct)
+	assert.Equal(t, -600.0, report.LastYear.NetProfit)
+	assert.Nil(t, report.LastYear.RevenueChangePct)
+}
+
+func TestProfitAndLossReport_RejectsInvalidPeriods(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupOrderTestContext()
+
+	for _, query := range []string{"period=week", "period=custom&startDate=2025-03-01", "period=custom&startDate=2025-03-01&endDate=2025-02-01"} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("GET", "/reports/pnl?"+query, nil)
+		handlers.ProfitAndLossReport(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
@@ -0,0 +1,107 @@
+package test
+
+import (
+	"bytes"
+	"dapa/app/handlers"
+	"dapa/app/model"
+	"dapa/app/utils"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+)
+
+// Realiza una petición a un handler con las claims del usuario indicado y retorna el código de respuesta
+func policyStatus(claims *model.EmployeeClaims, method, path, id string, body any, handler gin.HandlerFunc) int {
+	payload, _ := json.Marshal(body)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("claims", claims)
+	c.Request, _ = http.NewRequest(method, path, bytes.NewReader(payload))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "id", Value: id}}
+	handler(c)
+	return w.Code
+}
+
+func TestUserPolicy_SelfOrManager(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterValidation("phone", utils.PhoneValidator)
+	}
+	db := setupOrderTestContext()
+	hash, _ := utils.HashPassword("Piloto123!")
+	db.Create(&model.User{Name: "Admin", Email: "admin@dapa.com", Role: "admin", IsActive: true})
+	db.Create(&model.User{Name: "Piloto", Email: "piloto@dapa.com", PasswordHash: hash, Role: "driver", IsActive: true})
+	db.Create(&model.User{Name: "Ayudante", Email: "ayudante@dapa.com", Role: "helper", IsActive: true})
+
+	admin := &model.EmployeeClaims{UserID: 1, Role: "admin"}
+	driver := &model.EmployeeClaims{UserID: 2, Role: "driver"}
+	update := func(role string) model.UserDTO {
+		return model.UserDTO{Name: "Piloto", LastName: "Pérez", Phone: "55551234", Email: "piloto@dapa.com", Role: role}
+	}
+
+	assert.Equal(t, http.StatusOK, policyStatus(driver, "GET", "/users/2", "2", nil, handlers.GetUserHandler))
+	assert.Equal(t, http.StatusForbidden, policyStatus(driver, "GET", "/users/3", "3", nil, handlers.GetUserHandler))
+	assert.Equal(t, http.StatusOK, policyStatus(admin, "GET", "/users/3", "3", nil, handlers.GetUserHandler))
+
+	assert.Equal(t, http.StatusOK, policyStatus(driver, "PUT", "/users/2", "2", update("driver"), handlers.UpdateUserHandler))
+	assert.Equal(t, http.StatusForbidden, policyStatus(driver, "PUT", "/users/2", "2", update("admin"), handlers.UpdateUserHandler))
+	changedEmail := update("driver")
+	changedEmail.Email = "otro@dapa.com"
+	assert.Equal(t, http.StatusForbidden, policyStatus(driver, "PUT", "/users/2", "2", changedEmail, handlers.UpdateUserHandler))
+	assert.Equal(t, http.StatusForbidden, policyStatus(driver, "PUT", "/users/3", "3", update("helper"), handlers.UpdateUserHandler))
+	assert.Equal(t, http.StatusForbidden, policyStatus(admin, "PUT", "/users/1", "1", update("driver"), handlers.UpdateUserHandler))
+	assert.Equal(t, http.StatusOK, policyStatus(admin, "PUT", "/users/2", "2", update("helper"), handlers.UpdateUserHandler))
+
+	var user model.User
+	db.First(&user, 2)
+	assert.Equal(t, "helper", user.Role)
+	assert.Equal(t, "Pérez", user.LastName)
+	assert.True(t, user.IsActive)
+	assert.True(t, utils.CheckPassword("Piloto123!", user.PasswordHash))
+}
+
+func TestCreatePayment_OnlyStaffWhoProgressOrders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupOrderTestContext()
+
+	driverID, helperID := uint(2), uint(3)
+	db.Create(&model.Order{Status: model.OrderStatusAssigned, UserID: &driverID, HelperID: &helperID, TotalAmount: 500, MeetingDate: time.Now()})
+
+	helper := &model.EmployeeClaims{UserID: helperID, Role: "helper"}
+	payment := model.PaymentDTO{Amount: 200, Method: model.PaymentMethodCash}
+	assert.Equal(t, http.StatusForbidden, policyStatus(helper, "POST", "/orders/1/payments", "1", payment, handlers.CreatePaymentHandler))
+
+	var count int64
+	db.Model(&model.Payment{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	driver := &model.EmployeeClaims{UserID: driverID, Role: "driver"}
+	assert.Equal(t, http.StatusCreated, policyStatus(driver, "POST", "/orders/1/payments", "1", payment, handlers.CreatePaymentHandler))
+}
+
+func TestGetSubmission_OnlyAssignedStaff(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupOrderTestContext()
+	db.AutoMigrate(&model.Submission{}, &model.Answer{})
+
+	helperID := uint(3)
+	db.Create(&model.Submission{Status: model.FormStatusApproved})
+	db.Create(&model.Submission{Status: model.FormStatusApproved})
+	db.Create(&model.Order{SubmissionID: 1, HelperID: &helperID, Status: model.OrderStatusAssigned})
+	db.Create(&model.Order{SubmissionID: 2, Status: model.OrderStatusPending})
+
+	helper := &model.EmployeeClaims{UserID: helperID, Role: "helper"}
+	assert.Equal(t, http.StatusOK, policyStatus(helper, "GET", "/form/submissions/1", "1", nil, handlers.GetSubmissionHandler))
+	assert.Equal(t, http.StatusForbidden, policyStatus(helper, "GET", "/form/submissions/2", "2", nil, handlers.GetSubmissionHandler))
+
+	admin := &model.EmployeeClaims{UserID: 1, Role: "admin"}
+	assert.Equal(t, http.StatusOK, policyStatus(admin, "GET", "/form/submissions/2", "2", nil, handlers.GetSubmissionHandler))
+}
@@ -0,0 +1,248 @@
+package test
+
+import (
+	"bytes"
+	"dapa/app/handlers"
+	"dapa/app/jobs"
+	"dapa/app/model"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+var guatemala, _ = time.LoadLocation(model.DefaultChartTimezone)
+
+var movingRule = model.PricingRule{
+	OrderType:              "mudanza",
+	BaseFee:                200,
+	PerKmRate:              5,
+	PerKgRate:              0.5,
+	HelperFee:              100,
+	WeekendSurchargePct:    20,
+	AfterHoursSurchargePct: 10,
+	WorkdayStartHour:       7,
+	WorkdayEndHour:         18,
+	MinimumCharge:          300,
+}
+
+func TestPricingRule_WeekdayWithinBusinessHours(t *testing.T) {
+	// Miércoles a las 10:00
+	breakdown := movingRule.Calculate(model.PricingInput{
+		DistanceKm:  20,
+		WeightKg:    300,
+		Helpers:     2,
+		MeetingDate: time.Date(2025, 3, 12, 10, 0, 0, 0, guatemala),
+	})
+
+	assert.Equal(t, 100.0, breakdown.DistanceFee)
+	assert.Equal(t, 150.0, breakdown.WeightFee)
+	assert.Equal(t, 200.0, breakdown.HelperFee)
+	assert.Zero(t, breakdown.WeekendSurcharge)
+	assert.Zero(t, breakdown.AfterHoursSurcharge)
+	assert.Equal(t, 650.0, breakdown.CalculatedTotal)
+}
+
+func TestPricingRule_WeekendAfterHoursSurcharges(t *testing.T) {
+	// Sábado a las 19:00
+	breakdown := movingRule.Calculate(model.PricingInput{
+		DistanceKm:  20,
+		WeightKg:    300,
+		Helpers:     2,
+		MeetingDate: time.Date(2025, 3, 15, 19, 0, 0, 0, guatemala),
+	})
+
+	assert.Equal(t, 130.0, breakdown.WeekendSurcharge)
+	assert.Equal(t, 65.0, breakdown.AfterHoursSurcharge)
+	assert.Equal(t, 845.0, breakdown.CalculatedTotal)
+}
+
+func TestPricingRule_EvaluatesSurchargesInBusinessTime(t *testing.T) {
+	input := model.PricingInput{DistanceKm: 20, WeightKg: 300, Helpers: 2}
+
+	// Viernes 23:30 en Guatemala, sábado 05:30 en UTC: no es fin de semana pero sí fuera de horario
+	input.MeetingDate = time.Date(2025, 3, 15, 5, 30, 0, 0, time.UTC)
+	breakdown := movingRule.Calculate(input)
+	assert.Zero(t, breakdown.WeekendSurcharge)
+	assert.Equal(t, 65.0, breakdown.AfterHoursSurcharge)
+
+	// Miércoles 17:30 en Guatemala, 23:30 en UTC: todavía dentro del horario
+	input.MeetingDate = time.Date(2025, 3, 12, 23, 30, 0, 0, time.UTC)
+	breakdown = movingRule.Calculate(input)
+	assert.Zero(t, breakdown.AfterHoursSurcharge)
+
+	// Miércoles 06:00 en Guatemala, 12:00 en UTC: antes de abrir
+	input.MeetingDate = time.Date(2025, 3, 12, 12, 0, 0, 0, time.UTC)
+	breakdown = movingRule.Calculate(input)
+	assert.Equal(t, 65.0, breakdown.AfterHoursSurcharge)
+
+	// Sábado 19:00 en Guatemala, domingo 01:00 en UTC
+	input.MeetingDate = time.Date(2025, 3, 16, 1, 0, 0, 0, time.UTC)
+	breakdown = movingRule.Calculate(input)
+	assert.Equal(t, 130.0, breakdown.WeekendSurcharge)
+	assert.Equal(t, 65.0, breakdown.AfterHoursSurcharge)
+}
+
+func TestPricingRule_AppliesMinimumCharge(t *testing.T) {
+	breakdown := movingRule.Calculate(model.PricingInput{
+		DistanceKm:  2,
+		MeetingDate: time.Date(2025, 3, 12, 10, 0, 0, 0, guatemala),
+	})
+
+	assert.Equal(t, 90.0, breakdown.MinimumAdjustment)
+	assert.Equal(t, 300.0, breakdown.CalculatedTotal)
+}
+
+func TestOverrideQuote_CreatesNewVersion(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupQuoteTestContext()
+
+	original := model.Quote{SubmissionID: 4, Version: 1, Status: model.QuoteStatusDraft, OrderType: "mudanza", Total: 650}
+	original.CalculatedTotal = 650
+	db.Create(&original)
+
+	body, _ := json.Marshal(model.OverrideQuoteDTO{Amount: 600, Reason: "Cliente frecuente"})
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("claims", &model.EmployeeClaims{UserID: 1, Role: "admin"})
+	c.Request, _ = http.NewRequest("PATCH", "/quotes/1/override", bytes.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "id", Value: "1"}}
+
+	handlers.OverrideQuoteHandler(c)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var quotes []model.Quote
+	db.Order("version").Find(&quotes)
+	assert.Len(t, quotes, 2)
+	assert.Equal(t, model.QuoteStatusSuperseded, quotes[0].Status)
+	assert.Equal(t, 2, quotes[1].Version)
+	assert.Equal(t, 600.0, quotes[1].Total)
+	assert.Equal(t, 650.0, quotes[1].CalculatedTotal)
+	assert.Equal(t, "Cliente frecuente", *quotes[1].OverrideReason)
+}
+
+func setupQuoteTestContext() *gorm.DB {
+	db := setupOrderTestContext()
+	db.AutoMigrate(&model.Submission{}, &model.Quote{}, &model.QuoteToken{})
+	return db
+}
+
+func TestAcceptQuoteByToken_CreatesOrder(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupQuoteTestContext()
+
+	db.Create(&model.Submission{Status: model.FormStatusPending})
+	db.Create(&model.Quote{SubmissionID: 1, Version: 1, Status: model.QuoteStatusDraft, ClientName: "Ana", Origin: "Zona 1", Destination: "Mixco", OrderType: "mudanza", MeetingDate: time.Now().AddDate(0, 0, 3), Total: 650})
+	db.Create(&model.QuoteToken{QuoteID: 1, Token: "abc", Expiry: time.Now().Add(time.Hour)})
+
+	body, _ := json.Marshal(model.QuoteTokenDTO{Token: "abc"})
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/quotes/review/accept", bytes.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	handlers.AcceptQuoteByTokenHandler(c)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var order model.Order
+	db.First(&order)
+	assert.Equal(t, uint(1), *order.QuoteID)
+	assert.Equal(t, 650.0, order.TotalAmount)
+	assert.Equal(t, "Mixco", order.Destination)
+
+	var submission model.Submission
+	db.First(&submission, 1)
+	assert.Equal(t, model.FormStatusApproved, submission.Status)
+}
+
+func TestAcceptQuoteByToken_RejectsSubmissionWithOrder(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupQuoteTestContext()
+
+	db.Create(&model.Submission{Status: model.FormStatusApproved})
+	db.Create(&model.Order{SubmissionID: 1, Status: model.OrderStatusPending, MeetingDate: time.Now()})
+	db.Create(&model.Quote{SubmissionID: 1, Version: 1, Status: model.QuoteStatusDraft, ClientName: "Ana", Origin: "Zona 1", Destination: "Mixco", OrderType: "mudanza", MeetingDate: time.Now().AddDate(0, 0, 3), Total: 650})
+	db.Create(&model.QuoteToken{QuoteID: 1, Token: "abc", Expiry: time.Now().Add(time.Hour)})
+
+	body, _ := json.Marshal(model.QuoteTokenDTO{Token: "abc"})
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/quotes/review/accept", bytes.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	handlers.AcceptQuoteByTokenHandler(c)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	var orders int64
+	db.Model(&model.Order{}).Count(&orders)
+	assert.Equal(t, int64(1), orders)
+
+	var quote model.Quote
+	db.First(&quote, 1)
+	assert.Equal(t, model.QuoteStatusDraft, quote.Status)
+}
+
+func TestCreateOrder_SupersedesDraftQuotes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupQuoteTestContext()
+
+	db.Create(&model.Submission{Status: model.FormStatusPending})
+	db.Create(&model.Quote{SubmissionID: 1, Version: 1, Status: model.QuoteStatusDraft, OrderType: "mudanza", Total: 650})
+
+	dto := model.AcceptSubmissionDTO{
+		SubmissionID: 1,
+		ClientName:   "Ana",
+		ClientPhone:  "55551234",
+		Origin:       "Zona 1",
+		Destination:  "Mixco",
+		TotalAmount:  700,
+		Details:      "Mudanza",
+		Type:         "mudanza",
+		MeetingDate:  time.Now().AddDate(0, 0, 3),
+	}
+
+	createOrder := func() int {
+		body, _ := json.Marshal(dto)
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Set("claims", &model.EmployeeClaims{UserID: 1, Role: "admin"})
+		c.Request, _ = http.NewRequest("POST", "/orders", bytes.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handlers.CreateOrderHandler(c)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusCreated, createOrder())
+	assert.Equal(t, http.StatusConflict, createOrder())
+
+	var quote model.Quote
+	db.First(&quote, 1)
+	assert.Equal(t, model.QuoteStatusSuperseded, quote.Status)
+}
+
+func TestExpireQuotes_ExpiresSubmission(t *testing.T) {
+	db := setupQuoteTestContext()
+
+	db.Create(&model.Submission{Status: model.FormStatusPending})
+	db.Create(&model.Quote{SubmissionID: 1, Version: 1, Status: model.QuoteStatusDraft, OrderType: "mudanza"})
+	db.Create(&model.QuoteToken{QuoteID: 1, Token: "abc", Expiry: time.Now().Add(-time.Hour)})
+
+	assert.NoError(t, jobs.ExpireQuotes(time.Now()))
+
+	var quote model.Quote
+	db.First(&quote, 1)
+	assert.Equal(t, model.QuoteStatusExpired, quote.Status)
+
+	var submission model.Submission
+	db.First(&submission, 1)
+	assert.Equal(t, model.FormStatusExpired, submission.Status)
+}
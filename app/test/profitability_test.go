@@ -0,0 +1,102 @@
+package test
+
+import (
+	"dapa/app/handlers"
+	"dapa/app/model"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVehicleProfitabilityReport_AttributesExpenses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupOrderTestContext()
+
+	date := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+	truck, van := uint(1), uint(2)
+	db.Create(&model.Vehicle{Brand: "Isuzu", Model: "NPR", LicensePlate: "C100AAA"})
+	db.Create(&model.Vehicle{Brand: "Toyota", Model: "Hiace", LicensePlate: "C200BBB"})
+	db.Create(&model.ExpenseType{Type: "Combustible"})
+
+	db.Create(&model.Order{VehicleID: &truck, Type: "mudanza", Status: model.OrderStatusDelivered, TotalAmount: 1000, MeetingDate: date})
+	db.Create(&model.Order{VehicleID: &van, Type: "flete", Status: model.OrderStatusDelivered, TotalAmount: 400, MeetingDate: date})
+	db.Create(&model.Order{VehicleID: &van, Type: "flete", Status: model.OrderStatusCancelled, TotalAmount: 900, MeetingDate: date})
+
+	db.Create(&model.Payment{OrderID: 1, Amount: 1000, Method: model.PaymentMethodCash, PaidAt: date})
+	db.Create(&model.Payment{OrderID: 2, Amount: 400, Method: model.PaymentMethodCash, PaidAt: date})
+
+	order1, order2 := uint(1), uint(2)
+	db.Create(&model.Expense{Date: date, TypeID: 1, Amount: 300, OrderID: &order1})
+	db.Create(&model.Expense{Date: date, TypeID: 1, Amount: 500, OrderID: &order2})
+	db.Create(&model.Expense{Date: date, TypeID: 1, Amount: 100, VehicleID: &truck})
+	db.Create(&model.Expense{Date: date.AddDate(0, 2, 0), TypeID: 1, Amount: 999, VehicleID: &truck})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/reports/profitability/vehicles?startDate=2025-03-01&endDate=2025-03-31", nil)
+	handlers.VehicleProfitabilityReport(c)
+
+	var resp struct {
+		Data []model.VehicleProfitabilityDTO `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, resp.Data, 2)
+	assert.Equal(t, "C100AAA", resp.Data[0].LicensePlate)
+	assert.Equal(t, model.NewProfitability(1000, 400), resp.Data[0].Profitability)
+	assert.Equal(t, 60.0, resp.Data[0].MarginPct)
+	assert.Equal(t, 1, resp.Data[1].Orders)
+	assert.Equal(t, -100.0, resp.Data[1].Margin)
+}
+
+func TestOrderProfitabilityReport_UsesCollectedRevenue(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupOrderTestContext()
+
+	date := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+	db.Create(&model.ExpenseType{Type: "Combustible"})
+
+	db.Create(&model.Order{Type: "mudanza", Status: model.OrderStatusDelivered, TotalAmount: 1000, MeetingDate: date})
+	db.Create(&model.Order{Type: "flete", Status: model.OrderStatusDelivered, TotalAmount: 400, MeetingDate: date})
+	db.Create(&model.Order{Type: "flete", Status: model.OrderStatusCancelled, TotalAmount: 900, MeetingDate: date})
+
+	db.Create(&model.Payment{OrderID: 1, Amount: 600, Method: model.PaymentMethodCash, PaidAt: date})
+	db.Create(&model.Payment{OrderID: 2, Amount: 400, Method: model.PaymentMethodTransfer, PaidAt: date})
+
+	voidedAt := time.Now()
+	db.Create(&model.Payment{OrderID: 1, Amount: 400, Method: model.PaymentMethodCash, PaidAt: date, VoidedAt: &voidedAt})
+
+	order1 := uint(1)
+	db.Create(&model.Expense{Date: date, TypeID: 1, Amount: 100, OrderID: &order1})
+
+	report := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("GET", "/reports/profitability/orders?"+query, nil)
+		handlers.OrderProfitabilityReport(c)
+		return w
+	}
+
+	w := report("startDate=2025-03-01&endDate=2025-03-31&sort=revenue&order=asc")
+
+	var resp struct {
+		Data []model.OrderProfitabilityDTO `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, resp.Data, 2)
+	assert.Equal(t, uint(2), resp.Data[0].OrderID)
+	assert.Equal(t, model.NewProfitability(400, 0), resp.Data[0].Profitability)
+	assert.Equal(t, uint(1), resp.Data[1].OrderID)
+	assert.Equal(t, model.NewProfitability(600, 100), resp.Data[1].Profitability)
+
+	assert.Equal(t, http.StatusBadRequest, report("order=sideways").Code)
+	assert.Equal(t, http.StatusBadRequest, report("sort=client").Code)
+}
@@ -0,0 +1,100 @@
+package test
+
+import (
+	"bytes"
+	"dapa/app/handlers"
+	"dapa/app/model"
+	"dapa/storage"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+var pngImage = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func submitProofOfDelivery(orderID string, signature []byte, claims *model.EmployeeClaims) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("recipientName", "Ana López")
+	part, _ := form.CreateFormFile("signature", "firma.png")
+	part.Write(signature)
+	part, _ = form.CreateFormFile("photos", "sala.png")
+	part.Write(pngImage)
+	form.Close()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("claims", claims)
+	c.Request, _ = http.NewRequest("POST", "/orders/"+orderID+"/proof-of-delivery", &body)
+	c.Request.Header.Set("Content-Type", form.FormDataContentType())
+	c.Params = gin.Params{{Key: "id", Value: orderID}}
+
+	handlers.SubmitProofOfDeliveryHandler(c)
+	return w
+}
+
+func TestChangeOrderStatus_DeliveredRequiresProofOfDelivery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupOrderTestContext()
+	storage.Files = storage.NewLocalStorage(t.TempDir(), "http://localhost:8080/api/files", "secret")
+
+	driverID := uint(7)
+	claims := &model.EmployeeClaims{UserID: driverID, Role: "driver"}
+	db.Create(&model.Order{UserID: &driverID, Status: model.OrderStatusCollected, MeetingDate: time.Now()})
+
+	w := changeOrderStatus("1", model.OrderStatusDelivered, claims)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = submitProofOfDelivery("1", []byte("not an image"), claims)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = submitProofOfDelivery("1", pngImage, claims)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var proof model.ProofOfDelivery
+	db.Preload("Files").First(&proof)
+	assert.Len(t, proof.Files, 2)
+	assert.Equal(t, model.AttachmentCategorySignature, proof.Files[0].Category)
+
+	content, err := storage.Files.Open(proof.Files[0].FileKey)
+	assert.NoError(t, err)
+	content.Close()
+
+	w = changeOrderStatus("1", model.OrderStatusDelivered, claims)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestGetProofOfDelivery_AssignedDriverCanRead(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupOrderTestContext()
+	storage.Files = storage.NewLocalStorage(t.TempDir(), "http://localhost:8080/api/files", "secret")
+
+	driverID := uint(7)
+	claims := &model.EmployeeClaims{UserID: driverID, Role: "driver"}
+	db.Create(&model.Order{UserID: &driverID, Status: model.OrderStatusCollected, MeetingDate: time.Now()})
+
+	w := submitProofOfDelivery("1", pngImage, claims)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	getProof := func(claims *model.EmployeeClaims) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Set("claims", claims)
+		c.Request, _ = http.NewRequest("GET", "/orders/1/proof-of-delivery", nil)
+		c.Params = gin.Params{{Key: "id", Value: "1"}}
+
+		handlers.GetProofOfDeliveryHandler(c)
+		return w
+	}
+
+	w = getProof(claims)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = getProof(&model.EmployeeClaims{UserID: 8, Role: "driver"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
@@ -0,0 +1,109 @@
+package test
+
+import (
+	"bytes"
+	"dapa/app/handlers"
+	"dapa/app/middlewares"
+	"dapa/app/model"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func sendRole(method string, id string, input model.RoleDTO, handler gin.HandlerFunc) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(input)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(method, "/roles/"+id, bytes.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "id", Value: id}}
+	handler(c)
+	return w
+}
+
+// Realiza una petición a una ruta que requiere el permiso indicado y retorna el código de respuesta
+func permissionStatus(role string, permission model.Permission) int {
+	router := gin.New()
+	router.GET("/resource", func(c *gin.Context) {
+		c.Set("claims", &model.EmployeeClaims{UserID: 1, Role: role})
+	}, middlewares.RequirePermission(permission), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/resource", nil)
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestRequirePermission_AppliesCustomRoles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupOrderTestContext()
+
+	dispatcher := model.RoleDTO{Name: "dispatcher", Permissions: []model.Permission{model.PermissionOrdersReadAll, model.PermissionOrdersAssign}}
+	assert.Equal(t, http.StatusCreated, sendRole("POST", "", dispatcher, handlers.CreateRoleHandler).Code)
+	assert.Equal(t, http.StatusConflict, sendRole("POST", "", dispatcher, handlers.CreateRoleHandler).Code)
+
+	invalid := model.RoleDTO{Name: "accountant", Permissions: []model.Permission{"reports:everything"}}
+	assert.Equal(t, http.StatusBadRequest, sendRole("POST", "", invalid, handlers.CreateRoleHandler).Code)
+
+	assert.Equal(t, http.StatusOK, permissionStatus("dispatcher", model.PermissionOrdersAssign))
+	assert.Equal(t, http.StatusForbidden, permissionStatus("dispatcher", model.PermissionReportsFinancial))
+	assert.Equal(t, http.StatusOK, permissionStatus("admin", model.PermissionReportsFinancial))
+	assert.Equal(t, http.StatusForbidden, permissionStatus("driver", model.PermissionOrdersAssign))
+
+	dispatcher.Permissions = append(dispatcher.Permissions, model.PermissionReportsFinancial)
+	assert.Equal(t, http.StatusOK, sendRole("PUT", "4", dispatcher, handlers.UpdateRoleHandler).Code)
+	assert.Equal(t, http.StatusOK, permissionStatus("dispatcher", model.PermissionReportsFinancial))
+}
+
+func TestUpdateRole_ProtectsSystemRoles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupOrderTestContext()
+
+	admin := model.RoleDTO{Name: "admin", Permissions: []model.Permission{model.PermissionOrdersReadAll}}
+	assert.Equal(t, http.StatusConflict, sendRole("PUT", "1", admin, handlers.UpdateRoleHandler).Code)
+
+	renamed := model.RoleDTO{Name: "pilot", Permissions: []model.Permission{model.PermissionOrdersReadOwn}}
+	assert.Equal(t, http.StatusConflict, sendRole("PUT", "2", renamed, handlers.UpdateRoleHandler).Code)
+	assert.Equal(t, http.StatusConflict, sendRole("DELETE", "3", model.RoleDTO{}, handlers.DeleteRoleHandler).Code)
+
+	var role model.Role
+	db.Preload("Permissions").First(&role, 1)
+	assert.Len(t, role.Permissions, len(model.Permissions))
+}
+
+func TestGetOrders_OwnPermissionOnlyListsAssignedOrders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupOrderTestContext()
+
+	helperID, otherID := uint(5), uint(6)
+	db.Create(&model.Order{HelperID: &helperID, Status: model.OrderStatusAssigned})
+	db.Create(&model.Order{HelperID: &otherID, Status: model.OrderStatusAssigned})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("claims", &model.EmployeeClaims{UserID: helperID, Role: "helper"})
+	c.Request, _ = http.NewRequest("GET", "/orders", nil)
+	handlers.GetOrdersHandler(c)
+
+	var resp struct {
+		Data []model.Order `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, resp.Data, 1)
+	assert.Equal(t, uint(1), resp.Data[0].ID)
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Set("claims", &model.EmployeeClaims{UserID: helperID, Role: "helper"})
+	c.Request, _ = http.NewRequest("GET", "/orders/2", nil)
+	c.Params = gin.Params{{Key: "id", Value: "2"}}
+	handlers.GetOrderHandler(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
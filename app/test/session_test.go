@@ -0,0 +1,137 @@
+package test
+
+import (
+	"bytes"
+	"dapa/app/handlers"
+	"dapa/app/middlewares"
+	"dapa/app/model"
+	"dapa/app/utils"
+	"dapa/database"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupSessionTestContext(t *testing.T) *gorm.DB {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterValidation("password", utils.PasswordValidator)
+	}
+
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	db.AutoMigrate(&model.User{}, &model.Session{}, &model.LoginAttempt{}, &model.Attachment{})
+	database.DB = db
+
+	hash, err := utils.HashPassword("Secreta123!")
+	assert.NoError(t, err)
+	db.Create(&model.User{Name: "Piloto", Email: "piloto@dapa.com", PasswordHash: hash, Role: "driver", IsActive: true})
+	return db
+}
+
+// Inicia sesión y retorna el token de acceso y el refresh token de la cookie
+func login(t *testing.T) (string, string) {
+	body, _ := json.Marshal(model.LoginDTO{Email: "piloto@dapa.com", Password: "Secreta123!"})
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/login", bytes.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	handlers.LoginHandler(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	return responseToken(w), refreshCookie(w)
+}
+
+func refresh(refreshToken string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/auth/refresh", nil)
+	c.Request.AddCookie(&http.Cookie{Name: "refresh_token", Value: refreshToken})
+	handlers.RefreshTokenHandler(c)
+	return w
+}
+
+func responseToken(w *httptest.ResponseRecorder) string {
+	var resp struct {
+		Data string `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return resp.Data
+}
+
+func refreshCookie(w *httptest.ResponseRecorder) string {
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == "refresh_token" {
+			return cookie.Value
+		}
+	}
+	return ""
+}
+
+// Realiza una petición autenticada a una ruta protegida y retorna el código de respuesta
+func authenticatedStatus(token string) int {
+	router := gin.New()
+	router.GET("/protected", middlewares.AuthMiddleware(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/protected", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestRefreshToken_RotatesAndRevokesOnReuse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupSessionTestContext(t)
+
+	token, refreshToken := login(t)
+	assert.NotEmpty(t, refreshToken)
+	assert.Equal(t, http.StatusOK, authenticatedStatus(token))
+
+	w := refresh(refreshToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+	rotated := refreshCookie(w)
+	assert.NotEqual(t, refreshToken, rotated)
+	assert.Equal(t, http.StatusOK, authenticatedStatus(responseToken(w)))
+
+	// Reutilizar el token anterior cierra la sesión
+	assert.Equal(t, http.StatusUnauthorized, refresh(refreshToken).Code)
+	assert.Equal(t, http.StatusUnauthorized, refresh(rotated).Code)
+	assert.Equal(t, http.StatusUnauthorized, authenticatedStatus(token))
+
+	var session model.Session
+	db.First(&session)
+	assert.NotNil(t, session.RevokedAt)
+}
+
+func TestLogoutAndDeactivation_RevokeSessions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupSessionTestContext(t)
+
+	token, refreshToken := login(t)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/auth/logout", nil)
+	c.Request.AddCookie(&http.Cookie{Name: "refresh_token", Value: refreshToken})
+	handlers.LogoutHandler(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusUnauthorized, authenticatedStatus(token))
+	assert.Equal(t, http.StatusUnauthorized, refresh(refreshToken).Code)
+
+	token, _ = login(t)
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("DELETE", "/users/1", nil)
+	c.Params = gin.Params{{Key: "id", Value: "1"}}
+	handlers.DeleteUserHandler(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusUnauthorized, authenticatedStatus(token))
+}
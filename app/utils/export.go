@@ -0,0 +1,254 @@
+package utils
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	MIMECSV  = "text/csv"
+	MIMEXLSX = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Tabla con los encabezados y valores a exportar
+// Los valores pueden ser textos, números, booleanos o fechas
+type Table struct {
+	Headers []string
+	Rows    [][]any
+}
+
+// Envía la información en JSON o, si se solicita con el parámetro format o el encabezado Accept, como hoja de cálculo
+// Recibe la información de la respuesta en JSON y las filas a exportar, que pueden ser una Table,
+// un struct o un slice de structs cuyos campos a exportar tienen la etiqueta export con el encabezado
+// Las filas ya están en memoria; el archivo se escribe en la respuesta conforme se genera
+// El nombre se usa para el archivo y la hoja de cálculo
+func RespondWithExport(c *gin.Context, data any, rows any, message string, name string) {
+	format := c.Query("format")
+	if format == "" {
+		switch c.NegotiateFormat(gin.MIMEJSON, MIMECSV, MIMEXLSX) {
+		case MIMECSV:
+			format = "csv"
+		case MIMEXLSX:
+			format = "xlsx"
+		}
+	}
+
+	if format == "" || format == "json" {
+		RespondWithSuccess(c, http.StatusOK, data, message)
+		return
+	}
+
+	if format != "csv" && format != "xlsx" {
+		RespondWithCustomError(c, http.StatusBadRequest, "Format must be json, csv or xlsx", "Invalid export format")
+		return
+	}
+
+	table, err := NewTable(rows)
+	if err != nil {
+		RespondWithInternalError(c, "Error exporting data")
+		return
+	}
+
+	fileName := fmt.Sprintf("%s-%s.%s", name, time.Now().Format("2006-01-02"), format)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, fileName))
+
+	if format == "csv" {
+		c.Header("Content-Type", MIMECSV+"; charset=utf-8")
+		c.Status(http.StatusOK)
+		err = WriteCSV(c.Writer, table)
+	} else {
+		c.Header("Content-Type", MIMEXLSX)
+		c.Status(http.StatusOK)
+		err = WriteXLSX(c.Writer, name, table)
+	}
+
+	// El encabezado ya se envió, así que solo se puede registrar el error y cortar la respuesta
+	if err != nil {
+		log.Printf("Error exporting %s: %v", fileName, err)
+		c.Error(err)
+		c.Abort()
+	}
+}
+
+// Construye la tabla a partir de una Table, un struct o un slice de structs
+// Solo se exportan los campos con la etiqueta export; los structs embebidos aportan sus propios campos
+func NewTable(rows any) (Table, error) {
+	if table, ok := rows.(Table); ok {
+		for _, row := range table.Rows {
+			for i, value := range row {
+				row[i] = normalizeValue(reflect.ValueOf(value))
+			}
+		}
+		return table, nil
+	}
+
+	value := reflect.ValueOf(rows)
+	if !value.IsValid() {
+		return Table{}, fmt.Errorf("can't export a nil value")
+	}
+	if value.Kind() != reflect.Slice {
+		slice := reflect.MakeSlice(reflect.SliceOf(value.Type()), 0, 1)
+		value = reflect.Append(slice, value)
+	}
+
+	elem := value.Type().Elem()
+	if elem.Kind() == reflect.Pointer {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct {
+		return Table{}, fmt.Errorf("can't export values of type %s", value.Type())
+	}
+
+	var table Table
+	var columns [][]int
+	exportColumns(elem, nil, &table.Headers, &columns)
+
+	table.Rows = make([][]any, value.Len())
+	for i := range table.Rows {
+		item := reflect.Indirect(value.Index(i))
+		row := make([]any, len(columns))
+		for j, index := range columns {
+			if item.IsValid() {
+				row[j] = cellValue(item, index)
+			}
+		}
+		table.Rows[i] = row
+	}
+	return table, nil
+}
+
+// Agrega los encabezados y los índices de los campos exportables del struct
+func exportColumns(t reflect.Type, parent []int, headers *[]string, columns *[][]int) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("export")
+		if tag == "-" || !field.IsExported() {
+			continue
+		}
+
+		index := append(append([]int{}, parent...), i)
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+
+		if field.Anonymous && fieldType.Kind() == reflect.Struct && fieldType != timeType {
+			exportColumns(fieldType, index, headers, columns)
+			continue
+		}
+
+		if tag != "" {
+			*headers = append(*headers, tag)
+			*columns = append(*columns, index)
+		}
+	}
+}
+
+// Obtiene el valor del campo; retorna nil si el campo o alguno de sus padres es un puntero nulo
+func cellValue(item reflect.Value, index []int) any {
+	field, err := item.FieldByIndexErr(index)
+	if err != nil {
+		return nil
+	}
+	return normalizeValue(field)
+}
+
+// Convierte el valor a uno de los tipos que se pueden escribir en una celda
+func normalizeValue(field reflect.Value) any {
+	if !field.IsValid() {
+		return nil
+	}
+
+	if field.Kind() == reflect.Pointer {
+		if field.IsNil() {
+			return nil
+		}
+		field = field.Elem()
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		return field.String()
+	case reflect.Bool:
+		return field.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return field.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(field.Uint())
+	case reflect.Float32, reflect.Float64:
+		return field.Float()
+	}
+	return field.Interface()
+}
+
+// Escribe la tabla como CSV en UTF-8 con BOM para que Excel reconozca los acentos
+func WriteCSV(w io.Writer, table Table) error {
+	if _, err := w.Write([]byte("\xEF\xBB\xBF")); err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(table.Headers); err != nil {
+		return err
+	}
+
+	record := make([]string, len(table.Headers))
+	for _, row := range table.Rows {
+		for i, value := range row {
+			record[i] = csvValue(value)
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func csvValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		// Evita que las hojas de cálculo interpreten el texto como una fórmula
+		if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+			return "'" + v
+		}
+		return v
+	case bool:
+		if v {
+			return "Sí"
+		}
+		return "No"
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', 2, 64)
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		if isDate(v) {
+			return v.Format("2006-01-02")
+		}
+		return v.Format("2006-01-02 15:04")
+	}
+	return fmt.Sprint(value)
+}
+
+// Indica si la fecha no tiene hora, como las columnas de tipo date
+func isDate(t time.Time) bool {
+	return t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 && t.Nanosecond() == 0
+}
@@ -10,15 +10,19 @@ import (
 
 var jwtSecret = []byte(EnvMustGet("JWT_SECRET"))
 
+// Duración de los tokens de acceso, después se deben renovar con el refresh token de la sesión
+const AccessTokenDuration = 15 * time.Minute
+
 // Genera un token JWT para un usuario
-// Recibe el usuario como parámetro
+// Recibe el usuario y la sesión a la que pertenece el token como parámetros
 // Retorna el token como string
-func GenerateToken(user *model.User) (string, error) {
-	expirationTime := time.Now().Add(24 * time.Hour)
+func GenerateToken(user *model.User, sessionID uint) (string, error) {
+	expirationTime := time.Now().Add(AccessTokenDuration)
 
 	claims := &model.EmployeeClaims{
-		UserID: user.ID,
-		Role:   user.Role,
+		UserID:    user.ID,
+		Role:      user.Role,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -4,15 +4,23 @@ import (
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
+	"runtime"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
 var resetSecret = []byte(EnvMustGet("RESET_SECRET"))
 
+// Limita los hashes y comparaciones de bcrypt simultáneos, que ocupan el CPU por completo
+// Una ráfaga de inicios de sesión espera su turno en lugar de saturar el servidor
+var bcryptSlots = make(chan struct{}, runtime.NumCPU())
+
 // Hashea una contaseña en texto plano utilizando bcrypt
 // Retorna el hash como string o un error
 func HashPassword(password string) (string, error) {
+	bcryptSlots <- struct{}{}
+	defer func() { <-bcryptSlots }()
+
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
 	return string(bytes), err
 }
@@ -27,6 +35,9 @@ func HashToken(token string) string {
 // Compara una contraseña en texto plano contra un hash
 // Retorna el valor de la comparación como boolean
 func CheckPassword(password string, hash string) bool {
+	bcryptSlots <- struct{}{}
+	defer func() { <-bcryptSlots }()
+
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	return err == nil
 }
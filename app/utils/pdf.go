@@ -0,0 +1,148 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Dimensiones de una página A4 en puntos
+const (
+	PDFPageWidth  = 595.0
+	PDFPageHeight = 842.0
+)
+
+// Fuentes estándar disponibles en cualquier lector de PDF
+type PDFFont string
+
+const (
+	PDFFontRegular PDFFont = "F1"
+	PDFFontBold    PDFFont = "F2"
+	PDFFontMono    PDFFont = "F3"
+)
+
+var pdfFontNames = []struct {
+	key  PDFFont
+	name string
+}{
+	{PDFFontRegular, "Helvetica"},
+	{PDFFontBold, "Helvetica-Bold"},
+	{PDFFontMono, "Courier"},
+}
+
+// Documento PDF sencillo compuesto por texto y líneas
+// Las coordenadas se miden en puntos desde la esquina superior izquierda de la página
+type PDFDocument struct {
+	pages []*bytes.Buffer
+}
+
+func NewPDFDocument() *PDFDocument {
+	doc := &PDFDocument{}
+	doc.AddPage()
+	return doc
+}
+
+// Agrega una página en blanco; los elementos siguientes se dibujan en ella
+func (d *PDFDocument) AddPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+}
+
+// Escribe una línea de texto a partir de la posición indicada
+func (d *PDFDocument) Text(x, y, size float64, font PDFFont, text string) {
+	fmt.Fprintf(d.current(), "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, PDFPageHeight-y, pdfEscape(text))
+}
+
+// Escribe una línea de texto alineada a la derecha de la posición indicada
+// Utiliza la fuente monoespaciada para conocer el ancho del texto
+func (d *PDFDocument) TextRight(x, y, size float64, text string) {
+	width := float64(len([]rune(text))) * size * 0.6
+	d.Text(x-width, y, size, PDFFontMono, text)
+}
+
+// Dibuja una línea recta entre dos puntos
+func (d *PDFDocument) Line(x1, y1, x2, y2 float64) {
+	fmt.Fprintf(d.current(), "0.5 w %.2f %.2f m %.2f %.2f l S\n", x1, PDFPageHeight-y1, x2, PDFPageHeight-y2)
+}
+
+// Genera el contenido del archivo PDF
+func (d *PDFDocument) Bytes() []byte {
+	var out bytes.Buffer
+	var offsets []int
+
+	// Los objetos se numeran: catálogo, árbol de páginas, fuentes y luego cada página con su contenido
+	fontStart := 3
+	pageStart := fontStart + len(pdfFontNames)
+	objects := pageStart + 2*len(d.pages) - 1
+
+	write := func(format string, args ...any) {
+		fmt.Fprintf(&out, format, args...)
+	}
+	begin := func(id int) {
+		offsets = append(offsets, out.Len())
+		write("%d 0 obj\n", id)
+	}
+
+	write("%%PDF-1.4\n%%\xe2\xe3\xcf\xd3\n")
+
+	begin(1)
+	write("<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", pageStart+2*i)
+	}
+	begin(2)
+	write("<< /Type /Pages /Kids [%s] /Count %d >>\nendobj\n", strings.Join(kids, " "), len(d.pages))
+
+	fonts := make([]string, len(pdfFontNames))
+	for i, font := range pdfFontNames {
+		begin(fontStart + i)
+		write("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>\nendobj\n", font.name)
+		fonts[i] = fmt.Sprintf("/%s %d 0 R", font.key, fontStart+i)
+	}
+
+	for i, page := range d.pages {
+		id := pageStart + 2*i
+		begin(id)
+		write("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << %s >> >> /Contents %d 0 R >>\nendobj\n",
+			PDFPageWidth, PDFPageHeight, strings.Join(fonts, " "), id+1)
+
+		begin(id + 1)
+		write("<< /Length %d >>\nstream\n", page.Len())
+		out.Write(page.Bytes())
+		write("endstream\nendobj\n")
+	}
+
+	xref := out.Len()
+	write("xref\n0 %d\n0000000000 65535 f \n", objects+1)
+	for _, offset := range offsets {
+		write("%010d 00000 n \n", offset)
+	}
+	write("trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", objects+1, xref)
+
+	return out.Bytes()
+}
+
+func (d *PDFDocument) current() *bytes.Buffer {
+	return d.pages[len(d.pages)-1]
+}
+
+// Convierte el texto a la codificación de las fuentes estándar y escapa los caracteres reservados
+// Los caracteres fuera de Latin-1 se reemplazan por '?'
+func pdfEscape(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(byte(r))
+		case r == '\n' || r == '\r' || r == '\t':
+			b.WriteByte(' ')
+		case r < 0x20 || r > 0xff:
+			b.WriteByte('?')
+		default:
+			b.WriteByte(byte(r))
+		}
+	}
+	return b.String()
+}
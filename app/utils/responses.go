@@ -60,6 +60,28 @@ func RespondWithCustomError(c *gin.Context, status int, err string, message stri
 	})
 }
 
+// Envía una respuesta en JSON con varios errores de validación
+// Recibe el código HTTP, la lista de errores y el mensaje como parámetro
+func RespondWithErrors(c *gin.Context, status int, errs []string, message string) {
+	c.JSON(status, model.ApiResponse{
+		Success: false,
+		Message: message,
+		Data:    nil,
+		Errors:  errs,
+	})
+}
+
+// Envía una respuesta en JSON cuando la operación entra en conflicto con otros recursos
+// Recibe los recursos en conflicto, el error a mostrar y el mensaje como parámetro
+func RespondWithConflict(c *gin.Context, data any, err string, message string) {
+	c.JSON(http.StatusConflict, model.ApiResponse{
+		Success: false,
+		Message: message,
+		Data:    data,
+		Errors:  []string{err},
+	})
+}
+
 // Envía una respuesta en JSON en caso de fallo interno del programa
 // Recibe el error a mostrar como parámetro
 func RespondWithInternalError(c *gin.Context, err string) {
@@ -71,11 +93,11 @@ func RespondWithInternalError(c *gin.Context, err string) {
 	})
 }
 
-// Envía una respuesta personalizada en JSON en caso de usuario no autorizado
+// Envía una respuesta personalizada en JSON en caso de usuario sin permisos para la acción
 func RespondWithUnathorizedError(c *gin.Context) {
-	c.JSON(http.StatusInternalServerError, model.ApiResponse{
+	c.JSON(http.StatusForbidden, model.ApiResponse{
 		Success: false,
-		Message: "Only administrators can perform this action",
+		Message: "You don't have permission to perform this action",
 		Data:    nil,
 		Errors:  []string{"Insufficient permissions"},
 	})
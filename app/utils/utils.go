@@ -5,19 +5,39 @@ import (
 	"fmt"
 	"net"
 	"net/smtp"
+	"time"
 )
 
 // Verifica si una string contiene únicamente dígitos
 // Retorna un boolean
 func isAllDigits(s string) bool {
 	for _, c := range s {
-		if c < 48 || c > 57 {
+		if c < '0' || c > '9' {
 			return false
 		}
 	}
 	return true
 }
 
+// Elimina espacios, guiones y cualquier otro símbolo de un número de teléfono
+// Retorna únicamente sus dígitos
+func NormalizePhone(phone string) string {
+	digits := make([]rune, 0, len(phone))
+	for _, c := range phone {
+		if c >= '0' && c <= '9' {
+			digits = append(digits, c)
+		}
+	}
+	return string(digits)
+}
+
+// Calcula el inicio del día de una fecha y el inicio del día siguiente en UTC
+// Retorna ambos límites para filtrar columnas de tipo fecha
+func DayBounds(t time.Time) (time.Time, time.Time) {
+	start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 0, 1)
+}
+
 // Retorna un mensaje de error legible para cada validador
 // Recibe el nombre como parámetro
 func getTagMessage(tag string) string {
@@ -56,5 +56,5 @@ var QuestionOptionValidator validator.Func = func(fl validator.FieldLevel) bool
 // El estado debe pertenecer a una lista válida
 var SubmissionStatusValidator validator.Func = func(fl validator.FieldLevel) bool {
 	status := fl.Field().String()
-	return status == "pending" || status == "approved" || status == "rejected"
+	return status == "pending" || status == "approved" || status == "rejected" || status == "expired"
 }
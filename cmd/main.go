@@ -2,13 +2,16 @@ package main
 
 import (
 	"log"
+	"strings"
 	"time"
 
+	"dapa/app/jobs"
 	"dapa/app/model"
 	"dapa/app/routes"
 	"dapa/app/utils"
 	"dapa/database"
 	_ "dapa/docs"
+	"dapa/storage"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -27,7 +30,16 @@ import (
 func main() {
 	router := gin.Default()
 
-	router.SetTrustedProxies(nil)
+	// La IP de cada cliente limita los intentos de inicio de sesión, así que solo se lee de los encabezados de proxies de confianza
+	// Sin TRUSTED_PROXIES se usa la dirección de la conexión
+	var trustedProxies []string
+	if proxies := utils.EnvGet("TRUSTED_PROXIES", ""); proxies != "" {
+		trustedProxies = strings.Split(proxies, ",")
+	}
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+	router.TrustedPlatform = utils.EnvGet("TRUSTED_PLATFORM_HEADER", "")
 
 	// Configuración del middleware de CORS
 	router.Use(cors.New(cors.Config{
@@ -57,9 +69,13 @@ func main() {
 	}
 
 	database.ConnectToDatabase()
+	storage.Connect()
 
 	SeedQuestionTypes()
 	SeedQuestions()
+	SeedRoles()
+
+	jobs.Start()
 
 	routes.SetupRoutes(router)
 
@@ -126,14 +142,14 @@ func SeedQuestions() {
 		isRequired  bool
 	}{
 		{
-			question:    "¿Cuál es tu nombre?",
+			question:    model.QuestionClientName,
 			description: stringPtr("Por favor ingresa tu nombre y apellidos"),
 			typeID:      textType.ID,
 			position:    1,
 			isRequired:  true,
 		},
 		{
-			question:    "¿Cuál es tu número de teléfono?",
+			question:    model.QuestionClientPhone,
 			description: stringPtr("Por favor escribe tu número de teléfono"),
 			typeID:      textType.ID,
 			position:    2,
@@ -185,6 +201,48 @@ func SeedQuestions() {
 	}
 }
 
+// Crea los roles del sistema que no existan
+// Los permisos de los roles existentes no se modifican, salvo el administrador que recibe los permisos nuevos del catálogo
+func SeedRoles() {
+	for _, defaultRole := range model.DefaultRoles {
+		permissions := defaultRole.Permissions
+		if defaultRole.Name == "admin" {
+			permissions = model.AllPermissions()
+		}
+
+		var role model.Role
+		result := database.DB.Preload("Permissions").Where("name = ?", defaultRole.Name).First(&role)
+
+		if result.Error != nil {
+			role = model.Role{Name: defaultRole.Name, Description: defaultRole.Description, System: true}
+			for _, permission := range permissions {
+				role.Permissions = append(role.Permissions, model.RolePermission{Permission: permission})
+			}
+
+			if err := database.DB.Create(&role).Error; err != nil {
+				log.Printf("Error creating role '%s': %v", defaultRole.Name, err)
+			} else {
+				log.Printf("Role '%s' created with ID: %d", role.Name, role.ID)
+			}
+			continue
+		}
+
+		if defaultRole.Name != "admin" {
+			continue
+		}
+
+		granted := role.PermissionSet()
+		for _, permission := range permissions {
+			if granted[permission] {
+				continue
+			}
+			if err := database.DB.Create(&model.RolePermission{RoleID: role.ID, Permission: permission}).Error; err != nil {
+				log.Printf("Error granting '%s' to role '%s': %v", permission, role.Name, err)
+			}
+		}
+	}
+}
+
 func stringPtr(s string) *string {
 	return &s
 }
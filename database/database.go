@@ -1,6 +1,7 @@
 package database
 
 import (
+	"embed"
 	"log"
 
 	"dapa/app/utils"
@@ -11,10 +12,17 @@ import (
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
 )
 
 var DB *gorm.DB
 
+// Migraciones de los cambios de esquema propios del backend
+// Se aplican después de las del repositorio de la base de datos y llevan su propio control de versiones
+//
+//go:embed migrations/*.sql
+var backendMigrations embed.FS
+
 // Inicializa la conexión a la base de datos PostgreSQL
 func ConnectToDatabase() {
 	dsn := "host=database user=" + utils.EnvMustGet("POSTGRES_USER") +
@@ -22,24 +30,20 @@ func ConnectToDatabase() {
 		" dbname=" + utils.EnvMustGet("POSTGRES_DB") + " port=5432 sslmode=disable TimeZone=UTC"
 
 	var err error
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true})
 	if err != nil {
 		log.Fatal("Failed to connect to DB:", err)
 	}
 
 	migrateDatabase()
+	migrateBackend()
 }
 
 // Aplica las migraciones necesarias a la base de datos
 func migrateDatabase() {
-	dbURL := "postgres://" + utils.EnvMustGet("POSTGRES_USER") +
-		":" + utils.EnvMustGet("POSTGRES_PASSWORD") +
-		"@database:5432/" + utils.EnvMustGet("POSTGRES_DB") +
-		"?sslmode=disable"
-
 	m, err := migrate.New(
 		"file:///database/migrations",
-		dbURL,
+		databaseURL(),
 	)
 	if err != nil {
 		log.Fatal("Failed to create migrate instance:", err)
@@ -50,3 +54,29 @@ func migrateDatabase() {
 		log.Fatal("Failed to migrate DB:", err)
 	}
 }
+
+// Aplica las migraciones incluidas en el binario
+// Usan una tabla de control distinta para no mezclarse con las versiones del repositorio de la base de datos
+func migrateBackend() {
+	source, err := iofs.New(backendMigrations, "migrations")
+	if err != nil {
+		log.Fatal("Failed to read backend migrations:", err)
+	}
+
+	m, err := migrate.NewWithSourceInstance("iofs", source, databaseURL()+"&x-migrations-table=backend_schema_migrations")
+	if err != nil {
+		log.Fatal("Failed to create migrate instance:", err)
+	}
+
+	err = m.Up()
+	if err != nil && err != migrate.ErrNoChange {
+		log.Fatal("Failed to migrate DB:", err)
+	}
+}
+
+func databaseURL() string {
+	return "postgres://" + utils.EnvMustGet("POSTGRES_USER") +
+		":" + utils.EnvMustGet("POSTGRES_PASSWORD") +
+		"@database:5432/" + utils.EnvMustGet("POSTGRES_DB") +
+		"?sslmode=disable"
+}
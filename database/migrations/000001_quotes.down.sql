@@ -0,0 +1,2 @@
+ALTER TABLE orders DROP COLUMN IF EXISTS quote_id;
+DROP INDEX IF EXISTS idx_quotes_submission_version;
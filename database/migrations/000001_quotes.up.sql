@@ -0,0 +1,54 @@
+CREATE TABLE IF NOT EXISTS pricing_rules (
+    id BIGSERIAL PRIMARY KEY,
+    order_type VARCHAR(50) NOT NULL UNIQUE,
+    base_fee DECIMAL NOT NULL,
+    per_km_rate DECIMAL NOT NULL,
+    per_kg_rate DECIMAL NOT NULL,
+    helper_fee DECIMAL NOT NULL,
+    weekend_surcharge_pct DECIMAL NOT NULL,
+    after_hours_surcharge_pct DECIMAL NOT NULL,
+    workday_start_hour BIGINT NOT NULL DEFAULT 7,
+    workday_end_hour BIGINT NOT NULL DEFAULT 18,
+    minimum_charge DECIMAL NOT NULL,
+    is_active BOOLEAN DEFAULT TRUE,
+    created_at TIMESTAMPTZ,
+    last_modified_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS quotes (
+    id BIGSERIAL PRIMARY KEY,
+    submission_id BIGINT NOT NULL,
+    version BIGINT NOT NULL,
+    status VARCHAR(15) NOT NULL DEFAULT 'draft',
+    client_name VARCHAR(50) NOT NULL,
+    client_phone VARCHAR(20) NOT NULL,
+    origin VARCHAR(100) NOT NULL,
+    destination VARCHAR(100) NOT NULL,
+    details VARCHAR(255),
+    order_type VARCHAR(50) NOT NULL,
+    meeting_date TIMESTAMPTZ NOT NULL,
+    distance_km DECIMAL,
+    weight_kg DECIMAL,
+    helpers BIGINT,
+    base_fee DECIMAL,
+    distance_fee DECIMAL,
+    weight_fee DECIMAL,
+    helper_fee DECIMAL,
+    weekend_surcharge DECIMAL,
+    after_hours_surcharge DECIMAL,
+    minimum_adjustment DECIMAL,
+    calculated_total DECIMAL,
+    override_amount DECIMAL,
+    override_reason VARCHAR(255),
+    total DECIMAL NOT NULL,
+    decline_reason VARCHAR(255),
+    responded_at TIMESTAMPTZ,
+    created_by BIGINT,
+    created_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_quotes_submission_id ON quotes (submission_id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_quotes_submission_version ON quotes (submission_id, version);
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS quote_id BIGINT;
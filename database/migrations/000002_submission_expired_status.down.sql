@@ -0,0 +1,2 @@
+-- Postgres no permite quitar un valor de un enum; los envíos expirados vuelven a quedar pendientes
+UPDATE submissions SET status = 'pending' WHERE status = 'expired';
@@ -0,0 +1 @@
+ALTER TYPE form_status ADD VALUE IF NOT EXISTS 'expired';
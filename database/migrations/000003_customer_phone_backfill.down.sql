@@ -0,0 +1,2 @@
+-- Los teléfonos normalizados y los clientes creados a partir de órdenes y envíos se conservan
+SELECT 1;
@@ -0,0 +1,87 @@
+CREATE TABLE IF NOT EXISTS customers (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    phone VARCHAR(20) NOT NULL UNIQUE,
+    email VARCHAR(50),
+    notes VARCHAR(255),
+    created_at TIMESTAMPTZ,
+    last_modified_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    is_active BOOLEAN DEFAULT TRUE
+);
+
+CREATE TABLE IF NOT EXISTS customer_addresses (
+    id BIGSERIAL PRIMARY KEY,
+    customer_id BIGINT NOT NULL,
+    label VARCHAR(50),
+    address VARCHAR(255) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_customer_addresses_customer_id ON customer_addresses (customer_id);
+
+ALTER TABLE submissions ADD COLUMN IF NOT EXISTS customer_id BIGINT;
+CREATE INDEX IF NOT EXISTS idx_submissions_customer_id ON submissions (customer_id);
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS customer_id BIGINT;
+CREATE INDEX IF NOT EXISTS idx_orders_customer_id ON orders (customer_id);
+
+-- Los teléfonos se guardan solo con dígitos, igual que utils.NormalizePhone
+-- Si dos clientes quedan con el mismo número se conserva el existente y el otro se deja sin cambios
+UPDATE customers c
+SET phone = regexp_replace(c.phone, '\D', '', 'g')
+WHERE c.phone ~ '\D'
+  AND NOT EXISTS (
+    SELECT 1 FROM customers other
+    WHERE other.id <> c.id AND other.phone = regexp_replace(c.phone, '\D', '', 'g')
+  );
+
+-- Clientes de las órdenes existentes, con el nombre de su orden más reciente
+INSERT INTO customers (name, phone, is_active, created_at, last_modified_at)
+SELECT DISTINCT ON (phone) LEFT(client_name, 100), phone, TRUE, NOW(), NOW()
+FROM (
+    SELECT id, client_name, LEFT(regexp_replace(client_phone, '\D', '', 'g'), 20) AS phone
+    FROM orders
+) normalized
+WHERE phone <> ''
+ORDER BY phone, id DESC
+ON CONFLICT (phone) DO NOTHING;
+
+UPDATE orders o
+SET customer_id = c.id
+FROM customers c
+WHERE o.customer_id IS NULL
+  AND c.phone = LEFT(regexp_replace(o.client_phone, '\D', '', 'g'), 20);
+
+-- Los envíos con orden toman el cliente de la orden
+UPDATE submissions s
+SET customer_id = o.customer_id
+FROM orders o
+WHERE s.customer_id IS NULL AND o.submission_id = s.id AND o.customer_id IS NOT NULL;
+
+-- Los demás se identifican con las respuestas a las preguntas fijas de nombre y teléfono
+CREATE TEMPORARY TABLE submission_clients AS
+SELECT s.id AS submission_id,
+       LEFT(COALESCE(name_answer.answer, ''), 100) AS name,
+       LEFT(regexp_replace(COALESCE(phone_answer.answer, ''), '\D', '', 'g'), 20) AS phone
+FROM submissions s
+JOIN answers phone_answer ON phone_answer.submission_id = s.id
+JOIN questions phone_question ON phone_question.id = phone_answer.question_id
+    AND phone_question.question = '¿Cuál es tu número de teléfono?'
+LEFT JOIN answers name_answer ON name_answer.submission_id = s.id
+    AND name_answer.question_id = (SELECT id FROM questions WHERE question = '¿Cuál es tu nombre?' LIMIT 1)
+WHERE s.customer_id IS NULL;
+
+INSERT INTO customers (name, phone, is_active, created_at, last_modified_at)
+SELECT DISTINCT ON (phone) name, phone, TRUE, NOW(), NOW()
+FROM submission_clients
+WHERE phone <> ''
+ORDER BY phone, submission_id DESC
+ON CONFLICT (phone) DO NOTHING;
+
+UPDATE submissions s
+SET customer_id = c.id
+FROM submission_clients sc
+JOIN customers c ON c.phone = sc.phone
+WHERE s.id = sc.submission_id AND sc.phone <> '';
+
+DROP TABLE submission_clients;
@@ -0,0 +1,5 @@
+DELETE FROM payments WHERE method = 'unknown' AND reference = 'Registro histórico';
+
+ALTER TABLE payments DROP COLUMN IF EXISTS void_reason;
+ALTER TABLE payments DROP COLUMN IF EXISTS voided_by;
+ALTER TABLE payments DROP COLUMN IF EXISTS voided_at;
@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS payments (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL,
+    amount DECIMAL NOT NULL,
+    method VARCHAR(15) NOT NULL,
+    paid_at DATE NOT NULL,
+    reference VARCHAR(100),
+    is_deposit BOOLEAN NOT NULL DEFAULT FALSE,
+    received_by BIGINT,
+    created_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_payments_order_id ON payments (order_id);
+
+-- Los pagos registrados por error se anulan en lugar de eliminarse
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS voided_at TIMESTAMPTZ;
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS voided_by BIGINT;
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS void_reason VARCHAR(255);
+
+-- Antes del registro de pagos los reportes contaban como cobrado el total de cada orden entregada en su fecha
+-- Se registra el saldo pendiente de esas órdenes para que los reportes históricos no cambien
+INSERT INTO payments (order_id, amount, method, paid_at, reference, is_deposit, created_at)
+SELECT o.id,
+       o.total_amount - COALESCE(paid.amount, 0),
+       'unknown',
+       COALESCE(o.date, o.meeting_date),
+       'Registro histórico',
+       FALSE,
+       NOW()
+FROM orders o
+LEFT JOIN (
+    SELECT order_id, SUM(amount) AS amount
+    FROM payments
+    WHERE voided_at IS NULL
+    GROUP BY order_id
+) paid ON paid.order_id = o.id
+WHERE o.status = 'delivered'
+  AND o.total_amount - COALESCE(paid.amount, 0) > 0;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_attachments_owner;
+DROP TABLE IF EXISTS attachments;
+DROP TABLE IF EXISTS proof_of_deliveries;
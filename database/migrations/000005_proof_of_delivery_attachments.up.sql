@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS proof_of_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL UNIQUE,
+    recipient_name VARCHAR(100) NOT NULL,
+    notes VARCHAR(255),
+    delivered_at TIMESTAMPTZ NOT NULL,
+    submitted_by BIGINT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS attachments (
+    id BIGSERIAL PRIMARY KEY,
+    owner_type VARCHAR(30) NOT NULL,
+    owner_id BIGINT NOT NULL,
+    category VARCHAR(50),
+    file_name VARCHAR(255) NOT NULL,
+    file_key VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size BIGINT NOT NULL,
+    uploaded_by BIGINT,
+    created_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachments_owner ON attachments (owner_type, owner_id);
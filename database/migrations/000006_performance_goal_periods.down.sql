@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_performance_goals_driver;
+DROP INDEX IF EXISTS idx_performance_goals_general;
+
+-- Las metas de cada periodo se conservan
+ALTER TABLE performance_goals ALTER COLUMN period_start DROP NOT NULL;
+ALTER TABLE performance_goals ALTER COLUMN period DROP NOT NULL;
@@ -0,0 +1,33 @@
+CREATE TABLE IF NOT EXISTS performance_goals (
+    id BIGSERIAL PRIMARY KEY,
+    order_goal BIGINT NOT NULL,
+    utility_goal DECIMAL NOT NULL,
+    average_per_order_goal DECIMAL NOT NULL,
+    travel_goal BIGINT NOT NULL,
+    delivery_goal DECIMAL NOT NULL,
+    achievement_rate_goal DECIMAL NOT NULL
+);
+
+ALTER TABLE performance_goals ADD COLUMN IF NOT EXISTS period VARCHAR(10);
+ALTER TABLE performance_goals ADD COLUMN IF NOT EXISTS period_start DATE;
+ALTER TABLE performance_goals ADD COLUMN IF NOT EXISTS user_id BIGINT;
+ALTER TABLE performance_goals ADD COLUMN IF NOT EXISTS updated_by BIGINT;
+ALTER TABLE performance_goals ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;
+
+-- Antes de tener periodo solo se usaba la primera meta, que pasa a ser la meta general del mes actual
+DELETE FROM performance_goals
+WHERE period IS NULL
+  AND id <> (SELECT MIN(id) FROM performance_goals WHERE period IS NULL);
+
+UPDATE performance_goals
+SET period = 'month',
+    period_start = date_trunc('month', NOW() AT TIME ZONE 'America/Guatemala')::date,
+    updated_at = NOW()
+WHERE period IS NULL;
+
+ALTER TABLE performance_goals ALTER COLUMN period SET NOT NULL;
+ALTER TABLE performance_goals ALTER COLUMN period_start SET NOT NULL;
+
+-- Las metas generales no tienen piloto, así que cada caso tiene su propio índice único
+CREATE UNIQUE INDEX IF NOT EXISTS idx_performance_goals_general ON performance_goals (period, period_start) WHERE user_id IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_performance_goals_driver ON performance_goals (period, period_start, user_id) WHERE user_id IS NOT NULL;
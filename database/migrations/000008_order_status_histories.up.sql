@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS order_status_histories (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL,
+    from_status VARCHAR(20),
+    to_status VARCHAR(20) NOT NULL,
+    changed_by BIGINT,
+    reason VARCHAR(255),
+    changed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_status_histories_order_id ON order_status_histories (order_id);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS order_items (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL,
+    description VARCHAR(255) NOT NULL,
+    quantity BIGINT NOT NULL DEFAULT 1,
+    weight_kg DECIMAL NOT NULL DEFAULT 0,
+    volume_m3 DECIMAL NOT NULL DEFAULT 0,
+    is_fragile BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_items_order_id ON order_items (order_id);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS order_stops (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL,
+    position BIGINT NOT NULL,
+    type VARCHAR(10) NOT NULL,
+    address VARCHAR(255) NOT NULL,
+    contact_name VARCHAR(100),
+    contact_phone VARCHAR(20),
+    window_start TIMESTAMPTZ,
+    window_end TIMESTAMPTZ,
+    status VARCHAR(15) NOT NULL DEFAULT 'pending',
+    completed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_stops_order_id ON order_stops (order_id);
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS quote_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    quote_id BIGINT NOT NULL UNIQUE,
+    token VARCHAR(255) NOT NULL UNIQUE,
+    expiry TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS invoices (
+    id BIGSERIAL PRIMARY KEY,
+    number BIGINT NOT NULL UNIQUE,
+    order_id BIGINT NOT NULL UNIQUE,
+    client_name VARCHAR(50) NOT NULL,
+    client_phone VARCHAR(20),
+    subtotal DECIMAL NOT NULL,
+    tax_rate DECIMAL NOT NULL,
+    tax_amount DECIMAL NOT NULL,
+    total DECIMAL NOT NULL,
+    document BYTEA NOT NULL,
+    issued_by BIGINT,
+    issued_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS invoice_sequences (
+    id BIGSERIAL PRIMARY KEY,
+    last_number BIGINT NOT NULL DEFAULT 0
+);
+
+-- La secuencia existe desde el inicio para que las primeras facturas simultáneas esperen el mismo bloqueo
+INSERT INTO invoice_sequences (id, last_number) VALUES (1, 0) ON CONFLICT (id) DO NOTHING;
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_expenses_user_id;
+DROP INDEX IF EXISTS idx_expenses_vehicle_id;
+DROP INDEX IF EXISTS idx_expenses_order_id;
+
+ALTER TABLE expenses DROP COLUMN IF EXISTS user_id;
+ALTER TABLE expenses DROP COLUMN IF EXISTS vehicle_id;
+ALTER TABLE expenses DROP COLUMN IF EXISTS order_id;
@@ -0,0 +1,8 @@
+-- Los gastos pueden asociarse a la orden, el vehículo o el empleado que los originó
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS order_id BIGINT;
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS vehicle_id BIGINT;
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS user_id BIGINT;
+
+CREATE INDEX IF NOT EXISTS idx_expenses_order_id ON expenses (order_id);
+CREATE INDEX IF NOT EXISTS idx_expenses_vehicle_id ON expenses (vehicle_id);
+CREATE INDEX IF NOT EXISTS idx_expenses_user_id ON expenses (user_id);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS kpi_snapshots (
+    id BIGSERIAL PRIMARY KEY,
+    period_start DATE NOT NULL UNIQUE,
+    total_income DECIMAL NOT NULL DEFAULT 0,
+    total_expenses DECIMAL NOT NULL DEFAULT 0,
+    utility DECIMAL NOT NULL DEFAULT 0,
+    orders BIGINT NOT NULL DEFAULT 0,
+    average_per_order DECIMAL NOT NULL DEFAULT 0,
+    completed_trips BIGINT NOT NULL DEFAULT 0,
+    delivered_orders BIGINT NOT NULL DEFAULT 0,
+    average_orders_per_employee DECIMAL NOT NULL DEFAULT 0,
+    fulfillment_rate DECIMAL NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ
+);
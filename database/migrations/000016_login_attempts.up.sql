@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS login_attempts (
+    id BIGSERIAL PRIMARY KEY,
+    kind VARCHAR(10) NOT NULL,
+    email VARCHAR(100),
+    user_id BIGINT,
+    ip VARCHAR(45),
+    user_agent VARCHAR(255),
+    success BOOLEAN NOT NULL DEFAULT FALSE,
+    reason VARCHAR(30),
+    created_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_attempts_email ON login_attempts (email);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_user_id ON login_attempts (user_id);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_ip ON login_attempts (ip);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_created_at ON login_attempts (created_at);
+
+-- Los fallos seguidos de cada cuenta y el bloqueo temporal que provocan
+ALTER TABLE users ADD COLUMN IF NOT EXISTS failed_login_attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locked_until TIMESTAMPTZ;